@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/cache"
+	"github.com/veenone/mvnenv-win/internal/tool"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+var (
+	cacheJSON         bool
+	cacheOlderThan    string
+	cacheKeep         int
+	cacheNotInstalled bool
+	cacheDryRun       bool
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and clean the download cache",
+	Long: `Inspect and clean the download cache.
+
+The cache holds downloaded archives (indexed by SHA-512), the cached list of
+available versions, and temporary files left by interrupted installs and
+mirrors.`,
+	Example: `  mvnenv cache list
+  mvnenv cache verify
+  mvnenv cache prune --older-than 90d --keep 2
+  mvnenv cache clear --json`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List cached files with their sizes and ages",
+	Example: `  mvnenv cache list`,
+	Args:    cobra.NoArgs,
+	RunE:    runCacheList,
+}
+
+var cacheVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Re-check the checksums of cached archives",
+	Long: `Re-check the size and SHA-512 of every cached archive.
+
+Exits non-zero if any archive fails. Failed archives are downloaded again the
+next time their version is installed.`,
+	Example: `  mvnenv cache verify`,
+	Args:    cobra.NoArgs,
+	RunE:    runCacheVerify,
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old, surplus or unused archives and leftover temporary files",
+	Long: `Remove cached archives selected by a policy, plus orphaned files.
+
+Archive policies combine; an archive is removed only if it matches all of them:
+  --older-than   fetched longer ago than the given age (e.g. 30d, 12h)
+  --keep         everything except the N most recently fetched archives per tool
+  --not-installed  its version is not currently installed; extension jars and
+                   overlay archives are kept while in use
+
+Without a policy only orphaned files are removed: temporary files untouched for
+15 minutes (interrupted installs and mirrors), files in the archive store that
+its index doesn't reference, and archives left in the cache by older mvnenv
+versions.`,
+	Example: `  mvnenv cache prune
+  mvnenv cache prune --not-installed
+  mvnenv cache prune --older-than 90d --keep 2
+  mvnenv cache prune --keep 3 --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runCachePrune,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove everything from the cache",
+	Long: `Remove every cached archive, the version list and leftover temporary files.
+
+Downloads still in progress in another mvnenv process are left alone.`,
+	Example: `  mvnenv cache clear`,
+	Args:    cobra.NoArgs,
+	RunE:    runCacheClear,
+}
+
+func init() {
+	cacheCmd.PersistentFlags().BoolVar(&cacheJSON, "json", false, "Print results as JSON")
+
+	cachePruneCmd.Flags().StringVar(&cacheOlderThan, "older-than", "", "Remove archives fetched longer ago than this age (e.g. 30d, 12h)")
+	cachePruneCmd.Flags().IntVar(&cacheKeep, "keep", 0, "Keep the N most recently fetched archives of each tool")
+	cachePruneCmd.Flags().BoolVar(&cacheNotInstalled, "not-installed", false, "Remove archives of versions that are not installed")
+	cachePruneCmd.Flags().BoolVar(&cacheDryRun, "dry-run", false, "Show what would be removed without removing it")
+
+	cacheCmd.AddCommand(cacheListCmd, cacheVerifyCmd, cachePruneCmd, cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// cacheReport is the JSON output of list, prune and clear
+type cacheReport struct {
+	Items     []cache.Item `json:"items"`
+	TotalSize int64        `json:"total_size"`
+	DryRun    bool         `json:"dry_run,omitempty"`
+}
+
+func newCacheReport(items []cache.Item) cacheReport {
+	report := cacheReport{Items: items}
+	if report.Items == nil {
+		report.Items = []cache.Item{}
+	}
+	for _, item := range items {
+		report.TotalSize += item.Size
+	}
+	return report
+}
+
+func runCacheList(cmd *cobra.Command, args []string) error {
+	items, err := cache.Inventory(getMvnenvRoot())
+	if err != nil {
+		return formatError(err)
+	}
+
+	report := newCacheReport(items)
+	if cacheJSON {
+		return printJSON(report)
+	}
+
+	if len(items) == 0 {
+		fmt.Println("Cache is empty")
+		return nil
+	}
+
+	printCacheItems(items)
+	fmt.Printf("\n%d items, %s\n", len(items), formatSize(report.TotalSize))
+	return nil
+}
+
+func runCacheVerify(cmd *cobra.Command, args []string) error {
+	results, err := cache.NewArchiveStore(getMvnenvRoot()).Verify()
+	if err != nil {
+		return formatError(err)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if !r.OK {
+			failed++
+		}
+	}
+
+	if cacheJSON {
+		if results == nil {
+			results = []cache.VerifyResult{}
+		}
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, r := range results {
+			status := "OK"
+			if !r.OK {
+				status = "FAILED: " + r.Error
+			}
+			fmt.Printf("%-6s %-12s %s\n", r.Item.Tool, r.Item.Version, status)
+		}
+		fmt.Printf("\n%d archives verified, %d failed\n", len(results), failed)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d cached archives failed verification", failed)
+	}
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	policy := cache.PrunePolicy{
+		Keep:         cacheKeep,
+		NotInstalled: cacheNotInstalled,
+		DryRun:       cacheDryRun,
+		Installed: func(toolName, version string) bool {
+			// Extension jars and overlays count as installed while in use
+			if versionpkg.IsStoredArtifact(toolName) {
+				return versionpkg.ArtifactInUse(mvnenvRoot, toolName, version)
+			}
+			def, err := tool.Get(toolName)
+			if err != nil {
+				return false
+			}
+			return def.IsValidInstallation(def.InstallPath(mvnenvRoot, version))
+		},
+	}
+	if cacheKeep < 0 {
+		return formatError(fmt.Errorf("--keep must not be negative"))
+	}
+	if cacheOlderThan != "" {
+		age, err := parseAge(cacheOlderThan)
+		if err != nil {
+			return formatError(err)
+		}
+		policy.OlderThan = age
+	}
+
+	removed, err := cache.Prune(mvnenvRoot, policy)
+	if err != nil {
+		return formatError(err)
+	}
+
+	report := newCacheReport(removed)
+	report.DryRun = cacheDryRun
+	if cacheJSON {
+		return printJSON(report)
+	}
+
+	if len(removed) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	printCacheItems(removed)
+	verb := "Removed"
+	if cacheDryRun {
+		verb = "Would remove"
+	}
+	fmt.Printf("\n%s %d items, freeing %s\n", verb, len(removed), formatSize(report.TotalSize))
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	removed, err := cache.Clear(getMvnenvRoot())
+	if err != nil {
+		return formatError(err)
+	}
+
+	report := newCacheReport(removed)
+	if cacheJSON {
+		return printJSON(report)
+	}
+
+	fmt.Printf("Cache cleared: removed %d items, freeing %s\n", len(removed), formatSize(report.TotalSize))
+	return nil
+}
+
+// printCacheItems prints cache items as a table
+func printCacheItems(items []cache.Item) {
+	fmt.Printf("%-14s  %-22s  %9s  %5s  %s\n", "KIND", "TOOL/VERSION", "SIZE", "AGE", "PATH")
+	for _, item := range items {
+		name := "-"
+		if item.Tool != "" {
+			name = item.Tool + " " + item.Version
+		}
+		fmt.Printf("%-14s  %-22s  %9s  %5s  %s\n",
+			item.Kind, name, formatSize(item.Size), formatAge(item.Age()), formatPath(item.Path))
+	}
+}
+
+// parseAge parses an age such as "30d", "12h" or "90m"; days are accepted in
+// addition to Go duration units
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age '%s'", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age '%s' (use e.g. 30d or 12h)", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/doctor"
+)
+
+var (
+	doctorFix  bool
+	doctorJSON bool
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose problems with the mvnenv environment",
+	Long: `Check the mvnenv environment and report each problem with how to fix it.
+
+The checks cover config.yaml, shim.exe and the shims, whether the shims
+directory comes first on PATH, the global and current versions, JAVA_HOME,
+the version and download caches, and the Nexus CA file and connection.
+
+Each check reports ok, warn or fail. With --fix, problems with a safe fix are
+repaired: shims are regenerated, versions.json is refreshed, the global
+version index is rewritten and corrupt cached archives are removed. PATH,
+JAVA_HOME and config.yaml are never changed.
+
+The command exits with an error when a check fails. --json prints a report
+for collecting across machines.`,
+	Example: `  mvnenv doctor
+  mvnenv doctor --fix
+  mvnenv doctor --json > doctor.json`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Apply safe fixes")
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	d := doctor.New(getMvnenvRoot())
+	d.SetFix(doctorFix)
+	if !doctorJSON {
+		d.OnResult(printDoctorResult)
+	}
+
+	summary, err := d.Run(cmd.Context())
+	if err != nil {
+		return formatError(err)
+	}
+	summary.Version = appVersion
+
+	if doctorJSON {
+		if err := printJSON(summary); err != nil {
+			return formatError(err)
+		}
+	} else {
+		fixable := 0
+		for _, r := range summary.Results {
+			if r.Fixable() {
+				fixable++
+			}
+		}
+		fmt.Printf("\n%d ok, %d warnings, %d failures\n", summary.OK, summary.Warnings, summary.Failures)
+		if fixable > 0 && !doctorFix {
+			fmt.Printf("Run 'mvnenv doctor --fix' to apply %d safe fixes\n", fixable)
+		}
+	}
+
+	if summary.Failures > 0 {
+		return fmt.Errorf("%d of %d checks failed", summary.Failures, len(summary.Results))
+	}
+	return nil
+}
+
+// printDoctorResult prints one check as it finishes
+func printDoctorResult(r doctor.Result) {
+	label := map[doctor.Status]string{
+		doctor.StatusOK:   "ok",
+		doctor.StatusWarn: "WARN",
+		doctor.StatusFail: "FAIL",
+	}[r.Status]
+
+	message := r.Message
+	if r.Fixed {
+		message += " (fixed)"
+	}
+	fmt.Printf("%-4s  %-16s  %s\n", label, r.Check, message)
+
+	if r.FixError != "" {
+		fmt.Printf("      %-16s  fix failed: %s\n", "", r.FixError)
+	}
+	if r.Status != doctor.StatusOK && r.Remedy != "" {
+		fmt.Printf("      %-16s  -> %s\n", "", r.Remedy)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/shim"
+	"github.com/veenone/mvnenv-win/internal/version"
+)
+
+var (
+	execVersion string
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [--version <version>] -- <command> [args...]",
+	Short: "Run a command with a specific Maven version",
+	Long: `Run any command with the environment a Maven shim would build.
+
+The command runs with MAVEN_HOME set to the selected Maven installation and
+that installation's bin directory prepended to PATH. Every other variable,
+such as MAVEN_OPTS, is passed on from the current environment unchanged. Bare
+command names such as "mvn" are looked up in the installation's bin
+directory first.
+
+Without --version, the active version is resolved as usual (shell > local >
+global). The exit code of the command is returned as mvnenv's exit code, so
+scripts and IDE run configurations can target a version explicitly.`,
+	Example: `  mvnenv exec --version 3.8.8 -- mvn -B verify
+  mvnenv exec --version 3.9.6 -- cmd /c echo %MAVEN_HOME%
+  mvnenv exec -- mvn -v`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+func init() {
+	execCmd.Flags().StringVar(&execVersion, "version", "", "Maven version to use (default: the active version)")
+	// Everything after the command name belongs to the command
+	execCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(execCmd)
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+	resolver := version.NewVersionResolver(mvnenvRoot)
+
+	var resolved *version.ResolvedVersion
+	var err error
+	if execVersion != "" {
+		if err := validateVersionFormat(execVersion); err != nil {
+			return formatError(err)
+		}
+		resolved, err = resolver.ResolveExplicitVersion(execVersion)
+	} else {
+		resolved, err = resolver.ResolveVersion()
+	}
+	if err != nil {
+		if version.IsNoVersionSetError(err) {
+			return fmt.Errorf("no Maven version is set (use --version or 'mvnenv global <version>')")
+		}
+		if version.IsVersionNotInstalledError(err) {
+			ver := execVersion
+			var vErr *version.VersionError
+			if errors.As(err, &vErr) {
+				ver = vErr.Version
+			}
+			return fmt.Errorf("Maven %s is not installed (use 'mvnenv install %s' first)", ver, ver)
+		}
+		return formatError(err)
+	}
+
+	executor := shim.NewShimExecutor(resolver)
+	exitCode, err := executor.ExecuteCommand(resolved, args[0], args[1:])
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/tool"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+var (
+	extVersion string
+	extAll     bool
+	extJSON    bool
+)
+
+var extCmd = &cobra.Command{
+	Use:   "ext",
+	Short: "Manage Maven core extensions of installed versions",
+	Long: `Add, remove and list core extensions in the lib/ext directory of installed
+Maven versions.
+
+Extension jars are downloaded from the Nexus configured under
+repositories.nexus, verified under the integrity policy, and kept in the
+download cache. They are recorded in the version's manifest, so reinstalling
+the version with --force or running "mvnenv repair" puts them back.
+
+Commands work on the current Maven version unless --version or --all is given.`,
+	Example: `  mvnenv ext add org.apache.maven.extensions:maven-build-cache-extension:1.2.0
+  mvnenv ext add com.example:event-spy:2.1 --all
+  mvnenv ext rm com.example:event-spy --version 3.9.6
+  mvnenv ext list --all`,
+}
+
+var extAddCmd = &cobra.Command{
+	Use:   "add <groupId:artifactId:version>...",
+	Short: "Add extensions, replacing other versions of them",
+	Example: `  mvnenv ext add org.apache.maven.extensions:maven-build-cache-extension:1.2.0
+  mvnenv ext add com.example:event-spy:2.1 --version 3.9.6`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExtAdd,
+}
+
+var extRmCmd = &cobra.Command{
+	Use:     "rm <groupId:artifactId[:version]>...",
+	Aliases: []string{"remove"},
+	Short:   "Remove extensions",
+	Example: `  mvnenv ext rm com.example:event-spy
+  mvnenv ext rm com.example:event-spy --all`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExtRm,
+}
+
+var extListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List extensions",
+	Example: `  mvnenv ext list
+  mvnenv ext list --all --json`,
+	Args: cobra.NoArgs,
+	RunE: runExtList,
+}
+
+func init() {
+	extCmd.PersistentFlags().StringVar(&extVersion, "version", "", "Maven version to work on (default: the current version)")
+	extCmd.PersistentFlags().BoolVar(&extAll, "all", false, "Work on every installed Maven version")
+	extListCmd.Flags().BoolVar(&extJSON, "json", false, "Print results as JSON")
+
+	extCmd.AddCommand(extAddCmd, extRmCmd, extListCmd)
+	rootCmd.AddCommand(extCmd)
+}
+
+// extTargets returns the Maven versions selected with --version or --all,
+// or the current version
+func extTargets() ([]string, error) {
+	mvnenvRoot := getMvnenvRoot()
+
+	switch {
+	case extAll && extVersion != "":
+		return nil, fmt.Errorf("use either --version or --all")
+	case extAll:
+		versions, err := versionpkg.NewToolLister(mvnenvRoot, tool.Maven).ListInstalled()
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no Maven versions are installed")
+		}
+		return versions, nil
+	case extVersion != "":
+		if err := validateVersionFormat(extVersion); err != nil {
+			return nil, err
+		}
+		return []string{extVersion}, nil
+	}
+
+	resolved, err := versionpkg.NewVersionResolver(mvnenvRoot).ResolveVersion()
+	if err != nil {
+		if versionpkg.IsNoVersionSetError(err) {
+			return nil, fmt.Errorf("no Maven version is set (use --version or --all)")
+		}
+		return nil, err
+	}
+	return []string{resolved.Version}, nil
+}
+
+// parseExtensions parses extension coordinates given as arguments
+func parseExtensions(args []string, requireVersion bool) ([]*versionpkg.Extension, error) {
+	var extensions []*versionpkg.Extension
+	for _, arg := range args {
+		ext, err := versionpkg.ParseExtension(arg, requireVersion)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions, nil
+}
+
+func runExtAdd(cmd *cobra.Command, args []string) error {
+	extensions, err := parseExtensions(args, true)
+	if err != nil {
+		return formatError(err)
+	}
+	versions, err := extTargets()
+	if err != nil {
+		return formatError(err)
+	}
+
+	installer := versionpkg.NewVersionInstaller(getMvnenvRoot())
+	for _, v := range versions {
+		for _, ext := range extensions {
+			added, err := installer.AddExtension(cmd.Context(), v, ext)
+			if err != nil {
+				return formatError(fmt.Errorf("add %s to Maven %s: %w", ext.GAV(), v, err))
+			}
+			fmt.Printf("Added %s to Maven %s (%s)\n", added.GAV(), v, added.File)
+		}
+	}
+	return nil
+}
+
+func runExtRm(cmd *cobra.Command, args []string) error {
+	extensions, err := parseExtensions(args, false)
+	if err != nil {
+		return formatError(err)
+	}
+	versions, err := extTargets()
+	if err != nil {
+		return formatError(err)
+	}
+
+	installer := versionpkg.NewVersionInstaller(getMvnenvRoot())
+	for _, v := range versions {
+		for _, ext := range extensions {
+			removed, err := installer.RemoveExtension(v, ext)
+			if err != nil {
+				return formatError(fmt.Errorf("remove %s from Maven %s: %w", ext.GAV(), v, err))
+			}
+			if removed == nil {
+				// With --all, versions without the extension are skipped
+				if !extAll {
+					return formatError(fmt.Errorf("Maven %s has no extension %s", v, ext.GAV()))
+				}
+				continue
+			}
+			fmt.Printf("Removed %s from Maven %s\n", removed.GAV(), v)
+		}
+	}
+	return nil
+}
+
+// versionExtensions lists the extensions of one Maven version
+type versionExtensions struct {
+	Version    string                 `json:"version"`
+	Extensions []versionpkg.Extension `json:"extensions"`
+}
+
+func runExtList(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	versions, err := extTargets()
+	if err != nil {
+		return formatError(err)
+	}
+
+	results := []versionExtensions{}
+	for _, v := range versions {
+		installPath := tool.Maven.InstallPath(mvnenvRoot, v)
+		if !tool.Maven.IsValidInstallation(installPath) {
+			return formatError(fmt.Errorf("Maven %s is not installed", v))
+		}
+		extensions, err := versionpkg.ListExtensions(installPath)
+		if err != nil {
+			return formatError(err)
+		}
+		if extensions == nil {
+			extensions = []versionpkg.Extension{}
+		}
+		results = append(results, versionExtensions{Version: v, Extensions: extensions})
+	}
+
+	if extJSON {
+		return printJSON(results)
+	}
+
+	for _, r := range results {
+		if len(r.Extensions) == 0 {
+			fmt.Printf("%-12s (no extensions)\n", r.Version)
+			continue
+		}
+		fmt.Println(r.Version)
+		for _, ext := range r.Extensions {
+			fmt.Printf("  %-50s %s\n", ext.GAV(), ext.File)
+		}
+	}
+	return nil
+}
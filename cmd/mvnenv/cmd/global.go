@@ -32,37 +32,46 @@ Version Resolution Hierarchy:
   mvnenv global 3.9.4
 
   # Unset global version
-  mvnenv global --unset`,
+  mvnenv global --unset
+
+  # Set global mvnd version
+  mvnenv global --tool mvnd 1.0.2`,
 	RunE: runGlobal,
 }
 
 func init() {
 	rootCmd.AddCommand(globalCmd)
 	globalCmd.Flags().BoolVar(&globalUnset, "unset", false, "Remove the global version setting")
+	addToolFlag(globalCmd)
 }
 
 func runGlobal(cmd *cobra.Command, args []string) error {
 	mvnenvRoot := getMvnenvRoot()
 	configMgr := config.NewManager(mvnenvRoot)
 
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+
 	// Case 1: Unset global version
 	if globalUnset {
-		if err := configMgr.UnsetGlobalVersion(); err != nil {
+		if err := configMgr.UnsetToolGlobalVersion(def.Name); err != nil {
 			return formatError(fmt.Errorf("failed to unset global version: %w", err))
 		}
-		fmt.Println("Global Maven version unset")
+		fmt.Printf("Global %s version unset\n", def.DisplayName)
 		return nil
 	}
 
 	// Case 2: Display current global version
 	if len(args) == 0 {
-		globalVersion, err := configMgr.GetGlobalVersion()
+		globalVersion, err := configMgr.GetToolGlobalVersion(def.Name)
 		if err != nil {
 			return formatError(fmt.Errorf("failed to read configuration: %w", err))
 		}
 
 		if globalVersion == "" {
-			fmt.Println("No global Maven version set (use 'mvnenv global <version>')")
+			fmt.Printf("No global %s version set (use 'mvnenv global %s<version>')\n", def.DisplayName, toolHint(def))
 		} else {
 			fmt.Println(globalVersion)
 		}
@@ -82,17 +91,17 @@ func runGlobal(cmd *cobra.Command, args []string) error {
 	}
 
 	// Validate version is installed
-	resolver := version.NewVersionResolver(mvnenvRoot)
+	resolver := version.NewToolResolver(mvnenvRoot, def)
 	if !resolver.IsVersionInstalled(newVersion) {
-		return formatError(fmt.Errorf("Maven %s is not installed (use 'mvnenv install %s' first)", newVersion, newVersion))
+		return formatError(fmt.Errorf("%s %s is not installed (use 'mvnenv install %s%s' first)", def.DisplayName, newVersion, toolHint(def), newVersion))
 	}
 
 	// Set global version
-	if err := configMgr.SetGlobalVersion(newVersion); err != nil {
+	if err := configMgr.SetToolGlobalVersion(def.Name, newVersion); err != nil {
 		return formatError(fmt.Errorf("failed to set global version: %w", err))
 	}
 
-	fmt.Printf("Global Maven version set to %s\n", newVersion)
+	fmt.Printf("Global %s version set to %s\n", def.DisplayName, newVersion)
 	return nil
 }
 
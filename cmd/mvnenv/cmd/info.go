@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+var infoJSON bool
+
+var infoCmd = &cobra.Command{
+	Use:   "info <version>",
+	Short: "Show where an installed version came from",
+	Long: `Show the provenance of an installed version.
+
+Prints the manifest written at install time: the source URL, archive checksum,
+install time, checksum and signature verification, and the Java runtime that
+"mvn -v" reported. Also shows the disk size of the installation and the files
+that differ from the original archive (see "mvnenv verify").`,
+	Example: `  mvnenv info 3.9.6
+  mvnenv info 3.9.6 --json
+  mvnenv info --tool mvnd 1.0.2`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInfo,
+}
+
+func init() {
+	infoCmd.Flags().BoolVar(&infoJSON, "json", false, "Print the information as JSON")
+	addToolFlag(infoCmd)
+	rootCmd.AddCommand(infoCmd)
+}
+
+// installInfo is the JSON output of info
+type installInfo struct {
+	Tool     string                  `json:"tool"`
+	Version  string                  `json:"version"`
+	Path     string                  `json:"path"`
+	Manifest *versionpkg.Manifest    `json:"manifest"`
+	DiskSize int64                   `json:"disk_size"`
+	Changes  []versionpkg.FileChange `json:"changes"`
+
+	// ChangesUnknown explains why changes couldn't be determined
+	ChangesUnknown string `json:"changes_unknown,omitempty"`
+}
+
+func runInfo(cmd *cobra.Command, args []string) error {
+	ver := args[0]
+	mvnenvRoot := getMvnenvRoot()
+
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+	if err := validateVersionFormat(ver); err != nil {
+		return formatError(err)
+	}
+
+	installPath := def.InstallPath(mvnenvRoot, ver)
+	if !def.IsValidInstallation(installPath) {
+		return formatError(fmt.Errorf("%s %s is not installed", def.DisplayName, ver))
+	}
+
+	info := installInfo{Tool: def.Name, Version: ver, Path: installPath}
+
+	if info.Manifest, err = versionpkg.ReadManifest(installPath); err != nil {
+		return formatError(err)
+	}
+	if info.DiskSize, err = dirSize(installPath); err != nil {
+		return formatError(fmt.Errorf("measure %s: %w", installPath, err))
+	}
+
+	verification, err := versionpkg.VerifyInstallation(mvnenvRoot, def, ver)
+	if err != nil {
+		return formatError(err)
+	}
+	info.Changes = verification.Changes
+	info.ChangesUnknown = verification.Unknown
+
+	if infoJSON {
+		return printJSON(info)
+	}
+
+	printInstallInfo(def.DisplayName, info)
+	return nil
+}
+
+// printInstallInfo prints installation details as text
+func printInstallInfo(displayName string, info installInfo) {
+	m := info.Manifest
+
+	fmt.Printf("%s %s\n", displayName, info.Version)
+	fmt.Printf("  Path:       %s\n", formatPath(info.Path))
+	fmt.Printf("  Disk size:  %s\n", formatSize(info.DiskSize))
+
+	if m == nil {
+		fmt.Println("  No manifest (installed by an older mvnenv; reinstall with --force to record one)")
+	} else {
+		fmt.Printf("  Installed:  %s\n", m.InstalledAt.Local().Format("2006-01-02 15:04"))
+		fmt.Printf("  Source:     %s\n", m.Source)
+		fmt.Printf("  Archive:    %s (%s)\n", m.Archive, formatSize(m.ArchiveSize))
+		fmt.Printf("  SHA-512:    %s\n", m.ArchiveSHA512)
+		if m.ArchiveSHA256 != "" {
+			fmt.Printf("  SHA-256:    %s\n", m.ArchiveSHA256)
+		}
+		if m.Integrity != nil {
+			fmt.Printf("  Checksum:   %s\n", m.Integrity)
+		} else {
+			fmt.Println("  Checksum:   not checked")
+		}
+		if m.Signature != nil {
+			fmt.Printf("  Signature:  %s\n", m.Signature)
+		} else {
+			fmt.Println("  Signature:  not checked")
+		}
+		if m.Java != "" {
+			fmt.Printf("  Java:       %s\n", m.Java)
+		} else {
+			fmt.Println("  Java:       unknown")
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case info.ChangesUnknown != "":
+		fmt.Printf("Changed files: unknown (%s)\n", info.ChangesUnknown)
+	case len(info.Changes) == 0:
+		fmt.Println("No files differ from the archive")
+	default:
+		fmt.Printf("Changed files (%d):\n", len(info.Changes))
+		for _, c := range info.Changes {
+			fmt.Printf("  %-9s %s\n", c.Change, c.Path)
+		}
+	}
+}
+
+// dirSize returns the total size of the files under path
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
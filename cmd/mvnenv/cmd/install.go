@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/veenone/mvnenv-win/internal/cache"
 	"github.com/veenone/mvnenv-win/internal/repository"
+	"github.com/veenone/mvnenv-win/internal/tool"
 	versionpkg "github.com/veenone/mvnenv-win/internal/version"
 	"github.com/veenone/mvnenv-win/pkg/maven"
 )
@@ -35,7 +37,9 @@ Use "latest" as the version to install the newest available Maven version.`,
 	Example: `  mvnenv install 3.9.4
   mvnenv install latest
   mvnenv install -l
-  mvnenv install -q 3.8.6`,
+  mvnenv install -q 3.8.6
+  mvnenv install --limit-rate 500K 3.9.6
+  mvnenv install --tool mvnd 1.0.2`,
 	RunE: runInstall,
 }
 
@@ -46,18 +50,31 @@ func init() {
 	installCmd.Flags().BoolVarP(&installSkipExisting, "skip-existing", "s", false, "Skip installation if version already exists (no error)")
 	installCmd.Flags().BoolVarP(&installClear, "clear", "c", false, "Clear cache before installing")
 	installCmd.Flags().BoolVar(&installOffline, "offline", false, "Offline mode: only use Nexus (fail if unavailable)")
+	addToolFlag(installCmd)
+	addLimitRateFlag(installCmd)
 	rootCmd.AddCommand(installCmd)
 }
 
 func runInstall(cmd *cobra.Command, args []string) error {
 	mvnenvRoot := getMvnenvRoot()
 
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+	if err := applyLimitRate(cmd); err != nil {
+		return err
+	}
+
 	// Set quiet mode
 	quietMode = installQuiet
 
 	// Handle list flag
 	if installList {
-		return listAvailableVersions()
+		if !def.IsMaven() {
+			return listAvailableToolVersions(cmd.Context(), def)
+		}
+		return listAvailableVersions(cmd.Context())
 	}
 
 	// Require version argument
@@ -81,19 +98,23 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	for _, version := range args {
 		// Handle "latest" keyword
 		if version == "latest" {
-			latestVersion, err := getLatestAvailableVersion(mvnenvRoot)
+			latestVersion, err := getLatestAvailableToolVersion(cmd.Context(), mvnenvRoot, def)
 			if err != nil {
 				failedInstalls = append(failedInstalls, fmt.Sprintf("%s (failed to determine latest: %v)", version, err))
 				continue
 			}
 			version = latestVersion
 			if !installQuiet {
-				fmt.Printf("Installing latest Maven version: %s\n", version)
+				fmt.Printf("Installing latest %s version: %s\n", def.DisplayName, version)
 			}
 		}
 
 		// Install version with flags
-		if err := installSingleVersion(mvnenvRoot, version); err != nil {
+		if err := installSingleVersion(cmd.Context(), mvnenvRoot, def, version); err != nil {
+			// Stop at Ctrl+C rather than moving on to the next version
+			if cmd.Context().Err() != nil {
+				return err
+			}
 			failedInstalls = append(failedInstalls, fmt.Sprintf("%s (%v)", version, err))
 		} else {
 			successfulInstalls = append(successfulInstalls, version)
@@ -122,9 +143,9 @@ func runInstall(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// installSingleVersion installs a single Maven version with flag handling
-func installSingleVersion(mvnenvRoot, version string) error {
-	installer := versionpkg.NewVersionInstaller(mvnenvRoot)
+// installSingleVersion installs a single tool version with flag handling
+func installSingleVersion(ctx context.Context, mvnenvRoot string, def *tool.Definition, version string) error {
+	installer := versionpkg.NewToolInstaller(mvnenvRoot, def)
 
 	// Configure installer based on flags
 	installer.SetForce(installForce)
@@ -133,15 +154,20 @@ func installSingleVersion(mvnenvRoot, version string) error {
 	installer.SetQuiet(installQuiet)
 
 	// Install version
-	if err := installer.InstallVersion(version); err != nil {
+	if err := installer.InstallVersion(ctx, version); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// clearInstallCache clears the download cache
+// clearInstallCache clears the download cache: the archive store and any
+// archives left in cache/ by earlier versions of mvnenv
 func clearInstallCache(mvnenvRoot string) error {
+	if err := cache.NewArchiveStore(mvnenvRoot).Clear(); err != nil {
+		return err
+	}
+
 	cacheDir := filepath.Join(mvnenvRoot, "cache")
 	entries, err := os.ReadDir(cacheDir)
 	if err != nil {
@@ -164,7 +190,7 @@ func clearInstallCache(mvnenvRoot string) error {
 	return nil
 }
 
-func listAvailableVersions() error {
+func listAvailableVersions(ctx context.Context) error {
 	mvnenvRoot := getMvnenvRoot()
 	cacheManager := cache.NewManager(mvnenvRoot)
 
@@ -176,7 +202,7 @@ func listAvailableVersions() error {
 		fmt.Println("Fetching available versions from configured repositories...")
 
 		repoManager := repository.NewManager(mvnenvRoot)
-		versions, err = repoManager.ListVersions()
+		versions, err = repoManager.ListVersions(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to list versions: %w", err)
 		}
@@ -211,8 +237,69 @@ func listAvailableVersions() error {
 	return nil
 }
 
-// getLatestAvailableVersion returns the latest available Maven version
-func getLatestAvailableVersion(mvnenvRoot string) (string, error) {
+// listAvailableToolVersions lists available versions of a tool other than
+// Maven. These lists are small and are not cached.
+func listAvailableToolVersions(ctx context.Context, def *tool.Definition) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	fmt.Printf("Fetching available %s versions from configured repositories...\n", def.DisplayName)
+	versions, err := fetchToolVersions(ctx, mvnenvRoot, def)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) == 0 {
+		fmt.Println("No versions found")
+		return nil
+	}
+
+	fmt.Printf("\nAvailable %s versions:\n", def.DisplayName)
+	for _, v := range versions {
+		fmt.Printf("  %s\n", v)
+	}
+
+	return nil
+}
+
+// getLatestAvailableToolVersion returns the latest available version of a tool
+func getLatestAvailableToolVersion(ctx context.Context, mvnenvRoot string, def *tool.Definition) (string, error) {
+	versions, err := availableToolVersions(ctx, mvnenvRoot, def)
+	if err != nil {
+		return "", err
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("no versions available")
+	}
+	return versions[0], nil
+}
+
+// availableToolVersions returns the available versions of a tool, newest
+// first. Maven versions come from the version cache while it is fresh.
+func availableToolVersions(ctx context.Context, mvnenvRoot string, def *tool.Definition) ([]string, error) {
+	if def.IsMaven() {
+		return getAvailableVersions(ctx, mvnenvRoot)
+	}
+	return fetchToolVersions(ctx, mvnenvRoot, def)
+}
+
+// fetchToolVersions fetches and sorts (newest first) the available versions of a tool
+func fetchToolVersions(ctx context.Context, mvnenvRoot string, def *tool.Definition) ([]string, error) {
+	repoManager := repository.NewManager(mvnenvRoot)
+	versions, err := repoManager.ListToolVersions(ctx, def)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	sorted, err := maven.SortVersions(versions)
+	if err != nil {
+		// If sorting fails, use unsorted
+		return versions, nil
+	}
+	return sorted, nil
+}
+
+// getAvailableVersions returns the available Maven versions, newest first
+func getAvailableVersions(ctx context.Context, mvnenvRoot string) ([]string, error) {
 	cacheManager := cache.NewManager(mvnenvRoot)
 
 	// Try to load from cache first
@@ -221,15 +308,15 @@ func getLatestAvailableVersion(mvnenvRoot string) (string, error) {
 	// If cache doesn't exist or is stale (>24 hours), fetch from repositories
 	if err != nil || len(versions) == 0 || cacheManager.IsCacheStale(24*time.Hour) {
 		repoManager := repository.NewManager(mvnenvRoot)
-		versions, err = repoManager.ListVersions()
+		versions, err = repoManager.ListVersions(ctx)
 		if err != nil {
-			return "", fmt.Errorf("failed to list versions: %w", err)
+			return nil, fmt.Errorf("failed to list versions: %w", err)
 		}
 
 		// Sort versions (newest first)
 		versions, err = maven.SortVersions(versions)
 		if err != nil {
-			return "", fmt.Errorf("failed to sort versions: %w", err)
+			return nil, fmt.Errorf("failed to sort versions: %w", err)
 		}
 
 		// Save to cache
@@ -239,10 +326,5 @@ func getLatestAvailableVersion(mvnenvRoot string) (string, error) {
 		}
 	}
 
-	if len(versions) == 0 {
-		return "", fmt.Errorf("no versions available")
-	}
-
-	// Return the first version (newest)
-	return versions[0], nil
+	return versions, nil
 }
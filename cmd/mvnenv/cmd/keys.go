@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/download"
+	"github.com/veenone/mvnenv-win/internal/signature"
+)
+
+var keysJSON bool
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the keys used to verify distribution signatures",
+	Long: `Manage the OpenPGP keyring used to verify distribution signatures.
+
+Downloaded archives are checked against their detached .asc signature using
+only the keys in %USERPROFILE%\.mvnenv\keys\pubring.asc. The keyring changes
+only when keys are imported, so a compromised download server cannot also
+supply the keys that vouch for its files.
+
+What happens when a signature is missing or invalid is set by
+verification.signature in config.yaml: require, warn (default) or off.`,
+	Example: `  mvnenv keys import
+  mvnenv keys import C:\Downloads\KEYS
+  mvnenv keys list`,
+}
+
+var keysImportCmd = &cobra.Command{
+	Use:   "import [file|url]",
+	Short: "Import public keys into the keyring",
+	Long: `Import public keys from a file or URL into the keyring.
+
+Without an argument, the KEYS file published for the selected tool is
+downloaded (for Maven: https://downloads.apache.org/maven/KEYS). Apache KEYS
+files and armored or binary keyrings are accepted. Keys already in the keyring
+are updated.`,
+	Example: `  mvnenv keys import
+  mvnenv keys import --tool mvnd
+  mvnenv keys import https://downloads.apache.org/maven/KEYS
+  mvnenv keys import C:\Downloads\KEYS`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runKeysImport,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the keys in the keyring",
+	Example: `  mvnenv keys list`,
+	Args:    cobra.NoArgs,
+	RunE:    runKeysList,
+}
+
+func init() {
+	keysCmd.PersistentFlags().BoolVar(&keysJSON, "json", false, "Print results as JSON")
+	addToolFlag(keysImportCmd)
+
+	keysCmd.AddCommand(keysImportCmd, keysListCmd)
+	rootCmd.AddCommand(keysCmd)
+}
+
+func runKeysImport(cmd *cobra.Command, args []string) error {
+	source := ""
+	if len(args) > 0 {
+		source = args[0]
+	} else {
+		def, err := selectedTool()
+		if err != nil {
+			return formatError(err)
+		}
+		if def.KeysURL == "" {
+			return formatError(fmt.Errorf("%s does not declare a KEYS URL; pass a file or URL to import", def.DisplayName))
+		}
+		source = def.KeysURL
+	}
+
+	data, err := readKeySource(cmd.Context(), source)
+	if err != nil {
+		return formatError(err)
+	}
+
+	keyring := signature.NewKeyring(getMvnenvRoot())
+	keys, added, err := keyring.Import(data)
+	if err != nil {
+		return formatError(fmt.Errorf("import keys from %s: %w", source, err))
+	}
+
+	if keysJSON {
+		return printJSON(struct {
+			Source   string          `json:"source"`
+			Imported []signature.Key `json:"imported"`
+			Added    int             `json:"added"`
+		}{source, keys, added})
+	}
+
+	fmt.Printf("Imported %d keys from %s (%d new, %d updated)\n", len(keys), source, added, len(keys)-added)
+	fmt.Printf("Keyring: %s\n", formatPath(keyring.Path()))
+	return nil
+}
+
+func runKeysList(cmd *cobra.Command, args []string) error {
+	keys, err := signature.NewKeyring(getMvnenvRoot()).Keys()
+	if err != nil {
+		return formatError(err)
+	}
+
+	if keysJSON {
+		return printJSON(keys)
+	}
+
+	if len(keys) == 0 {
+		fmt.Println("The keyring is empty. Import keys with: mvnenv keys import")
+		return nil
+	}
+
+	for _, k := range keys {
+		fmt.Printf("%s  %s  created %s\n", k.KeyID, k.Fingerprint, k.Created.Format("2006-01-02"))
+		for _, uid := range k.UserIDs {
+			fmt.Printf("    %s\n", uid)
+		}
+	}
+	fmt.Printf("\n%d keys\n", len(keys))
+	return nil
+}
+
+// readKeySource reads keys from a local file or an http(s) URL
+func readKeySource(ctx context.Context, source string) ([]byte, error) {
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		data, err := download.NewDownloader().Fetch(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("download %s: %w", source, err)
+		}
+		return data, nil
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", source, err)
+	}
+	return data, nil
+}
@@ -58,7 +58,7 @@ func runLatest(cmd *cobra.Command, args []string) error {
 			// Cache doesn't exist or is empty, fetch from repositories
 			fmt.Println("Fetching versions from configured repositories...")
 			repoManager := repository.NewManager(mvnenvRoot)
-			versions, err = repoManager.ListVersions()
+			versions, err = repoManager.ListVersions(cmd.Context())
 			if err != nil {
 				return formatError(fmt.Errorf("fetch versions: %w", err))
 			}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+)
+
+var (
+	// limitRate caps the bandwidth of a command's transfers
+	limitRate string
+)
+
+// addLimitRateFlag registers the --limit-rate flag on a command
+func addLimitRateFlag(c *cobra.Command) {
+	c.Flags().StringVar(&limitRate, "limit-rate", "",
+		"Limit the bandwidth of all transfers together, e.g. 500K or 2M bytes per second (0 = unlimited; default from network.limit_rate)")
+}
+
+// applyLimitRate sets the rate limit given with --limit-rate, overriding
+// the one from config.yaml
+func applyLimitRate(c *cobra.Command) error {
+	if !c.Flags().Changed("limit-rate") {
+		return nil
+	}
+	rate, err := httpclient.ParseRate(limitRate)
+	if err != nil {
+		return formatError(err)
+	}
+	httpclient.SetRateLimit(rate)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/config"
 	"github.com/veenone/mvnenv-win/internal/version"
 )
 
@@ -15,14 +16,17 @@ var localCmd = &cobra.Command{
 
 This creates a .maven-version file in the current directory that specifies
 which Maven version to use. This setting takes precedence over the global
-version but is overridden by the shell version.`,
+version but is overridden by the shell version. The directory is registered
+as a project, so "mvnenv uninstall" refuses to remove the version it uses.`,
 	Example: `  mvnenv local 3.8.6
-  mvnenv local 3.9.4`,
+  mvnenv local 3.9.4
+  mvnenv local --tool mvnd 1.0.2`,
 	Args: cobra.ExactArgs(1),
 	RunE: runLocal,
 }
 
 func init() {
+	addToolFlag(localCmd)
 	rootCmd.AddCommand(localCmd)
 }
 
@@ -30,16 +34,28 @@ func runLocal(cmd *cobra.Command, args []string) error {
 	ver := args[0]
 	mvnenvRoot := getMvnenvRoot()
 
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+
 	// Verify version is installed
-	resolver := version.NewVersionResolver(mvnenvRoot)
+	resolver := version.NewToolResolver(mvnenvRoot, def)
 	if !resolver.IsVersionInstalled(ver) {
 		return fmt.Errorf("version '%s' not installed", ver)
 	}
 
-	// Write .maven-version file in current directory
-	versionFile := ".maven-version"
+	// Write the tool's version file (.maven-version for Maven) in current directory
+	versionFile := def.VersionFile
 	if err := os.WriteFile(versionFile, []byte(ver), 0644); err != nil {
-		return fmt.Errorf("failed to write .maven-version file: %w", err)
+		return fmt.Errorf("failed to write %s file: %w", versionFile, err)
+	}
+
+	// Register the project so uninstall knows the version is in use here
+	if cwd, err := os.Getwd(); err == nil {
+		if err := config.NewManager(mvnenvRoot).AddProject(cwd); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not register project: %v\n", err)
+		}
 	}
 
 	fmt.Printf("%s\n", ver)
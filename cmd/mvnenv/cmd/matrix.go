@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/matrix"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+var (
+	matrixVersions   string
+	matrixParallel   int
+	matrixReportsDir string
+	matrixNoInstall  bool
+	matrixJSON       bool
+)
+
+var matrixCmd = &cobra.Command{
+	Use:   "matrix --versions <v1,v2,...> -- <command> [args...]",
+	Short: "Run a command against several Maven versions",
+	Long: `Run the same command once per Maven version and report the results.
+
+Missing versions are installed first (disable with --no-install). Each run's
+output is written to <reports-dir>/maven-<version>.log and a machine-readable
+summary.json is written next to the logs. A pass/fail table with durations is
+printed at the end.
+
+Runs are sequential by default. Use --parallel to run several versions at the
+same time; note that parallel builds of the same project share its target
+directory.
+
+The exit code is non-zero if the command failed for any version.`,
+	Example: `  mvnenv matrix --versions 3.8.8,3.9.6,4.0.0-rc-2 -- mvn -B verify
+  mvnenv matrix --versions 3.8.8,3.9.6 --parallel 2 --reports-dir build/matrix -- mvn -B -q test
+  mvnenv matrix --versions 3.9.6,3.9.9 --json -- mvn -B verify`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMatrix,
+}
+
+func init() {
+	matrixCmd.Flags().StringVar(&matrixVersions, "versions", "", "Comma-separated Maven versions to run against (required)")
+	matrixCmd.Flags().IntVarP(&matrixParallel, "parallel", "p", 1, "Maximum number of versions to run at the same time")
+	matrixCmd.Flags().StringVar(&matrixReportsDir, "reports-dir", "mvnenv-matrix", "Directory for per-version logs and summary.json")
+	matrixCmd.Flags().BoolVar(&matrixNoInstall, "no-install", false, "Fail versions that are not installed instead of installing them")
+	matrixCmd.Flags().BoolVar(&matrixJSON, "json", false, "Print the JSON summary to stdout instead of the table")
+	addLimitRateFlag(matrixCmd)
+	matrixCmd.MarkFlagRequired("versions")
+	// Everything after the command name belongs to the command
+	matrixCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(matrixCmd)
+}
+
+func runMatrix(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	versions, err := parseVersionList(matrixVersions)
+	if err != nil {
+		return formatError(err)
+	}
+	if err := applyLimitRate(cmd); err != nil {
+		return err
+	}
+
+	// Progress goes to stderr in JSON mode so stdout stays parseable
+	out := os.Stdout
+	if matrixJSON {
+		out = os.Stderr
+	}
+
+	// Install missing versions up front so runs don't race on installs
+	if !matrixNoInstall {
+		resolver := versionpkg.NewVersionResolver(mvnenvRoot)
+		for _, v := range versions {
+			if resolver.IsVersionInstalled(v) {
+				continue
+			}
+			fmt.Fprintf(out, "Installing Maven %s...\n", v)
+			installer := versionpkg.NewVersionInstaller(mvnenvRoot)
+			installer.SetSkipExisting(true)
+			installer.SetQuiet(true)
+			if err := installer.InstallVersion(cmd.Context(), v); err != nil {
+				if cmd.Context().Err() != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Warning: Failed to install Maven %s: %v\n", v, err)
+			}
+		}
+	}
+
+	runner := matrix.NewRunner(mvnenvRoot, matrixReportsDir)
+	runner.SetParallel(matrixParallel)
+	runner.OnStart(func(v string) {
+		fmt.Fprintf(out, "→ Maven %s: running %s\n", v, strings.Join(args, " "))
+	})
+	runner.OnFinish(func(r matrix.Result) {
+		mark := "✓"
+		if r.Status != matrix.StatusPass {
+			mark = "✗"
+		}
+		fmt.Fprintf(out, "%s Maven %s: %s (%s)\n", mark, r.Version, r.Status, formatDuration(r.Duration))
+	})
+
+	summary, err := runner.Run(versions, args)
+	if err != nil {
+		return formatError(err)
+	}
+
+	summaryPath := filepath.Join(matrixReportsDir, "summary.json")
+	if err := matrix.WriteSummary(summary, summaryPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	if matrixJSON {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return formatError(fmt.Errorf("marshal summary: %w", err))
+		}
+		fmt.Println(string(data))
+	} else {
+		printMatrixTable(summary)
+		fmt.Printf("\nLogs and summary written to %s\n", formatPath(matrixReportsDir))
+	}
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d versions failed", summary.Failed, len(summary.Results))
+	}
+
+	return nil
+}
+
+// printMatrixTable prints the pass/fail summary table
+func printMatrixTable(summary *matrix.Summary) {
+	width := len("VERSION")
+	for _, r := range summary.Results {
+		if len(r.Version) > width {
+			width = len(r.Version)
+		}
+	}
+
+	fmt.Println("\nMatrix Summary")
+	fmt.Println("==============")
+	fmt.Printf("%-*s  %-6s  %-5s  %s\n", width, "VERSION", "STATUS", "EXIT", "DURATION")
+	for _, r := range summary.Results {
+		fmt.Printf("%-*s  %-6s  %-5d  %s\n", width, r.Version, strings.ToUpper(string(r.Status)), r.ExitCode, formatDuration(r.Duration))
+	}
+	fmt.Printf("\nPassed: %d  Failed: %d\n", summary.Passed, summary.Failed)
+}
+
+// parseVersionList splits a comma-separated version list and validates each entry
+func parseVersionList(list string) ([]string, error) {
+	var versions []string
+	seen := make(map[string]bool)
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		if err := validateVersionFormat(v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+		seen[v] = true
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no versions given")
+	}
+
+	return versions, nil
+}
+
+// formatDuration rounds a duration for display
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/tool"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+var (
+	overlayAll  bool
+	overlayJSON bool
+)
+
+var overlayCmd = &cobra.Command{
+	Use:   "overlay",
+	Short: "List and apply configuration overlays",
+	Long: `Manage the overlays declared under overlays: in config.yaml.
+
+An overlay is a set of files, such as a corporate conf/settings.xml, copied
+over an installation after it is extracted. Its files come from the directory
+%USERPROFILE%\.mvnenv\overlays\<name>, the archive overlays\<name>.zip, or a
+zip fetched from Nexus. A version range selects which versions get it.
+
+New installations get their overlays automatically; "overlay apply" applies
+them again to versions that are already installed.`,
+	Example: `  mvnenv overlay list
+  mvnenv overlay apply 3.9.6
+  mvnenv overlay apply --all`,
+}
+
+var overlayApplyCmd = &cobra.Command{
+	Use:   "apply [version]",
+	Short: "Apply overlays to installed versions",
+	Example: `  mvnenv overlay apply 3.9.6
+  mvnenv overlay apply --all
+  mvnenv overlay apply --tool mvnd 1.0.2`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runOverlayApply,
+}
+
+var overlayListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List configured overlays",
+	Example: `  mvnenv overlay list --json`,
+	Args:    cobra.NoArgs,
+	RunE:    runOverlayList,
+}
+
+func init() {
+	overlayApplyCmd.Flags().BoolVar(&overlayAll, "all", false, "Apply to every installed version of the tool")
+	addToolFlag(overlayApplyCmd)
+	overlayListCmd.Flags().BoolVar(&overlayJSON, "json", false, "Print results as JSON")
+
+	overlayCmd.AddCommand(overlayApplyCmd, overlayListCmd)
+	rootCmd.AddCommand(overlayCmd)
+}
+
+func runOverlayApply(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	if overlayAll == (len(args) == 1) {
+		return formatError(fmt.Errorf("specify a version or --all"))
+	}
+
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+
+	var versions []string
+	if overlayAll {
+		if versions, err = versionpkg.NewToolLister(mvnenvRoot, def).ListInstalled(); err != nil {
+			return formatError(err)
+		}
+	} else {
+		if err := validateVersionFormat(args[0]); err != nil {
+			return formatError(err)
+		}
+		versions = args
+	}
+
+	installer := versionpkg.NewToolInstaller(mvnenvRoot, def)
+	for _, v := range versions {
+		applied, err := installer.ApplyOverlays(cmd.Context(), v)
+		if err != nil {
+			return formatError(fmt.Errorf("%s %s: %w", def.DisplayName, v, err))
+		}
+		if len(applied) == 0 {
+			fmt.Printf("No overlays configured for %s %s\n", def.DisplayName, v)
+			continue
+		}
+		for _, o := range applied {
+			fmt.Printf("Applied overlay %s to %s %s (%d files)\n", o.Name, def.DisplayName, v, len(o.Files))
+		}
+	}
+	return nil
+}
+
+func runOverlayList(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	cfg, err := config.NewManager(mvnenvRoot).Load()
+	if err != nil {
+		return formatError(err)
+	}
+
+	if overlayJSON {
+		overlays := cfg.Overlays
+		if overlays == nil {
+			overlays = []config.OverlayConfig{}
+		}
+		return printJSON(overlays)
+	}
+
+	if len(cfg.Overlays) == 0 {
+		fmt.Println("No overlays configured")
+		return nil
+	}
+
+	fmt.Printf("%-20s  %-8s  %-16s  %s\n", "NAME", "TOOL", "VERSIONS", "SOURCE")
+	for _, o := range cfg.Overlays {
+		toolName := o.Tool
+		if toolName == "" {
+			toolName = tool.NameMaven
+		}
+		versions := o.Versions
+		if versions == "" {
+			versions = "all"
+		}
+		fmt.Printf("%-20s  %-8s  %-16s  %s\n", o.Name, toolName, versions, overlaySourceLabel(mvnenvRoot, o))
+	}
+	return nil
+}
+
+// overlaySourceLabel describes where an overlay's files come from
+func overlaySourceLabel(mvnenvRoot string, o config.OverlayConfig) string {
+	if o.Artifact != "" {
+		return "Nexus " + o.Artifact
+	}
+	dir := filepath.Join(mvnenvRoot, versionpkg.OverlaysDir, o.Name)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return formatPath(dir)
+	}
+	if _, err := os.Stat(dir + ".zip"); err == nil {
+		return formatPath(dir + ".zip")
+	}
+	return "missing (" + formatPath(dir) + ")"
+}
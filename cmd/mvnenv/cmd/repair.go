@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+var repairCmd = &cobra.Command{
+	Use:   "repair <version>",
+	Short: "Restore an installed version from its archive",
+	Long: `Restore an installed version to its original state.
+
+Modified and missing files are extracted again from the cached archive and
+added files are removed. Extensions added with "mvnenv ext add" are put back.
+Files matching verification.allow in config.yaml are left alone. If the
+archive is no longer cached, it is downloaded again and checked like an
+install.`,
+	Example: `  mvnenv repair 3.9.6
+  mvnenv repair --tool mvnd 1.0.2`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRepair,
+}
+
+func init() {
+	addToolFlag(repairCmd)
+	rootCmd.AddCommand(repairCmd)
+}
+
+func runRepair(cmd *cobra.Command, args []string) error {
+	ver := args[0]
+
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+	if err := validateVersionFormat(ver); err != nil {
+		return formatError(err)
+	}
+
+	installer := versionpkg.NewToolInstaller(getMvnenvRoot(), def)
+	changes, err := installer.RepairVersion(cmd.Context(), ver)
+	if err != nil {
+		return formatError(err)
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("%s %s is unchanged, nothing to repair\n", def.DisplayName, ver)
+		return nil
+	}
+
+	for _, c := range changes {
+		action := "restored"
+		if c.Change == versionpkg.FileAdded {
+			action = "removed"
+		}
+		fmt.Printf("  %-9s %s\n", action, c.Path)
+	}
+	fmt.Printf("%s %s repaired (%d files)\n", def.DisplayName, ver, len(changes))
+	return nil
+}
@@ -1,14 +1,23 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/veenone/mvnenv-win/cmd/mvnenv/plugins"
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+	"github.com/veenone/mvnenv-win/internal/progress"
+	"github.com/veenone/mvnenv-win/internal/tool"
 )
 
-var appVersion string
+var (
+	appVersion   string
+	progressMode string
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "mvnenv",
@@ -28,11 +37,47 @@ Features:
   mvnenv local 3.8.6
   mvn --version`,
 	SilenceUsage: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flags().Changed("progress") {
+			return nil
+		}
+		mode, err := progress.ParseMode(progressMode)
+		if err != nil {
+			return formatError(err)
+		}
+		progress.SetMode(mode)
+		return nil
+	},
 }
 
 // Execute runs the root command
 func Execute() error {
-	return rootCmd.Execute()
+	// Make tools declared in config.yaml available to --tool
+	if err := tool.LoadConfigured(getMvnenvRoot()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load tool definitions: %v\n", err)
+	}
+	if err := tool.MigrateInstallations(getMvnenvRoot()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to move tool installations: %v\n", err)
+	}
+	if err := httpclient.LoadConfigured(getMvnenvRoot()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to load network settings: %v\n", err)
+	}
+
+	// Ctrl+C cancels the command's context so downloads and installs stop
+	// and clean up after themselves; a second Ctrl+C exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	if err != nil && ctx.Err() != nil {
+		fmt.Println()
+		return fmt.Errorf("interrupted")
+	}
+	return err
 }
 
 // SetVersion sets the application version
@@ -45,6 +90,9 @@ func init() {
 	// Disable default completion command
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
+	rootCmd.PersistentFlags().StringVar(&progressMode, "progress", "auto",
+		"Progress output: auto, tty, plain, json (NDJSON events on stderr) or none; default from "+progress.ModeEnv)
+
 	// Register plugin commands
 	for _, plugin := range plugins.GetAll() {
 		rootCmd.AddCommand(plugin.Command())
@@ -26,6 +26,7 @@ After running this command, you need to set the environment variable manually:
 }
 
 func init() {
+	addToolFlag(shellCmd)
 	rootCmd.AddCommand(shellCmd)
 }
 
@@ -33,18 +34,24 @@ func runShell(cmd *cobra.Command, args []string) error {
 	ver := args[0]
 	mvnenvRoot := getMvnenvRoot()
 
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+
 	// Verify version is installed
-	resolver := version.NewVersionResolver(mvnenvRoot)
+	resolver := version.NewToolResolver(mvnenvRoot, def)
 	if !resolver.IsVersionInstalled(ver) {
 		return fmt.Errorf("version '%s' not installed", ver)
 	}
 
 	// Output instructions for setting environment variable
+	envVar := def.VersionEnv
 	fmt.Printf("%s\n", ver)
 	fmt.Println()
 	fmt.Println("To set this version in your current shell session:")
-	fmt.Println("  PowerShell: $env:MVNENV_MAVEN_VERSION = \"" + ver + "\"")
-	fmt.Println("  cmd.exe: set MVNENV_MAVEN_VERSION=" + ver)
+	fmt.Println("  PowerShell: $env:" + envVar + " = \"" + ver + "\"")
+	fmt.Println("  cmd.exe: set " + envVar + "=" + ver)
 
 	return nil
 }
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -44,7 +45,7 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Current Maven version: %s (set by %s)\n", currentVersion, resolved.Source)
 
 	// Get latest available version
-	latestVersion, err := getLatestVersion(mvnenvRoot)
+	latestVersion, err := getLatestVersion(cmd.Context(), mvnenvRoot)
 	if err != nil {
 		return formatError(fmt.Errorf("failed to determine latest version: %w", err))
 	}
@@ -63,7 +64,7 @@ func runStatus(cmd *cobra.Command, args []string) error {
 }
 
 // getLatestVersion returns the latest available Maven version
-func getLatestVersion(mvnenvRoot string) (string, error) {
+func getLatestVersion(ctx context.Context, mvnenvRoot string) (string, error) {
 	cacheManager := cache.NewManager(mvnenvRoot)
 
 	// Try to load from cache first
@@ -72,7 +73,7 @@ func getLatestVersion(mvnenvRoot string) (string, error) {
 	// If cache doesn't exist or is empty, fetch from repositories
 	if err != nil || len(versions) == 0 {
 		repoManager := repository.NewManager(mvnenvRoot)
-		versions, err = repoManager.ListVersions()
+		versions, err = repoManager.ListVersions(ctx)
 		if err != nil {
 			return "", fmt.Errorf("failed to list versions: %w", err)
 		}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/tool"
+)
+
+var (
+	// toolName selects which managed tool a command operates on
+	toolName string
+)
+
+// addToolFlag registers the --tool flag on a command
+func addToolFlag(c *cobra.Command) {
+	c.Flags().StringVar(&toolName, "tool", tool.NameMaven,
+		fmt.Sprintf("Tool to operate on (%s, or one declared in config.yaml)", strings.Join(tool.Names(), ", ")))
+}
+
+// selectedTool returns the definition of the tool chosen with --tool
+func selectedTool() (*tool.Definition, error) {
+	return tool.Get(toolName)
+}
+
+// toolHint returns the --tool argument to repeat in command hints
+func toolHint(def *tool.Definition) string {
+	if def.IsMaven() {
+		return ""
+	}
+	return "--tool " + def.Name + " "
+}
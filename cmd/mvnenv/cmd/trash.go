@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+var trashJSON bool
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List, restore or empty uninstalled versions",
+	Long: `Manage versions removed by "mvnenv uninstall".
+
+Uninstalled versions are kept in %USERPROFILE%\.mvnenv\trash for 7 days and
+can be restored until then. Older entries are deleted the next time a
+version is uninstalled.`,
+	Example: `  mvnenv trash list
+  mvnenv trash restore 3.9.4
+  mvnenv trash empty`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List uninstalled versions that can be restored",
+	Example: `  mvnenv trash list`,
+	Args:    cobra.NoArgs,
+	RunE:    runTrashList,
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <version>",
+	Short: "Restore an uninstalled version",
+	Example: `  mvnenv trash restore 3.9.4
+  mvnenv trash restore --tool mvnd 1.0.2`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTrashRestore,
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:     "empty",
+	Short:   "Permanently delete all uninstalled versions",
+	Example: `  mvnenv trash empty`,
+	Args:    cobra.NoArgs,
+	RunE:    runTrashEmpty,
+}
+
+func init() {
+	trashListCmd.Flags().BoolVar(&trashJSON, "json", false, "Print results as JSON")
+	addToolFlag(trashRestoreCmd)
+
+	trashCmd.AddCommand(trashListCmd, trashRestoreCmd, trashEmptyCmd)
+	rootCmd.AddCommand(trashCmd)
+}
+
+func runTrashList(cmd *cobra.Command, args []string) error {
+	entries, err := versionpkg.ListTrash(getMvnenvRoot())
+	if err != nil {
+		return formatError(err)
+	}
+
+	if trashJSON {
+		if entries == nil {
+			entries = []versionpkg.TrashEntry{}
+		}
+		return printJSON(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Trash is empty")
+		return nil
+	}
+
+	fmt.Printf("%-22s  %-16s  %s\n", "TOOL/VERSION", "UNINSTALLED", "EXPIRES IN")
+	for _, e := range entries {
+		expires := "expired"
+		if left := time.Until(e.Expires()); left > 0 {
+			expires = formatAge(left)
+		}
+		fmt.Printf("%-22s  %-16s  %s\n", e.Tool+" "+e.Version,
+			e.TrashedAt.Local().Format("2006-01-02 15:04"), expires)
+	}
+	return nil
+}
+
+func runTrashRestore(cmd *cobra.Command, args []string) error {
+	ver := args[0]
+
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+	if err := validateVersionFormat(ver); err != nil {
+		return formatError(err)
+	}
+
+	installer := versionpkg.NewToolInstaller(getMvnenvRoot(), def)
+	if err := installer.RestoreVersion(ver); err != nil {
+		return formatError(err)
+	}
+
+	fmt.Printf("%s %s restored\n", def.DisplayName, ver)
+	return nil
+}
+
+func runTrashEmpty(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	purged, err := versionpkg.PurgeTrash(mvnenvRoot, true)
+	if err != nil {
+		return formatError(err)
+	}
+
+	fmt.Printf("Deleted %d versions from %s\n", len(purged), formatPath(filepath.Join(mvnenvRoot, "trash")))
+	return nil
+}
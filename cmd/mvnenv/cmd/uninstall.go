@@ -1,36 +1,106 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 	versionpkg "github.com/veenone/mvnenv-win/internal/version"
 )
 
+var (
+	uninstallForce     bool
+	uninstallAllExcept []string
+)
+
 var uninstallCmd = &cobra.Command{
-	Use:   "uninstall <version>",
-	Short: "Uninstall a Maven version",
-	Long: `Remove an installed Maven version.
+	Use:   "uninstall <version>...",
+	Short: "Uninstall Maven versions",
+	Long: `Remove installed Maven versions.
+
+Uninstalled versions are moved to the trash, from which "mvnenv trash restore"
+brings them back for 7 days.
 
-Removes the specified Maven version from the mvnenv versions directory.
-This frees up disk space and removes the version from the available versions list.`,
+A version is refused while it is the global version, the shell version, or
+the version in a .maven-version file of the current directory or of a project
+registered with "mvnenv local". Use --force to uninstall it anyway.`,
 	Example: `  mvnenv uninstall 3.8.6
-  mvnenv uninstall 3.9.4`,
-	Args: cobra.ExactArgs(1),
+  mvnenv uninstall 3.8.6 3.8.8
+  mvnenv uninstall --all-except 3.9.6,3.8.8
+  mvnenv uninstall --force 3.9.4
+  mvnenv uninstall --tool mvnd 1.0.2`,
 	RunE: runUninstall,
 }
 
 func init() {
+	uninstallCmd.Flags().BoolVarP(&uninstallForce, "force", "f", false, "Uninstall versions that are in use")
+	uninstallCmd.Flags().StringSliceVar(&uninstallAllExcept, "all-except", nil, "Uninstall every installed version except these (comma-separated)")
+	addToolFlag(uninstallCmd)
 	rootCmd.AddCommand(uninstallCmd)
 }
 
 func runUninstall(cmd *cobra.Command, args []string) error {
-	ver := args[0]
 	mvnenvRoot := getMvnenvRoot()
 
-	// Uninstall version
-	installer := versionpkg.NewVersionInstaller(mvnenvRoot)
-	if err := installer.UninstallVersion(ver); err != nil {
+	def, err := selectedTool()
+	if err != nil {
 		return formatError(err)
 	}
 
+	allExcept := cmd.Flags().Changed("all-except")
+	switch {
+	case allExcept && len(args) > 0:
+		return formatError(fmt.Errorf("--all-except cannot be combined with version arguments"))
+	case !allExcept && len(args) == 0:
+		return formatError(fmt.Errorf("specify at least one version, or --all-except"))
+	}
+
+	versions := args
+	if allExcept {
+		installed, err := versionpkg.NewToolLister(mvnenvRoot, def).ListInstalled()
+		if err != nil {
+			return formatError(err)
+		}
+		keep := make(map[string]bool)
+		for _, v := range uninstallAllExcept {
+			keep[v] = true
+		}
+		versions = nil
+		for _, v := range installed {
+			if !keep[v] {
+				versions = append(versions, v)
+			}
+		}
+		if len(versions) == 0 {
+			fmt.Println("Nothing to uninstall")
+			return nil
+		}
+	}
+
+	installer := versionpkg.NewToolInstaller(mvnenvRoot, def)
+	installer.SetForce(uninstallForce)
+
+	if len(versions) == 1 {
+		if err := validateVersionFormat(versions[0]); err != nil {
+			return formatError(err)
+		}
+		return formatError(installer.UninstallVersion(versions[0]))
+	}
+
+	// Keep going after a refusal so one version in use doesn't block the rest
+	failed := 0
+	for _, ver := range versions {
+		err := validateVersionFormat(ver)
+		if err == nil {
+			err = installer.UninstallVersion(ver)
+		}
+		if err != nil {
+			printError("%v", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d versions were not uninstalled", failed, len(versions))
+	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/veenone/mvnenv-win/internal/cache"
+	"github.com/veenone/mvnenv-win/internal/progress"
 	"github.com/veenone/mvnenv-win/internal/repository"
 	"github.com/veenone/mvnenv-win/pkg/maven"
 )
@@ -27,14 +28,15 @@ func init() {
 func runUpdate(cmd *cobra.Command, args []string) error {
 	mvnenvRoot := getMvnenvRoot()
 
-	fmt.Println("Fetching available Maven versions from configured repositories...")
-
 	// Fetch versions from all repositories (Apache + Nexus if configured)
+	task := progress.Default().Start(progress.KindList, "available Maven versions")
 	repoManager := repository.NewManager(mvnenvRoot)
-	versions, err := repoManager.ListVersions()
+	versions, err := repoManager.ListVersions(progress.WithTask(cmd.Context(), task))
 	if err != nil {
+		task.Fail(err)
 		return formatError(err)
 	}
+	task.Done(fmt.Sprintf("%d versions", len(versions)))
 
 	// Sort versions (newest first)
 	sortedVersions, err := maven.SortVersions(versions)
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/config"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+	"github.com/veenone/mvnenv-win/pkg/maven"
+)
+
+var (
+	upgradeGlobal    bool
+	upgradeLocal     bool
+	upgradeScope     string
+	upgradeUninstall bool
+	upgradeDryRun    bool
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Move version pins to the newest release",
+	Long: `Upgrade the global version and the local version file to the newest available
+version within a scope of the version they pin, installing it if needed.
+
+The scope is patch by default (3.9.4 to 3.9.9), minor (3.8.8 to 3.9.9) or
+major. Pre-releases are only chosen when the pinned version is one.
+
+Without --global or --local, both pins are upgraded where they are set. The
+local pin is the .maven-version file that applies to the current directory.
+With --uninstall, a superseded version is uninstalled once nothing else
+selects it.`,
+	Example: `  mvnenv upgrade
+  mvnenv upgrade --global --scope minor
+  mvnenv upgrade --local --uninstall
+  mvnenv upgrade --dry-run
+  mvnenv upgrade --tool mvnd`,
+	Args: cobra.NoArgs,
+	RunE: runUpgrade,
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradeGlobal, "global", false, "Upgrade the global version")
+	upgradeCmd.Flags().BoolVar(&upgradeLocal, "local", false, "Upgrade the local version file")
+	upgradeCmd.Flags().StringVar(&upgradeScope, "scope", string(maven.ScopePatch), "How far to upgrade: patch, minor or major")
+	upgradeCmd.Flags().BoolVar(&upgradeUninstall, "uninstall", false, "Uninstall superseded versions nothing else uses")
+	upgradeCmd.Flags().BoolVarP(&upgradeDryRun, "dry-run", "n", false, "Show what would change without changing it")
+	addToolFlag(upgradeCmd)
+	addLimitRateFlag(upgradeCmd)
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+// versionPin is a place that pins a version: the global setting or a
+// version file
+type versionPin struct {
+	label   string
+	version string
+	target  string
+	set     func(version string) error
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+	scope, err := maven.ParseScope(upgradeScope)
+	if err != nil {
+		return formatError(err)
+	}
+	if err := applyLimitRate(cmd); err != nil {
+		return err
+	}
+
+	// Neither flag means both pins, skipping one that isn't set
+	both := !upgradeGlobal && !upgradeLocal
+	configMgr := config.NewManager(mvnenvRoot)
+	resolver := versionpkg.NewToolResolver(mvnenvRoot, def)
+
+	var pins []*versionPin
+	if upgradeGlobal || both {
+		global, err := configMgr.GetToolGlobalVersion(def.Name)
+		if err != nil {
+			return formatError(fmt.Errorf("failed to read configuration: %w", err))
+		}
+		if global != "" {
+			pins = append(pins, &versionPin{
+				label:   "config.yaml (global)",
+				version: global,
+				set: func(version string) error {
+					return configMgr.SetToolGlobalVersion(def.Name, version)
+				},
+			})
+		} else if !both {
+			return formatError(fmt.Errorf("no global %s version set (use 'mvnenv global %s<version>')", def.DisplayName, toolHint(def)))
+		}
+	}
+	if upgradeLocal || both {
+		path, local, ok := resolver.LocalVersionFile()
+		if ok {
+			pins = append(pins, &versionPin{
+				label:   formatPath(path),
+				version: local,
+				set: func(version string) error {
+					if err := os.WriteFile(path, []byte(version), 0644); err != nil {
+						return fmt.Errorf("failed to write %s: %w", path, err)
+					}
+					// Register the project so uninstall knows the new version is in use
+					return configMgr.AddProject(filepath.Dir(path))
+				},
+			})
+		} else if !both {
+			return formatError(fmt.Errorf("no %s file in the current directory or its parents (use 'mvnenv local %s<version>')", def.VersionFile, toolHint(def)))
+		}
+	}
+	if len(pins) == 0 {
+		return formatError(fmt.Errorf("no %s version is pinned (use 'mvnenv global' or 'mvnenv local')", def.DisplayName))
+	}
+
+	available, err := availableToolVersions(cmd.Context(), mvnenvRoot, def)
+	if err != nil {
+		return formatError(err)
+	}
+
+	var changed []*versionPin
+	for _, pin := range pins {
+		target, ok, err := maven.NewestInScope(pin.version, available, scope)
+		if err != nil {
+			return formatError(fmt.Errorf("%s: %w", pin.label, err))
+		}
+		if !ok {
+			fmt.Printf("%s: %s %s is up to date (scope: %s)\n", pin.label, def.DisplayName, pin.version, scope)
+			continue
+		}
+		pin.target = target
+		changed = append(changed, pin)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if upgradeDryRun {
+		printPinDiff(changed)
+		fmt.Println("Dry run: nothing was changed")
+		return nil
+	}
+
+	installer := versionpkg.NewToolInstaller(mvnenvRoot, def)
+	installer.SetSkipExisting(true)
+	targets := make(map[string]bool)
+	for _, pin := range changed {
+		if targets[pin.target] {
+			continue
+		}
+		targets[pin.target] = true
+		if resolver.IsVersionInstalled(pin.target) {
+			continue
+		}
+		if err := installer.InstallVersion(cmd.Context(), pin.target); err != nil {
+			return formatError(fmt.Errorf("install %s %s: %w", def.DisplayName, pin.target, err))
+		}
+	}
+
+	for _, pin := range changed {
+		if err := pin.set(pin.target); err != nil {
+			return formatError(fmt.Errorf("update %s: %w", pin.label, err))
+		}
+	}
+	printPinDiff(changed)
+
+	if !upgradeUninstall {
+		return nil
+	}
+
+	// Only uninstall once no pin, shell or registered project selects it
+	superseded := make(map[string]bool)
+	for _, pin := range changed {
+		if superseded[pin.version] || targets[pin.version] {
+			continue
+		}
+		superseded[pin.version] = true
+
+		if usages := resolver.FindUsages(pin.version); len(usages) > 0 {
+			fmt.Printf("Kept %s %s, still selected as:\n", def.DisplayName, pin.version)
+			for _, u := range usages {
+				fmt.Printf("  - %s\n", u)
+			}
+			continue
+		}
+		if !resolver.IsVersionInstalled(pin.version) {
+			continue
+		}
+		if err := installer.UninstallVersion(pin.version); err != nil {
+			printError("%v", err)
+		}
+	}
+	return nil
+}
+
+// printPinDiff shows the old and new version of each pin
+func printPinDiff(pins []*versionPin) {
+	for _, pin := range pins {
+		fmt.Println(pin.label)
+		fmt.Printf("  - %s\n", pin.version)
+		fmt.Printf("  + %s\n", pin.target)
+	}
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/tool"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+var (
+	verifyAll  bool
+	verifyJSON bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [version]",
+	Short: "Detect changes to installed versions",
+	Long: `Compare installed versions with the file hashes recorded at install time.
+
+Reports files that were added, removed (missing) or modified since the version
+was installed. Installations made before hashes were recorded are compared
+with their cached archive. Overlays and extensions applied by mvnenv are part
+of the recorded state. Files matching verification.allow in config.yaml, such
+as hand-made local edits, are not reported.
+
+Exits non-zero if any installation has changed. Use "mvnenv repair" to restore
+a version.`,
+	Example: `  mvnenv verify 3.9.6
+  mvnenv verify --all
+  mvnenv verify --all --json
+  mvnenv verify --tool mvnd 1.0.2`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifyAll, "all", false, "Verify every installed version (of every tool unless --tool is given)")
+	verifyCmd.Flags().BoolVar(&verifyJSON, "json", false, "Print results as JSON")
+	addToolFlag(verifyCmd)
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	mvnenvRoot := getMvnenvRoot()
+
+	if verifyAll == (len(args) == 1) {
+		return formatError(fmt.Errorf("specify a version or --all"))
+	}
+
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+
+	type target struct {
+		def     *tool.Definition
+		version string
+	}
+	var targets []target
+
+	if verifyAll {
+		defs := tool.All()
+		if cmd.Flags().Changed("tool") {
+			defs = []*tool.Definition{def}
+		}
+		for _, d := range defs {
+			versions, err := versionpkg.NewToolLister(mvnenvRoot, d).ListInstalled()
+			if err != nil {
+				return formatError(err)
+			}
+			for _, v := range versions {
+				targets = append(targets, target{d, v})
+			}
+		}
+	} else {
+		ver := args[0]
+		if err := validateVersionFormat(ver); err != nil {
+			return formatError(err)
+		}
+		if !def.IsValidInstallation(def.InstallPath(mvnenvRoot, ver)) {
+			return formatError(fmt.Errorf("%s %s is not installed", def.DisplayName, ver))
+		}
+		targets = append(targets, target{def, ver})
+	}
+
+	results := []*versionpkg.Verification{}
+	failed := 0
+	for _, t := range targets {
+		v, err := versionpkg.VerifyInstallation(mvnenvRoot, t.def, t.version)
+		if err != nil {
+			return formatError(fmt.Errorf("verify %s %s: %w", t.def.DisplayName, t.version, err))
+		}
+		if !v.Clean() {
+			failed++
+		}
+		results = append(results, v)
+	}
+
+	if verifyJSON {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		for _, v := range results {
+			printVerification(v)
+		}
+		if len(results) > 1 || verifyAll {
+			fmt.Printf("\n%d installations verified, %d changed or unverifiable\n", len(results), failed)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d installations changed or could not be verified", failed)
+	}
+	return nil
+}
+
+// printVerification prints the outcome of verifying one installation
+func printVerification(v *versionpkg.Verification) {
+	name := v.Tool + " " + v.Version
+	switch {
+	case v.Unknown != "":
+		fmt.Printf("%-20s UNKNOWN: %s\n", name, v.Unknown)
+	case len(v.Changes) == 0:
+		fmt.Printf("%-20s OK\n", name)
+	default:
+		fmt.Printf("%-20s %d changed files\n", name, len(v.Changes))
+		for _, c := range v.Changes {
+			fmt.Printf("  %-9s %s\n", c.Change, c.Path)
+		}
+	}
+}
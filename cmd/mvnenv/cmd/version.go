@@ -21,24 +21,30 @@ Shows the Maven version currently in use and its source:
 }
 
 func init() {
+	addToolFlag(versionCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
 func runVersion(cmd *cobra.Command, args []string) error {
 	mvnenvRoot := getMvnenvRoot()
-	resolver := version.NewVersionResolver(mvnenvRoot)
+
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+	resolver := version.NewToolResolver(mvnenvRoot, def)
 
 	resolved, err := resolver.ResolveVersion()
 	if err != nil {
 		if version.IsNoVersionSetError(err) {
-			fmt.Println("No Maven version is set.")
-			fmt.Println("Set a version with: mvnenv global <version>")
+			fmt.Printf("No %s version is set.\n", def.DisplayName)
+			fmt.Printf("Set a version with: mvnenv global %s<version>\n", toolHint(def))
 			return nil
 		}
 		if version.IsVersionNotInstalledError(err) {
 			ver := version.ExtractVersionFromError(err)
-			fmt.Printf("Maven version '%s' is set but not installed.\n", ver)
-			fmt.Printf("Install it with: mvnenv install %s\n", ver)
+			fmt.Printf("%s version '%s' is set but not installed.\n", def.DisplayName, ver)
+			fmt.Printf("Install it with: mvnenv install %s%s\n", toolHint(def), ver)
 			return nil
 		}
 		return formatError(err)
@@ -19,12 +19,18 @@ marked with an asterisk (*).`,
 }
 
 func init() {
+	addToolFlag(versionsCmd)
 	rootCmd.AddCommand(versionsCmd)
 }
 
 func runVersions(cmd *cobra.Command, args []string) error {
 	mvnenvRoot := getMvnenvRoot()
-	lister := version.NewVersionLister(mvnenvRoot)
+
+	def, err := selectedTool()
+	if err != nil {
+		return formatError(err)
+	}
+	lister := version.NewToolLister(mvnenvRoot, def)
 
 	versions, err := lister.ListInstalled()
 	if err != nil {
@@ -32,8 +38,8 @@ func runVersions(cmd *cobra.Command, args []string) error {
 	}
 
 	if len(versions) == 0 {
-		fmt.Println("No Maven versions installed.")
-		fmt.Println("Install a version with: mvnenv install <version>")
+		fmt.Printf("No %s versions installed.\n", def.DisplayName)
+		fmt.Printf("Install a version with: mvnenv install %s<version>\n", toolHint(def))
 		return nil
 	}
 
@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"fmt"
-	"path/filepath"
 
 	"github.com/spf13/cobra"
+	"github.com/veenone/mvnenv-win/internal/tool"
 	"github.com/veenone/mvnenv-win/internal/version"
 )
 
@@ -16,7 +16,8 @@ var whichCmd = &cobra.Command{
 Displays the full Windows path to the specified Maven command executable
 based on the currently active version.`,
 	Example: `  mvnenv which mvn
-  mvnenv which mvnDebug`,
+  mvnenv which mvnDebug
+  mvnenv which mvnd`,
 	Args: cobra.ExactArgs(1),
 	RunE: runWhich,
 }
@@ -29,21 +30,23 @@ func runWhich(cmd *cobra.Command, args []string) error {
 	command := args[0]
 	mvnenvRoot := getMvnenvRoot()
 
-	resolver := version.NewVersionResolver(mvnenvRoot)
+	// The command determines the tool (mvnd for mvnd, Maven otherwise)
+	def := tool.ForCommand(command)
+	resolver := version.NewToolResolver(mvnenvRoot, def)
 	resolved, err := resolver.ResolveVersion()
 	if err != nil {
 		if version.IsNoVersionSetError(err) {
-			return fmt.Errorf("no Maven version is set")
+			return fmt.Errorf("no %s version is set", def.DisplayName)
 		}
 		if version.IsVersionNotInstalledError(err) {
 			ver := version.ExtractVersionFromError(err)
-			return fmt.Errorf("Maven version '%s' is set but not installed", ver)
+			return fmt.Errorf("%s version '%s' is set but not installed", def.DisplayName, ver)
 		}
 		return formatError(err)
 	}
 
 	// Construct path to command
-	commandPath := filepath.Join(resolved.Path, "bin", command+".cmd")
+	commandPath := def.LauncherPath(resolved.Path, command)
 	fmt.Println(commandPath)
 
 	return nil
@@ -11,9 +11,13 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/veenone/mvnenv-win/cmd/mvnenv/plugins"
 	"github.com/veenone/mvnenv-win/internal/config"
-	"github.com/veenone/mvnenv-win/internal/download"
+	"github.com/veenone/mvnenv-win/internal/diskspace"
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+	"github.com/veenone/mvnenv-win/internal/lock"
 	"github.com/veenone/mvnenv-win/internal/nexus"
+	"github.com/veenone/mvnenv-win/internal/progress"
 	"github.com/veenone/mvnenv-win/internal/repository"
+	"github.com/veenone/mvnenv-win/internal/tool"
 	"github.com/veenone/mvnenv-win/pkg/maven"
 )
 
@@ -36,6 +40,7 @@ func (p *MirrorPlugin) Command() *cobra.Command {
 		dryRun      bool
 		skipExisting bool
 		maxVersions  int
+		limitRate    string
 	)
 
 	cmd := &cobra.Command{
@@ -49,20 +54,30 @@ This command requires Nexus to be configured in the config file.`,
 		Example: `  mvnenv mirror
   mvnenv mirror --dry-run
   mvnenv mirror --skip-existing
-  mvnenv mirror --max 10`,
+  mvnenv mirror --max 10
+  mvnenv mirror --limit-rate 2M`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMirror(dryRun, skipExisting, maxVersions)
+			// --limit-rate overrides network.limit_rate
+			if cmd.Flags().Changed("limit-rate") {
+				rate, err := httpclient.ParseRate(limitRate)
+				if err != nil {
+					return err
+				}
+				httpclient.SetRateLimit(rate)
+			}
+			return runMirror(cmd.Context(), dryRun, skipExisting, maxVersions)
 		},
 	}
 
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be mirrored without uploading")
 	cmd.Flags().BoolVar(&skipExisting, "skip-existing", true, "Skip versions that already exist in Nexus")
 	cmd.Flags().IntVar(&maxVersions, "max", 0, "Maximum number of versions to mirror (0 = all)")
+	cmd.Flags().StringVar(&limitRate, "limit-rate", "", "Limit the bandwidth of downloads and uploads together, e.g. 500K or 2M bytes per second (0 = unlimited)")
 
 	return cmd
 }
 
-func runMirror(dryRun, skipExisting bool, maxVersions int) error {
+func runMirror(ctx context.Context, dryRun, skipExisting bool, maxVersions int) error {
 	mvnenvRoot := getMvnenvRoot()
 
 	// Load config
@@ -89,7 +104,8 @@ func runMirror(dryRun, skipExisting bool, maxVersions int) error {
 	// Get list of available versions from Apache
 	fmt.Println("Fetching available versions from Apache Maven archive...")
 	apache := repository.NewApacheArchive()
-	versions, err := apache.ListVersions()
+	repoManager := repository.NewManager(mvnenvRoot)
+	versions, err := apache.ListVersions(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list versions: %w", err)
 	}
@@ -116,7 +132,7 @@ func runMirror(dryRun, skipExisting bool, maxVersions int) error {
 			CAFile:             cfg.Mirror.Nexus.TLS.CAFile,
 		}
 	}
-	nexusClient, err := nexus.NewClient(cfg.Mirror.Nexus.BaseURL, cfg.Mirror.Nexus.Username, cfg.Mirror.Nexus.Password, nexusTLS)
+	nexusClient, err := nexus.NewClient(cfg.Mirror.Nexus.BaseURL, cfg.Mirror.Nexus.Username, cfg.Mirror.Nexus.Password, nexusTLS, cfg.Mirror.Nexus.Proxy)
 	if err != nil {
 		return fmt.Errorf("failed to create Nexus client: %w", err)
 	}
@@ -125,7 +141,7 @@ func runMirror(dryRun, skipExisting bool, maxVersions int) error {
 	var existingVersions map[string]bool
 	if skipExisting {
 		fmt.Println("Checking existing versions in Nexus...")
-		existing, err := nexusClient.ListVersions(context.Background())
+		existing, err := nexusClient.ListVersions(ctx)
 		if err != nil {
 			fmt.Printf("Warning: Could not check existing versions: %v\n", err)
 			existingVersions = make(map[string]bool)
@@ -141,13 +157,31 @@ func runMirror(dryRun, skipExisting bool, maxVersions int) error {
 	// Create temp directory for downloads
 	tempDir := filepath.Join(mvnenvRoot, "cache", "mirror-temp")
 	if !dryRun {
+		// The temp directory is shared, so only one mirror may run at a time
+		fileLock, err := lock.Acquire(mvnenvRoot, "mirror")
+		if err != nil {
+			return fmt.Errorf("failed to lock mirror: %w", err)
+		}
+		defer fileLock.Release()
+
 		if err := os.MkdirAll(tempDir, 0755); err != nil {
 			return fmt.Errorf("failed to create temp directory: %w", err)
 		}
 		defer os.RemoveAll(tempDir)
+
+		// Each archive must fit in the temp directory before it is written
+		apache.SetPreflight(func(size int64) error {
+			err := diskspace.Check(diskspace.Need{Path: tempDir, Bytes: size, What: "mirror temp directory"})
+			if err != nil && !diskspace.IsInsufficient(err) {
+				fmt.Printf("\n  Warning: Could not check disk space: %v\n", err)
+				return nil
+			}
+			return err
+		})
 	}
 
 	// Process each version
+	reporter := progress.Default()
 	successCount := 0
 	skippedCount := 0
 	failedCount := 0
@@ -170,41 +204,45 @@ func runMirror(dryRun, skipExisting bool, maxVersions int) error {
 
 		// Download from Apache
 		archivePath := filepath.Join(tempDir, fmt.Sprintf("apache-maven-%s-bin.zip", version))
-		fmt.Printf("  Downloading from Apache...")
-
-		progressCallback := download.ProgressCallback(func(downloaded, total int64) {
-			if total > 0 {
-				percent := float64(downloaded) / float64(total) * 100
-				fmt.Printf("\r  Downloading from Apache... %6.1f%%", percent)
-			}
-		})
+		archiveName := filepath.Base(archivePath)
 
-		err = apache.DownloadVersion(version, archivePath, progressCallback)
+		task := reporter.Start(progress.KindDownload, archiveName)
+		err = apache.DownloadVersion(progress.WithTask(ctx, task), version, archivePath, task.Update)
+		if ctx.Err() != nil {
+			task.Fail(ctx.Err())
+			return ctx.Err()
+		}
 		if err != nil {
-			fmt.Printf("\r  ✗ Download failed: %v\n\n", err)
+			task.Fail(err)
+			fmt.Println()
 			failedCount++
 			continue
 		}
-		fmt.Printf("\r  ✓ Downloaded from Apache              \n")
-
-		// Upload to Nexus
-		fmt.Printf("  Uploading to Nexus...")
+		task.Done("")
 
-		uploadCallback := func(uploaded, total int64) {
-			if total > 0 {
-				percent := float64(uploaded) / float64(total) * 100
-				fmt.Printf("\r  Uploading to Nexus... %6.1f%%", percent)
-			}
+		// Never publish an archive that fails the integrity policy
+		if _, err := repoManager.CheckIntegrity(ctx, tool.Maven, version, tool.Maven.DownloadURLFor(version), archivePath); err != nil {
+			fmt.Printf("  ✗ Verification failed: %v\n\n", err)
+			failedCount++
+			os.Remove(archivePath)
+			continue
 		}
 
-		err = nexusClient.UploadVersion(context.Background(), version, archivePath, uploadCallback)
+		// Upload to Nexus
+		task = reporter.Start(progress.KindUpload, archiveName)
+		err = nexusClient.UploadVersion(ctx, version, archivePath, task.Update)
+		if ctx.Err() != nil {
+			task.Fail(ctx.Err())
+			return ctx.Err()
+		}
 		if err != nil {
-			fmt.Printf("\r  ✗ Upload failed: %v\n\n", err)
+			task.Fail(err)
+			fmt.Println()
 			failedCount++
 			os.Remove(archivePath)
 			continue
 		}
-		fmt.Printf("\r  ✓ Uploaded to Nexus                \n")
+		task.Done("")
 
 		// Clean up downloaded file
 		os.Remove(archivePath)
@@ -0,0 +1,361 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/download"
+	"github.com/veenone/mvnenv-win/internal/integrity"
+	"github.com/veenone/mvnenv-win/internal/lock"
+	"github.com/veenone/mvnenv-win/internal/signature"
+)
+
+// ArchiveEntry describes a downloaded archive kept in the archive store
+type ArchiveEntry struct {
+	Tool      string    `json:"tool"`
+	Version   string    `json:"version"`
+	Name      string    `json:"name"`
+	File      string    `json:"file"`
+	Source    string    `json:"source"`
+	Size      int64     `json:"size"`
+	SHA512    string    `json:"sha512"`
+	FetchedAt time.Time `json:"fetched_at"`
+
+	// Integrity records the last verification against a published checksum
+	Integrity *integrity.Result `json:"integrity,omitempty"`
+
+	// Signature records the last OpenPGP verification of the archive
+	Signature *signature.Result `json:"signature,omitempty"`
+}
+
+// archiveIndex is the on-disk index of the archive store
+type archiveIndex struct {
+	Archives []ArchiveEntry `json:"archives"`
+}
+
+// ArchiveStore is a content-addressed store of downloaded archives. Files are
+// named by their SHA-512 and indexed by tool and version, so an archive that
+// still verifies is reused instead of downloaded again.
+type ArchiveStore struct {
+	dir       string
+	indexFile string
+}
+
+// NewArchiveStore opens the archive store configured for mvnenvRoot. The
+// location is taken from MVNENV_CACHE_DIR, then cache.dir in config.yaml,
+// then <mvnenvRoot>/cache/archives.
+func NewArchiveStore(mvnenvRoot string) *ArchiveStore {
+	dir := os.Getenv("MVNENV_CACHE_DIR")
+	if dir == "" {
+		if cfg, err := config.NewManager(mvnenvRoot).Load(); err == nil && cfg.Cache != nil {
+			dir = cfg.Cache.Dir
+		}
+	}
+	if dir == "" {
+		dir = filepath.Join(mvnenvRoot, "cache", "archives")
+	}
+
+	return &ArchiveStore{
+		dir:       dir,
+		indexFile: filepath.Join(dir, "index.json"),
+	}
+}
+
+// Dir returns the store directory
+func (s *ArchiveStore) Dir() string {
+	return s.dir
+}
+
+// Path returns the location of an entry's archive
+func (s *ArchiveStore) Path(entry ArchiveEntry) string {
+	return filepath.Join(s.dir, entry.File)
+}
+
+// Lookup returns the stored archive for a tool version and its path. The
+// archive's size and SHA-512 are checked first; an archive that no longer
+// verifies is dropped from the store and nil is returned with an error
+// saying why, for the caller to show as a warning.
+func (s *ArchiveStore) Lookup(toolName, version string) (*ArchiveEntry, string, error) {
+	index, err := s.readIndex()
+	if err != nil {
+		return nil, "", fmt.Errorf("could not read archive cache: %w", err)
+	}
+
+	for _, entry := range index.Archives {
+		if entry.Tool != toolName || entry.Version != version {
+			continue
+		}
+
+		path := s.Path(entry)
+		if err := verifyArchive(path, entry); err != nil {
+			s.Remove(toolName, version)
+			return nil, "", fmt.Errorf("cached %s %s archive is invalid (%v), downloading it again", toolName, version, err)
+		}
+
+		return &entry, path, nil
+	}
+
+	return nil, "", nil
+}
+
+// DownloadPath returns the path inside the store to download an archive to
+// before it is added. Being on the same volume lets Add move it in place. The
+// path is the same on every run, so an interrupted download resumes.
+func (s *ArchiveStore) DownloadPath(toolName, version string) (string, error) {
+	downloadsDir := filepath.Join(s.dir, "downloads")
+	if err := os.MkdirAll(downloadsDir, 0755); err != nil {
+		return "", fmt.Errorf("create downloads directory: %w", err)
+	}
+
+	name := fileName(toolName) + "-" + fileName(version) + ".download"
+	return filepath.Join(downloadsDir, name), nil
+}
+
+// fileName makes a value, such as an "ext:<groupId>:<artifactId>" tool key,
+// safe to use in a file name
+func fileName(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// Add moves a downloaded archive into the store and indexes it under the tool
+// version, replacing any previous entry for that version. entry supplies the
+// tool, version, name and source; size, checksum and fetch time are filled in.
+func (s *ArchiveStore) Add(entry ArchiveEntry, path string) (*ArchiveEntry, string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("stat archive: %w", err)
+	}
+
+	sum, err := download.FileSHA512(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("calculate checksum: %w", err)
+	}
+
+	entry.File = sum + archiveExt(entry.Name)
+	entry.Size = info.Size()
+	entry.SHA512 = sum
+	entry.FetchedAt = time.Now().UTC()
+
+	err = s.update(func(index *archiveIndex) error {
+		storePath := s.Path(entry)
+		if _, err := os.Stat(storePath); err == nil {
+			// Identical content is already stored
+			os.Remove(path)
+		} else if err := os.Rename(path, storePath); err != nil {
+			return fmt.Errorf("move archive into cache: %w", err)
+		}
+
+		index.Archives = append(withoutVersion(index.Archives, entry.Tool, entry.Version), entry)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &entry, s.Path(entry), nil
+}
+
+// SetIntegrity records the checksum verification of a stored archive
+func (s *ArchiveStore) SetIntegrity(toolName, version string, result integrity.Result) error {
+	return s.update(func(index *archiveIndex) error {
+		for i := range index.Archives {
+			if index.Archives[i].Tool == toolName && index.Archives[i].Version == version {
+				index.Archives[i].Integrity = &result
+			}
+		}
+		return nil
+	})
+}
+
+// SetSignature records the signature verification of a stored archive
+func (s *ArchiveStore) SetSignature(toolName, version string, result signature.Result) error {
+	return s.update(func(index *archiveIndex) error {
+		for i := range index.Archives {
+			if index.Archives[i].Tool == toolName && index.Archives[i].Version == version {
+				index.Archives[i].Signature = &result
+			}
+		}
+		return nil
+	})
+}
+
+// Entries returns all indexed archives
+func (s *ArchiveStore) Entries() ([]ArchiveEntry, error) {
+	index, err := s.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	return index.Archives, nil
+}
+
+// Remove drops a tool version from the store, deleting its archive unless
+// another entry has identical content
+func (s *ArchiveStore) Remove(toolName, version string) error {
+	return s.update(func(index *archiveIndex) error {
+		return s.removeVersion(index, toolName, version)
+	})
+}
+
+// removeVersion drops a tool version from the index, deleting its archive
+// unless another entry has identical content
+func (s *ArchiveStore) removeVersion(index *archiveIndex, toolName, version string) error {
+	kept := withoutVersion(index.Archives, toolName, version)
+	for _, entry := range index.Archives {
+		if entry.Tool == toolName && entry.Version == version && !referenced(kept, entry.File) {
+			if err := os.Remove(s.Path(entry)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove %s: %w", entry.File, err)
+			}
+		}
+	}
+	index.Archives = kept
+	return nil
+}
+
+// Clear removes every archive and the index
+func (s *ArchiveStore) Clear() error {
+	return s.update(func(index *archiveIndex) error {
+		for _, entry := range index.Archives {
+			if err := os.Remove(s.Path(entry)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("remove %s: %w", entry.File, err)
+			}
+		}
+		index.Archives = nil
+		return nil
+	})
+}
+
+// update applies fn to the index and saves it while holding the store lock
+func (s *ArchiveStore) update(fn func(*archiveIndex) error) error {
+	fileLock, err := s.acquire()
+	if err != nil {
+		return err
+	}
+	defer fileLock.Release()
+
+	return s.modify(fn)
+}
+
+// acquire takes the store lock. The lock lives in the store so machines
+// sharing it are serialized too.
+func (s *ArchiveStore) acquire() (*lock.FileLock, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+
+	fileLock, err := lock.AcquireFile(filepath.Join(s.dir, "index.lock"), lock.Timeout())
+	if err != nil {
+		return nil, fmt.Errorf("lock archive cache: %w", err)
+	}
+	return fileLock, nil
+}
+
+// modify applies fn to the index and saves it; the caller holds the store
+// lock
+func (s *ArchiveStore) modify(fn func(*archiveIndex) error) error {
+	index, err := s.readIndex()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(index); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal archive index: %w", err)
+	}
+
+	// Atomic write
+	tempFile := s.indexFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("write archive index: %w", err)
+	}
+
+	if err := os.Rename(tempFile, s.indexFile); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("rename archive index: %w", err)
+	}
+
+	return nil
+}
+
+// readIndex loads the index, returning an empty one if none exists yet
+func (s *ArchiveStore) readIndex() (*archiveIndex, error) {
+	index := &archiveIndex{}
+
+	data, err := os.ReadFile(s.indexFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("read archive index: %w", err)
+	}
+
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("unmarshal archive index: %w", err)
+	}
+
+	return index, nil
+}
+
+// verifyArchive checks an archive against its recorded size and checksum
+func verifyArchive(path string, entry ArchiveEntry) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != entry.Size {
+		return fmt.Errorf("size mismatch: expected %d, got %d", entry.Size, info.Size())
+	}
+
+	sum, err := download.FileSHA512(path)
+	if err != nil {
+		return err
+	}
+	if sum != entry.SHA512 {
+		return fmt.Errorf("checksum mismatch")
+	}
+
+	return nil
+}
+
+// withoutVersion returns entries other than the given tool version
+func withoutVersion(entries []ArchiveEntry, toolName, version string) []ArchiveEntry {
+	var kept []ArchiveEntry
+	for _, entry := range entries {
+		if entry.Tool != toolName || entry.Version != version {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// referenced reports whether any entry uses the given store file
+func referenced(entries []ArchiveEntry, file string) bool {
+	for _, entry := range entries {
+		if entry.File == file {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveExt returns an archive's extension, keeping ".tar.gz" whole
+func archiveExt(name string) string {
+	if strings.HasSuffix(name, ".tar.gz") {
+		return ".tar.gz"
+	}
+	return filepath.Ext(name)
+}
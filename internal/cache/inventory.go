@@ -0,0 +1,371 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/lock"
+)
+
+// ItemKind classifies what a file in the cache is
+type ItemKind string
+
+const (
+	// KindArchive is an indexed archive in the archive store
+	KindArchive ItemKind = "archive"
+
+	// KindLegacyArchive is an archive left in cache/ by mvnenv versions that
+	// predate the archive store; it is never reused
+	KindLegacyArchive ItemKind = "legacy-archive"
+
+	// KindVersionList is the cached list of available versions
+	KindVersionList ItemKind = "version-list"
+
+	// KindTemporary is a partial download, temp file or mirror work directory
+	KindTemporary ItemKind = "temporary"
+
+	// KindOrphan is a file in the archive store that the index doesn't know
+	KindOrphan ItemKind = "orphan"
+)
+
+// TempGracePeriod is how long a temporary file must be left untouched before
+// prune treats it as abandoned by an interrupted install or mirror
+const TempGracePeriod = 15 * time.Minute
+
+// Item is one entry of the cache inventory
+type Item struct {
+	Kind    ItemKind `json:"kind"`
+	Path    string   `json:"path"`
+	Tool    string   `json:"tool,omitempty"`
+	Version string   `json:"version,omitempty"`
+	Source  string   `json:"source,omitempty"`
+	SHA512  string   `json:"sha512,omitempty"`
+	Size    int64    `json:"size"`
+	// Modified is the fetch time for archives and the modification time
+	// (newest file, for directories) otherwise
+	Modified time.Time `json:"modified"`
+}
+
+// Age returns how long ago the item was fetched or last modified
+func (it Item) Age() time.Duration {
+	return time.Since(it.Modified)
+}
+
+// Inventory lists everything in the cache directory and the archive store
+func Inventory(mvnenvRoot string) ([]Item, error) {
+	cacheDir := NewManager(mvnenvRoot).cacheDir
+	store := NewArchiveStore(mvnenvRoot)
+
+	items, err := storeItems(store)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read cache directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(cacheDir, entry.Name())
+		if samePath(path, store.Dir()) {
+			continue
+		}
+
+		var kind ItemKind
+		switch {
+		case entry.Name() == "versions.json":
+			kind = KindVersionList
+		case entry.Name() == "mirror-temp" || strings.HasSuffix(entry.Name(), ".tmp"):
+			kind = KindTemporary
+		case !entry.IsDir() && filepath.Ext(entry.Name()) == ".zip":
+			kind = KindLegacyArchive
+		default:
+			continue
+		}
+
+		size, modified, err := diskUsage(path)
+		if err != nil {
+			continue
+		}
+		items = append(items, Item{Kind: kind, Path: path, Size: size, Modified: modified})
+	}
+
+	return items, nil
+}
+
+// storeItems lists the archive store: indexed archives, leftover downloads
+// and files the index doesn't reference
+func storeItems(store *ArchiveStore) ([]Item, error) {
+	archives, err := store.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	known := map[string]bool{"index.json": true, "index.lock": true, "downloads": true}
+
+	for _, a := range archives {
+		items = append(items, Item{
+			Kind:     KindArchive,
+			Path:     store.Path(a),
+			Tool:     a.Tool,
+			Version:  a.Version,
+			Source:   a.Source,
+			SHA512:   a.SHA512,
+			Size:     a.Size,
+			Modified: a.FetchedAt,
+		})
+		known[a.File] = true
+	}
+
+	files, err := os.ReadDir(store.Dir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read archive cache: %w", err)
+	}
+	for _, f := range files {
+		if known[f.Name()] {
+			continue
+		}
+
+		kind := KindOrphan
+		if strings.HasSuffix(f.Name(), ".tmp") {
+			kind = KindTemporary
+		}
+
+		path := filepath.Join(store.Dir(), f.Name())
+		if size, modified, err := diskUsage(path); err == nil {
+			items = append(items, Item{Kind: kind, Path: path, Size: size, Modified: modified})
+		}
+	}
+
+	downloads, _ := os.ReadDir(filepath.Join(store.Dir(), "downloads"))
+	for _, f := range downloads {
+		path := filepath.Join(store.Dir(), "downloads", f.Name())
+		if size, modified, err := diskUsage(path); err == nil {
+			items = append(items, Item{Kind: KindTemporary, Path: path, Size: size, Modified: modified})
+		}
+	}
+
+	return items, nil
+}
+
+// VerifyResult is the outcome of re-checking one stored archive
+type VerifyResult struct {
+	Item  Item   `json:"item"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Verify re-checks the size and SHA-512 of every archive in the store
+func (s *ArchiveStore) Verify() ([]VerifyResult, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VerifyResult, 0, len(entries))
+	for _, entry := range entries {
+		result := VerifyResult{
+			Item: Item{
+				Kind:     KindArchive,
+				Path:     s.Path(entry),
+				Tool:     entry.Tool,
+				Version:  entry.Version,
+				Source:   entry.Source,
+				SHA512:   entry.SHA512,
+				Size:     entry.Size,
+				Modified: entry.FetchedAt,
+			},
+			OK: true,
+		}
+		if err := verifyArchive(s.Path(entry), entry); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// PrunePolicy selects archives to remove. Archive selectors combine: an
+// archive is pruned only if it matches every selector that is set. Orphaned
+// files, legacy archives and abandoned temporary files are always pruned.
+type PrunePolicy struct {
+	// OlderThan prunes archives fetched longer ago than this (0 = any age)
+	OlderThan time.Duration
+
+	// Keep protects the most recently fetched archives of each tool
+	// (0 = no protection)
+	Keep int
+
+	// NotInstalled prunes only archives whose version is not installed;
+	// Installed reports whether a tool version is installed
+	NotInstalled bool
+	Installed    func(tool, version string) bool
+
+	// DryRun reports what would be removed without removing it
+	DryRun bool
+}
+
+// selectsArchives reports whether the policy prunes any archives at all
+func (p PrunePolicy) selectsArchives() bool {
+	return p.OlderThan > 0 || p.Keep > 0 || p.NotInstalled
+}
+
+// Prune removes the cache items selected by the policy and returns them.
+// Archives are considered newest first: Keep protects the most recent
+// archives of each tool whether or not they are installed, and OlderThan and
+// NotInstalled then select among the rest. The store lock is held
+// throughout, and files of downloads still in progress are left alone.
+func Prune(mvnenvRoot string, policy PrunePolicy) ([]Item, error) {
+	store := NewArchiveStore(mvnenvRoot)
+	fileLock, err := store.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Release()
+
+	items, err := Inventory(mvnenvRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Newest first, so Keep protects the most recent archives of each tool
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Modified.After(items[j].Modified)
+	})
+
+	var selected []Item
+	kept := make(map[string]int)
+
+	for _, item := range items {
+		switch item.Kind {
+		case KindArchive:
+			if !policy.selectsArchives() {
+				continue
+			}
+			if policy.Keep > 0 && kept[item.Tool] < policy.Keep {
+				kept[item.Tool]++
+				continue
+			}
+			if policy.OlderThan > 0 && item.Age() <= policy.OlderThan {
+				continue
+			}
+			if policy.NotInstalled && policy.Installed != nil && policy.Installed(item.Tool, item.Version) {
+				continue
+			}
+		case KindTemporary:
+			if item.Age() <= TempGracePeriod || inFlight(item) {
+				continue
+			}
+		case KindOrphan, KindLegacyArchive:
+		default:
+			continue
+		}
+		selected = append(selected, item)
+	}
+
+	if policy.DryRun {
+		return selected, nil
+	}
+
+	return removeItems(store, selected)
+}
+
+// Clear removes everything in the cache, including the version list, except
+// the files of downloads still in progress. The store lock is held
+// throughout. The removed items are returned.
+func Clear(mvnenvRoot string) ([]Item, error) {
+	store := NewArchiveStore(mvnenvRoot)
+	fileLock, err := store.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Release()
+
+	items, err := Inventory(mvnenvRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []Item
+	for _, item := range items {
+		if !inFlight(item) {
+			selected = append(selected, item)
+		}
+	}
+
+	return removeItems(store, selected)
+}
+
+// inFlight reports whether an item belongs to a download in progress: the
+// files of a download in downloads/ (the archive, its .part and .part.json
+// and its .lock) are kept while the download's lock is held
+func inFlight(item Item) bool {
+	if item.Kind != KindTemporary || filepath.Base(filepath.Dir(item.Path)) != "downloads" {
+		return false
+	}
+
+	base := item.Path
+	for _, suffix := range []string{".lock", ".part.json", ".part"} {
+		base = strings.TrimSuffix(base, suffix)
+	}
+	return lock.Held(base + ".lock")
+}
+
+// removeItems deletes cache items, returning those that were removed; the
+// caller holds the store lock
+func removeItems(store *ArchiveStore, items []Item) ([]Item, error) {
+	var removed []Item
+	for _, item := range items {
+		var err error
+		if item.Kind == KindArchive {
+			err = store.modify(func(index *archiveIndex) error {
+				return store.removeVersion(index, item.Tool, item.Version)
+			})
+		} else {
+			err = os.RemoveAll(item.Path)
+		}
+		if err != nil {
+			return removed, fmt.Errorf("remove %s: %w", item.Path, err)
+		}
+		removed = append(removed, item)
+	}
+
+	return removed, nil
+}
+
+// diskUsage returns the total size and newest modification time of a file or
+// directory tree
+func diskUsage(path string) (int64, time.Time, error) {
+	var size int64
+	var modified time.Time
+
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+		return nil
+	})
+
+	return size, modified, err
+}
+
+// samePath reports whether two paths refer to the same location
+func samePath(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && strings.EqualFold(a, b)
+}
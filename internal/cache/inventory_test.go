@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/lock"
+)
+
+// newStore returns the archive store of a fresh mvnenv root
+func newStore(t *testing.T) (string, *ArchiveStore) {
+	t.Helper()
+	t.Setenv("MVNENV_CACHE_DIR", "")
+	root := t.TempDir()
+	return root, NewArchiveStore(root)
+}
+
+// addArchive stores an archive for a tool version fetched age ago
+func addArchive(t *testing.T, store *ArchiveStore, toolName, version string, age time.Duration) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	if err := os.WriteFile(path, []byte(toolName+" "+version), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := store.Add(ArchiveEntry{Tool: toolName, Version: version, Name: version + ".zip"}, path); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.update(func(index *archiveIndex) error {
+		for i := range index.Archives {
+			if index.Archives[i].Tool == toolName && index.Archives[i].Version == version {
+				index.Archives[i].FetchedAt = time.Now().Add(-age).UTC()
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTemp creates a file under the store last modified age ago
+func writeTemp(t *testing.T, store *ArchiveStore, name string, age time.Duration) string {
+	t.Helper()
+	path := filepath.Join(store.Dir(), name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// archives returns "tool version" of the given items' archives, sorted
+func archives(items []Item) []string {
+	var names []string
+	for _, item := range items {
+		if item.Kind == KindArchive {
+			names = append(names, item.Tool+" "+item.Version)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// remaining returns "tool version" of the archives left in the store
+func remaining(t *testing.T, store *ArchiveStore) []string {
+	t.Helper()
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Tool+" "+e.Version)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPrunePolicies(t *testing.T) {
+	const day = 24 * time.Hour
+	installed := func(toolName, version string) bool {
+		return toolName == "maven" && version == "3.9.5"
+	}
+
+	tests := []struct {
+		name   string
+		policy PrunePolicy
+		want   []string
+	}{
+		{"none", PrunePolicy{}, nil},
+		{"keep", PrunePolicy{Keep: 1}, []string{"maven 3.8.8", "maven 3.9.5"}},
+		{"older-than", PrunePolicy{OlderThan: 36 * time.Hour}, []string{"maven 3.8.8", "maven 3.9.5", "mvnd 1.0.0"}},
+		{"keep and older-than", PrunePolicy{Keep: 1, OlderThan: 60 * time.Hour}, []string{"maven 3.8.8"}},
+		{"not-installed", PrunePolicy{NotInstalled: true, Installed: installed}, []string{"maven 3.8.8", "maven 3.9.6", "mvnd 1.0.0"}},
+		// Keep counts the newest archives whether installed or not
+		{"keep and not-installed", PrunePolicy{Keep: 2, NotInstalled: true, Installed: installed}, []string{"maven 3.8.8"}},
+		{"all selectors", PrunePolicy{Keep: 1, OlderThan: 36 * time.Hour, NotInstalled: true, Installed: installed}, []string{"maven 3.8.8"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, store := newStore(t)
+			addArchive(t, store, "maven", "3.9.6", 1*day)
+			addArchive(t, store, "maven", "3.9.5", 2*day)
+			addArchive(t, store, "maven", "3.8.8", 3*day)
+			addArchive(t, store, "mvnd", "1.0.0", 4*day)
+
+			dryRun := tt.policy
+			dryRun.DryRun = true
+			selected, err := Prune(root, dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := archives(selected); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("dry run selected %v, want %v", got, tt.want)
+			}
+			if got := remaining(t, store); len(got) != 4 {
+				t.Fatalf("dry run removed archives, left %v", got)
+			}
+
+			removed, err := Prune(root, tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := archives(removed); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("removed %v, want %v", got, tt.want)
+			}
+			if got := remaining(t, store); len(got)+len(tt.want) != 4 {
+				t.Errorf("left %v after removing %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPruneTemporaryFiles(t *testing.T) {
+	root, store := newStore(t)
+	fresh := writeTemp(t, store, "downloads/maven-3.9.6.download.part", time.Minute)
+	abandoned := writeTemp(t, store, "downloads/maven-3.9.5.download.part", time.Hour)
+	orphan := writeTemp(t, store, "stray.zip", time.Minute)
+
+	// A slow download still holds its lock after the grace period
+	active := writeTemp(t, store, "downloads/maven-3.8.8.download.part", time.Hour)
+	activeLock, err := lock.AcquireFile(filepath.Join(store.Dir(), "downloads", "maven-3.8.8.download.lock"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer activeLock.Release()
+
+	if _, err := Prune(root, PrunePolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, kept := range map[string]bool{fresh: true, abandoned: false, orphan: false, active: true} {
+		if _, err := os.Stat(path); (err == nil) != kept {
+			t.Errorf("%s: kept = %v, want %v", filepath.Base(path), err == nil, kept)
+		}
+	}
+}
+
+func TestClearKeepsDownloadsInProgress(t *testing.T) {
+	root, store := newStore(t)
+	addArchive(t, store, "maven", "3.9.6", time.Hour)
+	abandoned := writeTemp(t, store, "downloads/maven-3.9.5.download.part", time.Minute)
+
+	active := []string{
+		writeTemp(t, store, "downloads/maven-3.8.8.download", time.Minute),
+		writeTemp(t, store, "downloads/maven-3.8.8.download.part", time.Minute),
+		writeTemp(t, store, "downloads/maven-3.8.8.download.part.json", time.Minute),
+	}
+	activeLock, err := lock.AcquireFile(filepath.Join(store.Dir(), "downloads", "maven-3.8.8.download.lock"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer activeLock.Release()
+
+	if _, err := Clear(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := remaining(t, store); len(got) != 0 {
+		t.Errorf("archives left: %v", got)
+	}
+	if _, err := os.Stat(abandoned); err == nil {
+		t.Errorf("%s was kept", filepath.Base(abandoned))
+	}
+	for _, path := range append(active, filepath.Join(store.Dir(), "downloads", "maven-3.8.8.download.lock")) {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s of a download in progress was removed", filepath.Base(path))
+		}
+	}
+}
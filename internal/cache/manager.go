@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/veenone/mvnenv-win/internal/lock"
 )
 
 // VersionCache stores cached version information
@@ -16,16 +18,18 @@ type VersionCache struct {
 
 // Manager handles version cache operations
 type Manager struct {
-	cacheDir  string
-	cacheFile string
+	mvnenvRoot string
+	cacheDir   string
+	cacheFile  string
 }
 
 // NewManager creates a new cache manager
 func NewManager(mvnenvRoot string) *Manager {
 	cacheDir := filepath.Join(mvnenvRoot, "cache")
 	return &Manager{
-		cacheDir:  cacheDir,
-		cacheFile: filepath.Join(cacheDir, "versions.json"),
+		mvnenvRoot: mvnenvRoot,
+		cacheDir:   cacheDir,
+		cacheFile:  filepath.Join(cacheDir, "versions.json"),
 	}
 }
 
@@ -46,6 +50,13 @@ func (m *Manager) SaveVersions(versions []string) error {
 		return fmt.Errorf("marshal cache: %w", err)
 	}
 
+	// Serialize with concurrent updates from other mvnenv processes
+	fileLock, err := lock.Acquire(m.mvnenvRoot, "cache")
+	if err != nil {
+		return fmt.Errorf("lock cache: %w", err)
+	}
+	defer fileLock.Release()
+
 	// Atomic write
 	tempFile := m.cacheFile + ".tmp"
 	if err := os.WriteFile(tempFile, data, 0644); err != nil {
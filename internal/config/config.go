@@ -1,11 +1,16 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/veenone/mvnenv-win/internal/lock"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,12 +18,115 @@ import (
 type Config struct {
 	Version       string            `yaml:"version"`
 	GlobalVersion string            `yaml:"global_version,omitempty"`
+	// GlobalVersions holds global versions of tools other than Maven (e.g. mvnd)
+	GlobalVersions map[string]string `yaml:"global_versions,omitempty"`
 	AutoRehash    bool              `yaml:"auto_rehash"`
 	Repositories  *RepositoriesConfig `yaml:"repositories,omitempty"`
 	Mirror        *MirrorConfig     `yaml:"mirror,omitempty"`
+	Wrapper       *WrapperConfig    `yaml:"wrapper,omitempty"`
+	Cache         *CacheConfig      `yaml:"cache,omitempty"`
+	Verification  *VerificationConfig `yaml:"verification,omitempty"`
+	Proxy         *ProxyConfig      `yaml:"proxy,omitempty"`
+	Network       *NetworkConfig    `yaml:"network,omitempty"`
+	Tools         []ToolConfig      `yaml:"tools,omitempty"`
+	Overlays      []OverlayConfig   `yaml:"overlays,omitempty"`
+	// Projects are project roots registered by "mvnenv local"; uninstall
+	// checks their version files before removing a version
+	Projects      []string          `yaml:"projects,omitempty"`
 	mu            sync.RWMutex
 }
 
+// ToolConfig declares an additional tool (e.g. Ant or Gradle) managed with
+// the same install, resolve and shim pipeline as Maven. Templates may use
+// {version} and {platform}.
+type ToolConfig struct {
+	Name           string            `yaml:"name"`
+	DisplayName    string            `yaml:"display_name,omitempty"`
+	GroupID        string            `yaml:"group_id,omitempty"`
+	ArtifactID     string            `yaml:"artifact_id,omitempty"`
+	Archive        string            `yaml:"archive"`
+	NexusArchive   string            `yaml:"nexus_archive,omitempty"`
+	UpstreamName   string            `yaml:"upstream_name,omitempty"`
+	DownloadURL    string            `yaml:"download_url,omitempty"`
+	SignatureSuffix string           `yaml:"signature_suffix,omitempty"`
+	KeysURL        string            `yaml:"keys_url,omitempty"`
+	ListURL        string            `yaml:"list_url,omitempty"`
+	ListFormat     string            `yaml:"list_format,omitempty"`
+	ListPattern    string            `yaml:"list_pattern,omitempty"`
+	VersionFile    string            `yaml:"version_file,omitempty"`
+	VersionEnv     string            `yaml:"version_env,omitempty"`
+	Launchers      []string          `yaml:"launchers"`
+	LauncherExt    string            `yaml:"launcher_ext,omitempty"`
+	HomeEnv        map[string]string `yaml:"home_env,omitempty"`
+}
+
+// WrapperConfig controls interception of Maven Wrapper (mvnw) projects
+type WrapperConfig struct {
+	// Intercept generates mvnw shims that run the wrapper's pinned version
+	// from mvnenv's managed installations instead of ~/.m2/wrapper
+	Intercept bool `yaml:"intercept"`
+}
+
+// CacheConfig configures the download archive store
+type CacheConfig struct {
+	// Dir holds downloaded archives; it may be a share used by several
+	// machines. Defaults to <MVNENV_ROOT>/cache/archives.
+	Dir string `yaml:"dir,omitempty"`
+}
+
+// VerificationConfig controls how downloaded archives are verified
+type VerificationConfig struct {
+	// Integrity is the checksum policy: strict, warn (default) or off
+	Integrity string `yaml:"integrity,omitempty"`
+
+	// Signature is the OpenPGP signature policy: require, warn (default) or off
+	Signature string `yaml:"signature,omitempty"`
+
+	// Allow lists files inside installations that verify and repair leave
+	// alone, as slash-separated patterns relative to the installation
+	// (e.g. "conf/settings.xml", "lib/ext/*"); "dir/**" matches a whole tree
+	Allow []string `yaml:"allow,omitempty"`
+}
+
+// OverlayConfig declares files copied over installations after extraction,
+// such as a corporate conf/settings.xml. Overlays apply in the order given,
+// so later ones win.
+type OverlayConfig struct {
+	// Name identifies the overlay; without Artifact, its files are taken
+	// from the directory overlays/<name> or the archive overlays/<name>.zip
+	// under MVNENV_ROOT
+	Name string `yaml:"name"`
+
+	// Tool is the tool the overlay applies to (default maven)
+	Tool string `yaml:"tool,omitempty"`
+
+	// Versions selects versions, e.g. ">=3.9" or ">=3.8 <4"; a bare version
+	// such as "3.9" matches by prefix. Empty selects every version.
+	Versions string `yaml:"versions,omitempty"`
+
+	// Artifact fetches the overlay as a zip from Nexus, given as
+	// groupId:artifactId:version
+	Artifact string `yaml:"artifact,omitempty"`
+}
+
+// NetworkConfig tunes the timeouts and retries of every request
+type NetworkConfig struct {
+	// ConnectTimeout bounds connecting to a server, including the TLS
+	// handshake, e.g. "30s" (default)
+	ConnectTimeout string `yaml:"connect_timeout,omitempty"`
+
+	// ReadTimeout bounds waiting for a response and for more data while
+	// reading it, e.g. "60s" (default); stalled transfers are retried
+	ReadTimeout string `yaml:"read_timeout,omitempty"`
+
+	// Retries is how often a failed request is retried (default 4)
+	Retries *int `yaml:"retries,omitempty"`
+
+	// LimitRate caps the bandwidth of all downloads and uploads together,
+	// e.g. "500K" or "2M" bytes per second; unlimited by default
+	LimitRate string `yaml:"limit_rate,omitempty"`
+}
+
 // RepositoriesConfig represents Maven repository sources configuration
 type RepositoriesConfig struct {
 	Nexus *NexusConfig `yaml:"nexus,omitempty"`
@@ -36,6 +144,23 @@ type NexusConfig struct {
 	Username string     `yaml:"username,omitempty"`
 	Password string     `yaml:"password,omitempty"`
 	TLS      *TLSConfig `yaml:"tls,omitempty"`
+	// Proxy overrides the global proxy for this repository
+	Proxy *ProxyConfig `yaml:"proxy,omitempty"`
+}
+
+// ProxyConfig routes downloads through an HTTP proxy. Without it,
+// HTTP(S)_PROXY and NO_PROXY are used, then the active proxies of
+// ~/.m2/settings.xml.
+type ProxyConfig struct {
+	// URL is the proxy, e.g. http://proxy.corp.example:8080; "direct"
+	// connects without a proxy
+	URL      string `yaml:"url"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// NoProxy lists hosts reached directly: host names, domain suffixes
+	// (".corp.example" or "*.corp.example"), IP addresses or CIDR ranges
+	NoProxy []string `yaml:"no_proxy,omitempty"`
 }
 
 // TLSConfig represents TLS configuration
@@ -44,11 +169,25 @@ type TLSConfig struct {
 	CAFile             string `yaml:"ca_file,omitempty"`
 }
 
+// globalVersionFileName is a plain-text copy of global_version kept next to
+// config.yaml so the shim can resolve the global version without parsing
+// YAML. Its first line is the version and its second the SHA-256 of the
+// config.yaml it was taken from.
+const globalVersionFileName = "global-version"
+
+// mavenTool is the tool name whose global version lives in global_version
+const mavenTool = "maven"
+
+// lockName is the cross-process lock guarding config.yaml
+const lockName = "config"
+
 // Manager handles configuration file operations
 type Manager struct {
-	configPath string
-	config     *Config
-	mu         sync.RWMutex
+	mvnenvRoot        string
+	configPath        string
+	globalVersionPath string
+	config            *Config
+	mu                sync.RWMutex
 }
 
 // NewManager creates a new configuration manager
@@ -57,7 +196,9 @@ func NewManager(mvnenvRoot string) *Manager {
 	configPath := filepath.Join(configDir, "config.yaml")
 
 	return &Manager{
-		configPath: configPath,
+		mvnenvRoot:        mvnenvRoot,
+		configPath:        configPath,
+		globalVersionPath: filepath.Join(configDir, globalVersionFileName),
 	}
 }
 
@@ -92,8 +233,44 @@ func (m *Manager) Load() (*Config, error) {
 	return m.config, nil
 }
 
-// Save saves configuration to disk
+// Save saves configuration to disk. Other mvnenv processes writing the
+// configuration at the same time are waited for.
 func (m *Manager) Save(config *Config) error {
+	fileLock, err := lock.Acquire(m.mvnenvRoot, lockName)
+	if err != nil {
+		return fmt.Errorf("lock config: %w", err)
+	}
+	defer fileLock.Release()
+
+	return m.save(config)
+}
+
+// Update loads the configuration, applies fn and saves the result while
+// holding the config lock, so concurrent read-modify-write cycles from other
+// mvnenv processes cannot overwrite each other. A configuration that can't
+// be read is an error rather than a reason to start over from the defaults;
+// only a missing file starts from them.
+func (m *Manager) Update(fn func(*Config) error) error {
+	fileLock, err := lock.Acquire(m.mvnenvRoot, lockName)
+	if err != nil {
+		return fmt.Errorf("lock config: %w", err)
+	}
+	defer fileLock.Release()
+
+	config, err := m.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(config); err != nil {
+		return err
+	}
+
+	return m.save(config)
+}
+
+// save writes configuration to disk; the caller holds the config lock
+func (m *Manager) save(config *Config) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -110,56 +287,223 @@ func (m *Manager) Save(config *Config) error {
 	}
 
 	// Write atomically (temp file + rename)
-	tmpPath := m.configPath + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
-		return fmt.Errorf("write temp config: %w", err)
+	if err := writeFileAtomic(m.configPath, data); err != nil {
+		return fmt.Errorf("write config file: %w", err)
 	}
 
-	if err := os.Rename(tmpPath, m.configPath); err != nil {
-		os.Remove(tmpPath)
-		return fmt.Errorf("rename config file: %w", err)
+	// Keep the shim's fast-path indexes in sync
+	if err := m.writeGlobalVersionIndexes(config, data); err != nil {
+		return fmt.Errorf("write global version index: %w", err)
 	}
 
 	m.config = config
 	return nil
 }
 
-// GetGlobalVersion returns the global Maven version
-func (m *Manager) GetGlobalVersion() (string, error) {
-	config, err := m.Load()
+// GetGlobalVersionFast returns the global Maven version from the precomputed
+// index file without parsing config.yaml. ok is false when the index is
+// missing or was taken from other config.yaml content (e.g. before a manual
+// edit); callers should then fall back to GetGlobalVersion.
+func (m *Manager) GetGlobalVersionFast() (version string, ok bool) {
+	return m.GetToolGlobalVersionFast(mavenTool)
+}
+
+// GetToolGlobalVersionFast is GetGlobalVersionFast for any tool
+func (m *Manager) GetToolGlobalVersionFast(tool string) (version string, ok bool) {
+	data, err := os.ReadFile(m.configPath)
+	if os.IsNotExist(err) {
+		// No config file means no global version
+		return "", true
+	}
 	if err != nil {
-		return "", err
+		return "", false
 	}
 
-	return config.GlobalVersion, nil
+	index, err := os.ReadFile(m.globalVersionIndexPath(tool))
+	if err != nil {
+		return "", false
+	}
+
+	version, hash, found := strings.Cut(string(index), "\n")
+	if !found || strings.TrimSpace(hash) != configHash(data) {
+		return "", false
+	}
+	return strings.TrimSpace(version), true
 }
 
-// SetGlobalVersion sets the global Maven version
-func (m *Manager) SetGlobalVersion(version string) error {
-	config, err := m.Load()
+// RefreshGlobalVersionIndex rewrites the fast-path indexes from config.yaml,
+// waiting for the config lock like Update
+func (m *Manager) RefreshGlobalVersionIndex() error {
+	return m.refreshGlobalVersionIndex(lock.Timeout())
+}
+
+// TryRefreshGlobalVersionIndex is RefreshGlobalVersionIndex for the shim,
+// which must not wait: when another process holds the config lock, it is
+// saving config.yaml and rewrites the indexes itself.
+func (m *Manager) TryRefreshGlobalVersionIndex() error {
+	return m.refreshGlobalVersionIndex(0)
+}
+
+// refreshGlobalVersionIndex rewrites the indexes while holding the config
+// lock, so they can't be taken from a config.yaml being replaced
+func (m *Manager) refreshGlobalVersionIndex(timeout time.Duration) error {
+	fileLock, err := lock.AcquireFile(lock.Path(m.mvnenvRoot, lockName), timeout)
 	if err != nil {
-		config = &Config{
-			Version:    "1.0",
-			AutoRehash: true,
+		return fmt.Errorf("lock config: %w", err)
+	}
+	defer fileLock.Release()
+
+	data, err := os.ReadFile(m.configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	config := &Config{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("parse config file: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.writeGlobalVersionIndexes(config, data)
+}
+
+// configHash identifies config.yaml content in the indexes
+func configHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// globalVersionIndexPath returns the index file for a tool's global version
+func (m *Manager) globalVersionIndexPath(tool string) string {
+	if isMavenTool(tool) {
+		return m.globalVersionPath
+	}
+	return filepath.Join(filepath.Dir(m.configPath), "global-"+tool+"-version")
+}
+
+// writeGlobalVersionIndexes writes the index for Maven, for every tool with a
+// global version, and clears indexes left over from tools that were unset.
+// data is the config.yaml content config was read from.
+func (m *Manager) writeGlobalVersionIndexes(config *Config, data []byte) error {
+	versions := map[string]string{m.globalVersionPath: config.GlobalVersion}
+	for tool, version := range config.GlobalVersions {
+		versions[m.globalVersionIndexPath(tool)] = version
+	}
+
+	stale, _ := filepath.Glob(filepath.Join(filepath.Dir(m.configPath), "global-*-version"))
+	for _, path := range stale {
+		if _, ok := versions[path]; !ok {
+			versions[path] = ""
+		}
+	}
+
+	hash := configHash(data)
+	for path, version := range versions {
+		if err := writeFileAtomic(path, []byte(version+"\n"+hash+"\n")); err != nil {
+			return err
 		}
 	}
 
-	config.GlobalVersion = version
-	return m.Save(config)
+	return nil
+}
+
+// writeFileAtomic writes data to a uniquely named temp file and renames it
+// into place, so concurrent writers never share a temp file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// GetGlobalVersion returns the global Maven version
+func (m *Manager) GetGlobalVersion() (string, error) {
+	return m.GetToolGlobalVersion(mavenTool)
+}
+
+// SetGlobalVersion sets the global Maven version
+func (m *Manager) SetGlobalVersion(version string) error {
+	return m.SetToolGlobalVersion(mavenTool, version)
 }
 
 // UnsetGlobalVersion removes the global Maven version
 func (m *Manager) UnsetGlobalVersion() error {
+	return m.SetToolGlobalVersion(mavenTool, "")
+}
+
+// GetToolGlobalVersion returns the global version of a tool. Maven's version
+// is stored in global_version, other tools' in global_versions.
+func (m *Manager) GetToolGlobalVersion(tool string) (string, error) {
 	config, err := m.Load()
 	if err != nil {
-		config = &Config{
-			Version:    "1.0",
-			AutoRehash: true,
-		}
+		return "", err
 	}
 
-	config.GlobalVersion = ""
-	return m.Save(config)
+	if isMavenTool(tool) {
+		return config.GlobalVersion, nil
+	}
+	return config.GlobalVersions[tool], nil
+}
+
+// SetToolGlobalVersion sets the global version of a tool. An empty version
+// unsets it.
+func (m *Manager) SetToolGlobalVersion(tool, version string) error {
+	return m.Update(func(config *Config) error {
+		switch {
+		case isMavenTool(tool):
+			config.GlobalVersion = version
+		case version == "":
+			delete(config.GlobalVersions, tool)
+		default:
+			if config.GlobalVersions == nil {
+				config.GlobalVersions = make(map[string]string)
+			}
+			config.GlobalVersions[tool] = version
+		}
+		return nil
+	})
+}
+
+// UnsetToolGlobalVersion removes the global version of a tool
+func (m *Manager) UnsetToolGlobalVersion(tool string) error {
+	return m.SetToolGlobalVersion(tool, "")
+}
+
+// AddProject registers a project root, ignoring roots already registered
+func (m *Manager) AddProject(dir string) error {
+	dir = filepath.Clean(dir)
+	return m.Update(func(config *Config) error {
+		for _, p := range config.Projects {
+			if strings.EqualFold(filepath.Clean(p), dir) {
+				return nil
+			}
+		}
+		config.Projects = append(config.Projects, dir)
+		return nil
+	})
+}
+
+// isMavenTool reports whether a tool name refers to Maven itself
+func isMavenTool(tool string) bool {
+	return tool == "" || tool == mavenTool
 }
 
 // GetConfig returns the current configuration
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/lock"
+)
+
+func TestUpdateKeepsUnreadableConfig(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "config", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := []byte("global_version: [3.9.6\n")
+	if err := os.WriteFile(path, corrupt, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewManager(root).AddProject(filepath.Join(root, "project")); err == nil {
+		t.Fatal("AddProject() succeeded on an unreadable config")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(corrupt) {
+		t.Errorf("config was rewritten:\n%s", data)
+	}
+}
+
+func TestUpdateStartsFromDefaults(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root)
+	project := filepath.Join(root, "project")
+
+	if err := m.AddProject(project); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := m.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.AutoRehash || len(config.Projects) != 1 || config.Projects[0] != project {
+		t.Errorf("config = %+v, want defaults with project %s", config, project)
+	}
+}
+
+// A hand edit is noticed from config.yaml's content, even when it leaves the
+// file's modification time older than the index
+func TestGlobalVersionIndexFollowsContent(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root)
+	if err := m.SetGlobalVersion("3.9.6"); err != nil {
+		t.Fatal(err)
+	}
+	if version, ok := m.GetGlobalVersionFast(); !ok || version != "3.9.6" {
+		t.Fatalf("GetGlobalVersionFast() = %q, %v; want 3.9.6 from the index", version, ok)
+	}
+
+	path := filepath.Join(root, "config", "config.yaml")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(data), "3.9.6", "3.8.8", 1)
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := info.ModTime().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := m.GetGlobalVersionFast(); ok {
+		t.Fatal("GetGlobalVersionFast() trusted an index of other config.yaml content")
+	}
+
+	if err := m.TryRefreshGlobalVersionIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if version, ok := m.GetGlobalVersionFast(); !ok || version != "3.8.8" {
+		t.Errorf("after refresh GetGlobalVersionFast() = %q, %v; want 3.8.8", version, ok)
+	}
+}
+
+// The shim's refresh doesn't wait for a process saving the config
+func TestTryRefreshGlobalVersionIndexDoesNotWait(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root)
+	if err := m.SetGlobalVersion("3.9.6"); err != nil {
+		t.Fatal(err)
+	}
+
+	held, err := lock.Acquire(root, lockName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Release()
+
+	start := time.Now()
+	if err := m.TryRefreshGlobalVersionIndex(); err == nil {
+		t.Error("TryRefreshGlobalVersionIndex() succeeded while the config was locked")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %s for the config lock", elapsed)
+	}
+}
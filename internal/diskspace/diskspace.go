@@ -0,0 +1,127 @@
+package diskspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Headroom is added to every volume's requirement so an install never fills
+// a volume to the last byte
+const Headroom = 32 * 1024 * 1024
+
+// Need is space required on the volume holding Path
+type Need struct {
+	Path  string
+	Bytes int64
+	// What describes the use in messages, e.g. "download cache"
+	What string
+}
+
+// InsufficientError reports a volume without enough free space
+type InsufficientError struct {
+	Volume    string
+	What      []string
+	Required  int64
+	Available int64
+}
+
+func (e *InsufficientError) Error() string {
+	return fmt.Sprintf("insufficient disk space on %s for the %s: required %d MB, available %d MB",
+		e.Volume, strings.Join(e.What, " and "), e.Required/(1024*1024), e.Available/(1024*1024))
+}
+
+// IsInsufficient reports whether err is an *InsufficientError
+func IsInsufficient(err error) bool {
+	var insufficient *InsufficientError
+	return errors.As(err, &insufficient)
+}
+
+// Check verifies that every volume has room for the needs placed on it.
+// Needs on the same volume are added up; volumes that differ are checked
+// separately. An *InsufficientError is returned for the first volume that
+// is short; other errors mean free space could not be determined.
+func Check(needs ...Need) error {
+	type volumeNeed struct {
+		path  string
+		what  []string
+		bytes int64
+	}
+	volumes := make(map[string]*volumeNeed)
+
+	for _, n := range needs {
+		path, err := existingAncestor(n.Path)
+		if err != nil {
+			return err
+		}
+		id, err := volumeID(path)
+		if err != nil {
+			return fmt.Errorf("identify volume of %s: %w", n.Path, err)
+		}
+
+		v, ok := volumes[id]
+		if !ok {
+			v = &volumeNeed{path: path}
+			volumes[id] = v
+		}
+		v.bytes += n.Bytes
+		v.what = append(v.what, n.What)
+	}
+
+	ids := make([]string, 0, len(volumes))
+	for id := range volumes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		v := volumes[id]
+		available, err := available(v.path)
+		if err != nil {
+			return fmt.Errorf("check free space of %s: %w", v.path, err)
+		}
+		if required := v.bytes + Headroom; available < required {
+			return &InsufficientError{
+				Volume:    volumeName(v.path),
+				What:      v.what,
+				Required:  required,
+				Available: available,
+			}
+		}
+	}
+
+	return nil
+}
+
+// Available returns the free space available to the current user on the
+// volume holding path, which need not exist yet
+func Available(path string) (int64, error) {
+	existing, err := existingAncestor(path)
+	if err != nil {
+		return 0, err
+	}
+	return available(existing)
+}
+
+// existingAncestor returns path, or its closest parent that exists, so space
+// can be checked before directories are created
+func existingAncestor(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no existing directory above %s", path)
+		}
+		dir = parent
+	}
+}
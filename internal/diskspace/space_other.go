@@ -0,0 +1,20 @@
+//go:build !windows && !linux && !darwin && !freebsd
+// +build !windows,!linux,!darwin,!freebsd
+
+package diskspace
+
+import "errors"
+
+var errUnsupported = errors.New("free space can't be determined on this platform")
+
+func available(dir string) (int64, error) {
+	return 0, errUnsupported
+}
+
+func volumeID(dir string) (string, error) {
+	return "", errUnsupported
+}
+
+func volumeName(dir string) string {
+	return dir
+}
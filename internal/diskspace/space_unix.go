@@ -0,0 +1,33 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package diskspace
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// available returns the free space for unprivileged users on the file
+// system holding dir
+func available(dir string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, fmt.Errorf("statfs: %w", err)
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// volumeID identifies the file system holding dir by its device number
+func volumeID(dir string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(dir, &st); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(st.Dev), nil
+}
+
+// volumeName names the file system holding dir for messages
+func volumeName(dir string) string {
+	return "the file system of " + dir
+}
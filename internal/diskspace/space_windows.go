@@ -1,24 +1,19 @@
 //go:build windows
 // +build windows
 
-package version
+package diskspace
 
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"unsafe"
 )
 
-// getAvailableDiskSpace returns the available disk space in bytes for the given path
-func (i *VersionInstaller) getAvailableDiskSpace(path string) (int64, error) {
-	// Get the volume root path
-	volumePath := filepath.VolumeName(path)
-	if volumePath == "" {
-		volumePath = filepath.Dir(path)
-	}
-	volumePath += string(filepath.Separator)
-
+// available returns the free space for the current user on the volume
+// holding dir
+func available(dir string) (int64, error) {
 	// Windows API call to GetDiskFreeSpaceExW
 	kernel32 := syscall.NewLazyDLL("kernel32.dll")
 	getDiskFreeSpaceEx := kernel32.NewProc("GetDiskFreeSpaceExW")
@@ -27,13 +22,13 @@ func (i *VersionInstaller) getAvailableDiskSpace(path string) (int64, error) {
 	var totalBytes int64
 	var totalFreeBytes int64
 
-	volumePathPtr, err := syscall.UTF16PtrFromString(volumePath)
+	dirPtr, err := syscall.UTF16PtrFromString(dir)
 	if err != nil {
 		return 0, fmt.Errorf("failed to convert path to UTF16: %w", err)
 	}
 
 	ret, _, err := getDiskFreeSpaceEx.Call(
-		uintptr(unsafe.Pointer(volumePathPtr)),
+		uintptr(unsafe.Pointer(dirPtr)),
 		uintptr(unsafe.Pointer(&freeBytesAvailable)),
 		uintptr(unsafe.Pointer(&totalBytes)),
 		uintptr(unsafe.Pointer(&totalFreeBytes)),
@@ -45,3 +40,13 @@ func (i *VersionInstaller) getAvailableDiskSpace(path string) (int64, error) {
 
 	return freeBytesAvailable, nil
 }
+
+// volumeID identifies the volume holding dir: its drive letter or UNC share
+func volumeID(dir string) (string, error) {
+	return strings.ToUpper(filepath.VolumeName(dir)), nil
+}
+
+// volumeName names the volume holding dir for messages
+func volumeName(dir string) string {
+	return filepath.VolumeName(dir) + string(filepath.Separator)
+}
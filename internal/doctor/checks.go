@@ -0,0 +1,356 @@
+package doctor
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/cache"
+	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+	"github.com/veenone/mvnenv-win/internal/repository"
+	"github.com/veenone/mvnenv-win/internal/shim"
+	"github.com/veenone/mvnenv-win/internal/tool"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+	"github.com/veenone/mvnenv-win/pkg/maven"
+)
+
+// versionCacheMaxAge matches when install and latest refetch versions.json
+const versionCacheMaxAge = 24 * time.Hour
+
+// launcherExtensions are tried when looking for another mvn on PATH
+var launcherExtensions = []string{".exe", ".cmd", ".bat", ""}
+
+// checks returns the checks in the order they run: configuration first,
+// since most other checks read it
+func (d *Doctor) checks() []check {
+	checks := []check{
+		{"config", d.checkConfig},
+		{"shims.binary", d.checkShimBinary},
+		{"shims.files", d.checkShimFiles},
+		{"shims.path", d.checkPath},
+	}
+	for _, def := range tool.All() {
+		def := def
+		name := "global"
+		if !def.IsMaven() {
+			name += "." + def.Name
+		}
+		checks = append(checks, check{name, func(ctx context.Context) Result { return d.checkGlobal(def) }})
+	}
+	return append(checks,
+		check{"version.current", d.checkCurrentVersion},
+		check{"java", d.checkJava},
+		check{"cache.versions", d.checkVersionCache},
+		check{"cache.archives", d.checkArchives},
+		check{"nexus.tls", d.checkNexusTLS},
+		check{"nexus.reachable", d.checkNexus},
+	)
+}
+
+func (d *Doctor) checkConfig(ctx context.Context) Result {
+	configPath := filepath.Join(d.mvnenvRoot, "config", "config.yaml")
+	remedy := "Fix " + configPath + " (see config.example.yaml for the format)"
+
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return ok("no config.yaml; defaults are used")
+	}
+	if _, err := config.NewManager(d.mvnenvRoot).Load(); err != nil {
+		return fail(remedy, "%v", err)
+	}
+	if err := httpclient.LoadConfigured(d.mvnenvRoot); err != nil {
+		return fail(remedy, "invalid network settings: %v", err)
+	}
+	if err := tool.LoadConfigured(d.mvnenvRoot); err != nil {
+		return fail(remedy, "invalid tool definitions: %v", err)
+	}
+	return ok("%s is valid", configPath)
+}
+
+func (d *Doctor) checkShimBinary(ctx context.Context) Result {
+	binary := shim.NewShimGenerator(d.mvnenvRoot).ShimBinary()
+	if _, err := os.Stat(binary); err != nil {
+		return fail("Reinstall mvnenv, or copy shim.exe from the release to "+binary, "shim.exe not found at %s", binary)
+	}
+	return ok("%s exists", binary)
+}
+
+func (d *Doctor) checkShimFiles(ctx context.Context) Result {
+	generator := shim.NewShimGenerator(d.mvnenvRoot)
+	if _, err := os.Stat(generator.ShimBinary()); err != nil {
+		return fail("Fix shims.binary first, then run 'mvnenv rehash'", "shims can't be generated without shim.exe")
+	}
+
+	stale, err := generator.CheckShims()
+	if err != nil {
+		return fail("Run 'mvnenv rehash'", "%v", err)
+	}
+	if len(stale) > 0 {
+		return fail("Run 'mvnenv rehash'", "missing or outdated shims: %s", strings.Join(stale, ", ")).
+			withFix(func(ctx context.Context) error {
+				_, err := generator.GenerateShims()
+				return err
+			})
+	}
+	return ok("shims in %s are up to date", generator.ShimsDir())
+}
+
+// checkPath checks that the shims directory is on PATH before any other
+// directory providing mvn
+func (d *Doctor) checkPath(ctx context.Context) Result {
+	shimsDir := shim.NewShimGenerator(d.mvnenvRoot).ShimsDir()
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		if samePath(dir, shimsDir) {
+			return ok("%s is on PATH", shimsDir)
+		}
+		if mvn := findLauncher(dir, "mvn"); mvn != "" {
+			return fail("Move "+shimsDir+" before "+dir+" in PATH, then open a new terminal",
+				"%s comes before the shims on PATH, so mvn bypasses mvnenv", mvn)
+		}
+	}
+	return fail("Add "+shimsDir+" to the start of your user PATH, then open a new terminal",
+		"%s is not on PATH", shimsDir)
+}
+
+func (d *Doctor) checkGlobal(def *tool.Definition) Result {
+	configMgr := config.NewManager(d.mvnenvRoot)
+	global, err := configMgr.GetToolGlobalVersion(def.Name)
+	if err != nil {
+		return fail("Fix config.yaml", "can't read the global %s version: %v", def.DisplayName, err)
+	}
+	if global == "" {
+		return ok("no global %s version set", def.DisplayName)
+	}
+
+	if !versionpkg.NewToolResolver(d.mvnenvRoot, def).IsVersionInstalled(global) {
+		return fail(fmt.Sprintf("Run 'mvnenv install %s%s', or choose an installed version with 'mvnenv global %s<version>'",
+			toolArg(def), global, toolArg(def)),
+			"global %s version %s is not installed", def.DisplayName, global)
+	}
+
+	// The shims read the global version from an index kept next to config.yaml
+	if indexed, fresh := configMgr.GetToolGlobalVersionFast(def.Name); !fresh || indexed != global {
+		return warn("Run 'mvnenv doctor --fix'", "the global %s version index is out of date with config.yaml", def.DisplayName).
+			withFix(func(ctx context.Context) error {
+				return configMgr.RefreshGlobalVersionIndex()
+			})
+	}
+	return ok("global %s version %s is installed", def.DisplayName, global)
+}
+
+func (d *Doctor) checkCurrentVersion(ctx context.Context) Result {
+	resolved, err := versionpkg.NewVersionResolver(d.mvnenvRoot).ResolveVersion()
+	if err != nil {
+		if versionpkg.IsNoVersionSetError(err) {
+			return warn("Run 'mvnenv global <version>' or 'mvnenv local <version>'", "no Maven version is set for this directory")
+		}
+		var versionErr *versionpkg.VersionError
+		if errors.As(err, &versionErr) {
+			return fail(fmt.Sprintf("Run 'mvnenv install %s'", versionErr.Version), "%v", err)
+		}
+		return fail("", "%v", err)
+	}
+	return ok("Maven %s (%s version)", resolved.Version, resolved.Source)
+}
+
+func (d *Doctor) checkJava(ctx context.Context) Result {
+	javaHome := os.Getenv("JAVA_HOME")
+	if javaHome == "" {
+		if java, err := exec.LookPath("java"); err == nil {
+			return warn("Set JAVA_HOME to the JDK Maven should use", "JAVA_HOME is not set; Maven falls back to %s", java)
+		}
+		return fail("Install a JDK and set JAVA_HOME to its directory", "JAVA_HOME is not set and java is not on PATH")
+	}
+
+	for _, name := range []string{"java.exe", "java"} {
+		if info, err := os.Stat(filepath.Join(javaHome, "bin", name)); err == nil && !info.IsDir() {
+			return ok("JAVA_HOME is %s", javaHome)
+		}
+	}
+	return fail("Point JAVA_HOME at a JDK directory containing bin\\java.exe",
+		"JAVA_HOME is %s, which has no bin\\java.exe", javaHome)
+}
+
+func (d *Doctor) checkVersionCache(ctx context.Context) Result {
+	cacheManager := cache.NewManager(d.mvnenvRoot)
+	if !cacheManager.CacheExists() {
+		return ok("versions.json not created yet; it is fetched when needed")
+	}
+
+	refresh := func(ctx context.Context) error {
+		versions, err := repository.NewManager(d.mvnenvRoot).ListVersions(ctx)
+		if err != nil {
+			return err
+		}
+		if versions, err = maven.SortVersions(versions); err != nil {
+			return err
+		}
+		return cacheManager.SaveVersions(versions)
+	}
+
+	versions, err := cacheManager.LoadVersions()
+	if err != nil {
+		return warn("Run 'mvnenv update'", "versions.json is unreadable: %v", err).withFix(refresh)
+	}
+	if len(versions) == 0 {
+		return warn("Run 'mvnenv update'", "versions.json lists no versions").withFix(refresh)
+	}
+	age, err := cacheManager.GetCacheAge()
+	if err != nil || age > versionCacheMaxAge {
+		return warn("Run 'mvnenv update'", "versions.json is stale (updated %d days ago)", int(age.Hours()/24)).withFix(refresh)
+	}
+	return ok("versions.json lists %d versions", len(versions))
+}
+
+func (d *Doctor) checkArchives(ctx context.Context) Result {
+	store := cache.NewArchiveStore(d.mvnenvRoot)
+	results, err := store.Verify()
+	if err != nil {
+		return fail("Run 'mvnenv cache clear'", "can't read the download cache: %v", err)
+	}
+
+	var corrupt []cache.VerifyResult
+	for _, r := range results {
+		if !r.OK {
+			corrupt = append(corrupt, r)
+		}
+	}
+	if len(corrupt) > 0 {
+		names := make([]string, len(corrupt))
+		for i, r := range corrupt {
+			names[i] = r.Item.Tool + " " + r.Item.Version
+		}
+		return warn("Run 'mvnenv cache verify' for details; corrupt archives are downloaded again when needed",
+			"%d of %d cached archives are corrupt: %s", len(corrupt), len(results), strings.Join(names, ", ")).
+			withFix(func(ctx context.Context) error {
+				for _, r := range corrupt {
+					if err := store.Remove(r.Item.Tool, r.Item.Version); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+	}
+	return ok("%d cached archives verified", len(results))
+}
+
+// nexusConfig returns the configured Nexus, or nil when it is not enabled
+func (d *Doctor) nexusConfig() (*config.NexusConfig, error) {
+	cfg, err := config.NewManager(d.mvnenvRoot).Load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Repositories == nil || cfg.Repositories.Nexus == nil || !cfg.Repositories.Nexus.Enabled {
+		return nil, nil
+	}
+	return cfg.Repositories.Nexus, nil
+}
+
+func (d *Doctor) checkNexusTLS(ctx context.Context) Result {
+	nexusCfg, err := d.nexusConfig()
+	if err != nil {
+		return warn("Fix config first", "skipped: %v", err)
+	}
+	if nexusCfg == nil || nexusCfg.TLS == nil {
+		return ok("no custom TLS settings")
+	}
+
+	if nexusCfg.TLS.CAFile == "" {
+		if nexusCfg.TLS.InsecureSkipVerify {
+			return warn("Set repositories.nexus.tls.ca_file to the server's CA instead of insecure_skip_verify",
+				"TLS certificate verification is disabled for Nexus")
+		}
+		return ok("no custom CA file")
+	}
+
+	remedy := "Set repositories.nexus.tls.ca_file to a readable PEM file with the CA certificate"
+	data, err := os.ReadFile(nexusCfg.TLS.CAFile)
+	if err != nil {
+		return fail(remedy, "can't read CA file: %v", err)
+	}
+
+	var certs int
+	var expired []string
+	for rest := data; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fail(remedy, "%s has an invalid certificate: %v", nexusCfg.TLS.CAFile, err)
+		}
+		certs++
+		if time.Now().After(cert.NotAfter) {
+			expired = append(expired, fmt.Sprintf("%s (expired %s)", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02")))
+		}
+	}
+
+	if certs == 0 {
+		return fail(remedy, "%s contains no PEM certificates", nexusCfg.TLS.CAFile)
+	}
+	if len(expired) > 0 {
+		return warn("Replace the expired certificates in "+nexusCfg.TLS.CAFile, "expired CA certificates: %s", strings.Join(expired, ", "))
+	}
+	return ok("%s has %d certificates", nexusCfg.TLS.CAFile, certs)
+}
+
+func (d *Doctor) checkNexus(ctx context.Context) Result {
+	ctx, cancel := context.WithTimeout(ctx, NexusTimeout)
+	defer cancel()
+
+	repoManager := repository.NewManager(d.mvnenvRoot)
+	configured, err := repoManager.CheckNexus(ctx)
+	if !configured {
+		return ok("Nexus is not configured")
+	}
+	if err != nil {
+		return fail("Check repositories.nexus (base_url, credentials, TLS) and the proxy settings in config.yaml",
+			"Nexus is unreachable: %v", err)
+	}
+
+	nexusCfg, _ := d.nexusConfig()
+	if nexusCfg != nil {
+		return ok("%s is reachable", nexusCfg.BaseURL)
+	}
+	return ok("Nexus is reachable")
+}
+
+// findLauncher returns the path of a command in dir, if there is one
+func findLauncher(dir, command string) string {
+	for _, ext := range launcherExtensions {
+		path := filepath.Join(dir, command+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// samePath compares directories the way Windows does, ignoring case and
+// trailing separators
+func samePath(a, b string) bool {
+	return strings.EqualFold(filepath.Clean(a), filepath.Clean(b))
+}
+
+// toolArg returns the --tool argument to repeat in command hints
+func toolArg(def *tool.Definition) string {
+	if def.IsMaven() {
+		return ""
+	}
+	return "--tool " + def.Name + " "
+}
@@ -0,0 +1,144 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Status is the outcome of one check
+type Status string
+
+const (
+	StatusOK   Status = "ok"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// NexusTimeout bounds the Nexus reachability check
+const NexusTimeout = 20 * time.Second
+
+// Result holds the outcome of one check
+type Result struct {
+	Check   string `json:"check"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+
+	// Remedy tells how to fix a warning or failure
+	Remedy string `json:"remedy,omitempty"`
+
+	// Fixed is set when --fix repaired the problem; FixError when it tried
+	// and failed
+	Fixed    bool   `json:"fixed,omitempty"`
+	FixError string `json:"fix_error,omitempty"`
+
+	// fix repairs the problem; nil when there is no safe fix
+	fix func(ctx context.Context) error
+}
+
+// Fixable reports whether --fix can repair the problem
+func (r Result) Fixable() bool {
+	return r.Status != StatusOK && r.fix != nil
+}
+
+// Summary is the machine-readable report of a doctor run
+type Summary struct {
+	Root      string    `json:"root"`
+	Version   string    `json:"mvnenv_version,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+	OK        int       `json:"ok"`
+	Warnings  int       `json:"warnings"`
+	Failures  int       `json:"failures"`
+	Results   []Result  `json:"results"`
+}
+
+// check runs one diagnosis
+type check struct {
+	name string
+	run  func(ctx context.Context) Result
+}
+
+// Doctor runs the environment checks of an mvnenv root
+type Doctor struct {
+	mvnenvRoot string
+	fix        bool
+	onResult   func(result Result)
+}
+
+// New creates a doctor for an mvnenv root
+func New(mvnenvRoot string) *Doctor {
+	return &Doctor{mvnenvRoot: mvnenvRoot}
+}
+
+// SetFix enables applying the safe fixes of failed checks
+func (d *Doctor) SetFix(fix bool) {
+	d.fix = fix
+}
+
+// OnResult registers a callback invoked as each check finishes
+func (d *Doctor) OnResult(fn func(result Result)) {
+	d.onResult = fn
+}
+
+// Run runs every check in order and summarizes the results. With fixing
+// enabled, a check with a safe fix is repaired and run again.
+func (d *Doctor) Run(ctx context.Context) (*Summary, error) {
+	summary := &Summary{Root: d.mvnenvRoot, CheckedAt: time.Now().UTC(), Results: []Result{}}
+
+	for _, c := range d.checks() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		result := d.runCheck(ctx, c)
+		if d.fix && result.Fixable() {
+			if err := result.fix(ctx); err != nil {
+				result.FixError = err.Error()
+			} else {
+				result = d.runCheck(ctx, c)
+				result.Fixed = true
+			}
+		}
+
+		switch result.Status {
+		case StatusOK:
+			summary.OK++
+		case StatusWarn:
+			summary.Warnings++
+		default:
+			summary.Failures++
+		}
+		summary.Results = append(summary.Results, result)
+		if d.onResult != nil {
+			d.onResult(result)
+		}
+	}
+
+	return summary, nil
+}
+
+// runCheck runs a check and names its result
+func (d *Doctor) runCheck(ctx context.Context, c check) Result {
+	result := c.run(ctx)
+	result.Check = c.name
+	return result
+}
+
+// ok, warn and fail build results
+func ok(format string, args ...interface{}) Result {
+	return Result{Status: StatusOK, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(remedy, format string, args ...interface{}) Result {
+	return Result{Status: StatusWarn, Message: fmt.Sprintf(format, args...), Remedy: remedy}
+}
+
+func fail(remedy, format string, args ...interface{}) Result {
+	return Result{Status: StatusFail, Message: fmt.Sprintf(format, args...), Remedy: remedy}
+}
+
+// withFix attaches a safe fix to a result
+func (r Result) withFix(fix func(ctx context.Context) error) Result {
+	r.fix = fix
+	return r
+}
@@ -1,95 +1,265 @@
 package download
 
 import (
+	"context"
 	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+	"github.com/veenone/mvnenv-win/internal/lock"
+	"github.com/veenone/mvnenv-win/internal/progress"
 )
 
-// Downloader handles file downloads with progress tracking
+// Downloader handles file downloads with progress tracking. Interrupted
+// downloads are resumed with HTTP Range requests when the server supports them.
 type Downloader struct {
-	client *http.Client
+	client    *http.Client
+	prepare   func(*http.Request)
+	preflight PreflightFunc
 }
 
 // NewDownloader creates a new downloader
 func NewDownloader() *Downloader {
 	return &Downloader{
-		client: &http.Client{},
+		client: httpclient.Default(),
+	}
+}
+
+// NewDownloaderWithClient creates a downloader that sends requests with the
+// given client, calling prepare (e.g. to add credentials) on each request
+func NewDownloaderWithClient(client *http.Client, prepare func(*http.Request)) *Downloader {
+	return &Downloader{
+		client:  client,
+		prepare: prepare,
 	}
 }
 
 // ProgressCallback is called during download to report progress
 type ProgressCallback func(downloaded int64, total int64)
 
-// Download downloads a file from URL to destination path with retry logic
-func (d *Downloader) Download(url string, destPath string, progress ProgressCallback) error {
-	const maxRetries = 3
-	var lastErr error
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		if attempt > 0 {
-			// Exponential backoff: 1s, 2s, 4s
-			backoff := time.Duration(1<<uint(attempt-1)) * time.Second
-			fmt.Printf("Retrying download in %v (attempt %d/%d)...\n", backoff, attempt+1, maxRetries)
-			time.Sleep(backoff)
+// PreflightFunc is called before a response body is written with the number
+// of bytes the server advertises are still to come. Returning an error
+// aborts the download without retrying.
+type PreflightFunc func(remaining int64) error
+
+// SetPreflight sets a check run before data is written, e.g. for disk space
+func (d *Downloader) SetPreflight(preflight PreflightFunc) {
+	d.preflight = preflight
+}
+
+// HTTPError is returned when the server answers with an unexpected status
+type HTTPError = httpclient.HTTPError
+
+// retryPolicy returns the policy downloads are retried under
+var retryPolicy = httpclient.Retry
+
+// Download downloads a file from URL to destination path. Data is written to
+// <destPath>.part, which is renamed into place once complete. Failed attempts
+// are retried under the shared retry policy, resuming from the bytes already
+// received if the server supports ranges and the file hasn't changed.
+//
+// A resumable .part file is kept when the download fails or ctx is canceled,
+// with the URL and validator in <destPath>.part.json, so a later download to
+// the same path picks up where it stopped. Downloads to the same path from
+// several processes take turns.
+func (d *Downloader) Download(ctx context.Context, url string, destPath string, progress ProgressCallback) error {
+	fileLock, err := lock.AcquireFile(destPath+".lock", lock.Timeout())
+	if err != nil {
+		return httpclient.Permanent(err)
+	}
+	defer fileLock.Release()
+
+	part := loadPartial(destPath+".part", url)
+
+	policy := retryPolicy()
+	policy.OnRetry = func(err error, delay time.Duration, attempt int) {
+		reportRetry(ctx, err, delay, attempt, policy.Attempts)
+	}
+
+	err = policy.Do(ctx, func() error {
+		return d.attempt(ctx, url, part, progress)
+	})
+	if err != nil {
+		if !part.resumable() {
+			part.remove()
 		}
+		return err
+	}
 
-		err := d.downloadWithRetry(url, destPath, progress)
-		if err == nil {
-			return nil
+	if err := os.Rename(part.path, destPath); err != nil {
+		return fmt.Errorf("rename download: %w", err)
+	}
+	os.Remove(part.metaPath())
+	return nil
+}
+
+// reportRetry reports a retry on the progress task carried by ctx, or on
+// stderr when there is none, keeping stdout for the command's output
+func reportRetry(ctx context.Context, err error, delay time.Duration, attempt, attempts int) {
+	if task := progress.TaskFrom(ctx); task != nil {
+		task.Retry(err, delay, attempt, attempts)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Retrying download in %v (attempt %d/%d): %v\n", delay.Round(time.Second), attempt, attempts, err)
+}
+
+// partialDownload tracks a .part file across attempts and runs
+type partialDownload struct {
+	path string
+	url  string
+	// offset is how many bytes of the file have been received
+	offset int64
+	// validator is the strong ETag or Last-Modified of the file being
+	// resumed, sent as If-Range so a changed file is downloaded in full
+	validator string
+}
+
+// partialMeta is what <file>.part.json records about a .part file
+type partialMeta struct {
+	URL       string `json:"url"`
+	Validator string `json:"validator"`
+}
+
+// loadPartial returns the .part file of a download, resuming one left by an
+// earlier run for the same URL; anything else found there is removed
+func loadPartial(path, url string) *partialDownload {
+	part := &partialDownload{path: path, url: url}
+
+	var meta partialMeta
+	data, err := os.ReadFile(part.metaPath())
+	if err == nil && json.Unmarshal(data, &meta) == nil && meta.URL == url && meta.Validator != "" {
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+			part.offset = info.Size()
+			part.validator = meta.Validator
+			return part
 		}
+	}
+
+	part.remove()
+	return part
+}
+
+func (p *partialDownload) metaPath() string {
+	return p.path + ".json"
+}
 
-		lastErr = err
-		// Delete partial file if download failed
-		os.Remove(destPath)
+// resumable reports whether a later run can resume the .part file
+func (p *partialDownload) resumable() bool {
+	return p.offset > 0 && p.validator != ""
+}
+
+// start records that a full response is being written to the .part file
+func (p *partialDownload) start(validator string) error {
+	p.offset = 0
+	p.validator = validator
+	if validator == "" {
+		os.Remove(p.metaPath())
+		return nil
+	}
+	data, err := json.Marshal(partialMeta{URL: p.url, Validator: validator})
+	if err != nil {
+		return err
 	}
+	return os.WriteFile(p.metaPath(), data, 0644)
+}
+
+// reset drops what was received so the next attempt starts over
+func (p *partialDownload) reset() {
+	p.offset, p.validator = 0, ""
+	os.Remove(p.metaPath())
+}
 
-	return fmt.Errorf("download failed after %d attempts: %w", maxRetries, lastErr)
+// remove deletes the .part file and its metadata
+func (p *partialDownload) remove() {
+	os.Remove(p.path)
+	os.Remove(p.metaPath())
 }
 
-// downloadWithRetry performs a single download attempt
-func (d *Downloader) downloadWithRetry(url string, destPath string, progress ProgressCallback) error {
-	// Create HTTP request
-	resp, err := d.client.Get(url)
+// attempt performs a single download attempt, resuming when possible
+func (d *Downloader) attempt(ctx context.Context, url string, part *partialDownload, progress ProgressCallback) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return httpclient.Permanent(fmt.Errorf("create request: %w", err))
+	}
+	if d.prepare != nil {
+		d.prepare(req)
+	}
+
+	resuming := part.resumable()
+	if resuming {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", part.offset))
+		req.Header.Set("If-Range", part.validator)
+	}
+
+	resp, err := d.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("HTTP GET failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
+	var out *os.File
+	var total int64
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && resuming:
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != part.offset {
+			// Unusable range; drop what we have and restart in full
+			part.reset()
+			return fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		total = size
+		out, err = os.OpenFile(part.path, os.O_WRONLY|os.O_APPEND, 0644)
+
+	case resp.StatusCode == http.StatusOK:
+		// Full response: a fresh download, or the file changed upstream
+		if err := part.start(rangeValidator(resp)); err != nil {
+			return httpclient.Permanent(fmt.Errorf("record download: %w", err))
+		}
+		total = resp.ContentLength
+		out, err = os.Create(part.path)
 
-	// Create destination file
-	out, err := os.Create(destPath)
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && resuming:
+		// The .part file doesn't fit the file on the server; start over
+		part.reset()
+		return fmt.Errorf("server can't resume the download (%s)", resp.Status)
+
+	default:
+		return httpclient.NewHTTPError(resp)
+	}
 	if err != nil {
-		return fmt.Errorf("create file: %w", err)
+		return httpclient.Permanent(fmt.Errorf("open file: %w", err))
 	}
 	defer out.Close()
 
-	// Get total size
-	totalSize := resp.ContentLength
+	if d.preflight != nil && total > 0 {
+		if err := d.preflight(total - part.offset); err != nil {
+			return httpclient.Permanent(err)
+		}
+	}
 
-	// Copy with progress
-	var downloaded int64
+	// Copy with progress, within the shared rate limit
+	body := httpclient.LimitReader(ctx, resp.Body)
 	buf := make([]byte, 32*1024) // 32KB buffer
 
 	for {
-		n, err := resp.Body.Read(buf)
+		n, err := body.Read(buf)
 		if n > 0 {
 			_, writeErr := out.Write(buf[:n])
 			if writeErr != nil {
-				return fmt.Errorf("write file: %w", writeErr)
+				return httpclient.Permanent(fmt.Errorf("write file: %w", writeErr))
 			}
-			downloaded += int64(n)
+			part.offset += int64(n)
 			if progress != nil {
-				progress(downloaded, totalSize)
+				progress(part.offset, total)
 			}
 		}
 		if err == io.EOF {
@@ -100,56 +270,90 @@ func (d *Downloader) downloadWithRetry(url string, destPath string, progress Pro
 		}
 	}
 
+	if total > 0 && part.offset != total {
+		return fmt.Errorf("incomplete download: received %d of %d bytes", part.offset, total)
+	}
+
 	return nil
 }
 
-// DownloadWithChecksum downloads a file and verifies SHA-512 checksum
-func (d *Downloader) DownloadWithChecksum(url string, checksumURL string, destPath string, progress ProgressCallback) error {
-	// Download main file
-	if err := d.Download(url, destPath, progress); err != nil {
-		return err
+// rangeValidator returns the value to send as If-Range when resuming a
+// response, or "" if the server doesn't support resuming it
+func rangeValidator(resp *http.Response) string {
+	if resp.Header.Get("Accept-Ranges") != "bytes" {
+		return ""
+	}
+	// Weak ETags can't be used with If-Range
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
 	}
+	return resp.Header.Get("Last-Modified")
+}
 
-	// Download checksum
-	resp, err := d.client.Get(checksumURL)
+// parseContentRange parses "bytes <start>-<end>/<size>"; size is -1 if unknown
+func parseContentRange(value string) (start, size int64, ok bool) {
+	var end int64
+	var sizeStr string
+	if _, err := fmt.Sscanf(value, "bytes %d-%d/%s", &start, &end, &sizeStr); err != nil {
+		return 0, 0, false
+	}
+	if sizeStr == "*" {
+		return start, -1, true
+	}
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
 	if err != nil {
-		return fmt.Errorf("download checksum: %w", err)
+		return 0, 0, false
 	}
-	defer resp.Body.Close()
+	return start, size, true
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("checksum HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
+// Fetch reads a small file, such as a checksum or signature, into memory,
+// retrying under the shared retry policy. A non-200 answer is returned as
+// *HTTPError.
+func (d *Downloader) Fetch(ctx context.Context, url string) ([]byte, error) {
+	var data []byte
+	err := httpclient.Retry().Do(ctx, func() error {
+		var err error
+		data, err = d.fetch(ctx, url)
+		return err
+	})
+	return data, err
+}
 
-	checksumData, err := io.ReadAll(resp.Body)
+// fetch makes a single attempt of Fetch
+func (d *Downloader) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("read checksum: %w", err)
+		return nil, httpclient.Permanent(fmt.Errorf("create request: %w", err))
 	}
-
-	// Parse checksum (format: "checksum filename" or just "checksum")
-	checksumStr := strings.TrimSpace(string(checksumData))
-	parts := strings.Fields(checksumStr)
-	if len(parts) == 0 {
-		return fmt.Errorf("empty checksum file")
+	if d.prepare != nil {
+		d.prepare(req)
 	}
-	expectedChecksum := strings.ToLower(parts[0])
 
-	// Calculate actual checksum
-	actualChecksum, err := calculateSHA512(destPath)
+	resp, err := d.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("calculate checksum: %w", err)
+		return nil, fmt.Errorf("HTTP GET failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpclient.NewHTTPError(resp)
 	}
 
-	// Verify checksum
-	if actualChecksum != expectedChecksum {
-		return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedChecksum, actualChecksum)
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
 	}
+	return data, nil
+}
 
-	return nil
+// IsNotFound reports whether err is an HTTP 404 answer
+func IsNotFound(err error) bool {
+	return httpclient.IsNotFound(err)
 }
 
-// calculateSHA512 calculates SHA-512 checksum of a file
-func calculateSHA512(filePath string) (string, error) {
+// FileSHA512 returns the hex SHA-512 checksum of a file
+func FileSHA512(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return "", err
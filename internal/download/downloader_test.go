@@ -0,0 +1,242 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+)
+
+// testServer serves a file, answering each request with the handler for
+// its number (from 1) and recording the requests
+type testServer struct {
+	*httptest.Server
+	t        *testing.T
+	handlers []http.HandlerFunc
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newTestServer(t *testing.T, handlers ...http.HandlerFunc) *testServer {
+	s := &testServer{t: t, handlers: handlers}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		n := len(s.requests)
+		s.mu.Unlock()
+
+		if n > len(s.handlers) {
+			t.Errorf("unexpected request %d (Range %q)", n, r.Header.Get("Range"))
+			http.Error(w, "unexpected request", http.StatusInternalServerError)
+			return
+		}
+		s.handlers[n-1](w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// request returns request n (from 1)
+func (s *testServer) request(n int) *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n > len(s.requests) {
+		s.t.Fatalf("got %d requests, want at least %d", len(s.requests), n)
+	}
+	return s.requests[n-1]
+}
+
+// serve answers with content under an ETag, handling Range and If-Range
+func serve(content []byte, etag string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file.zip", time.Time{}, bytes.NewReader(content))
+	}
+}
+
+// dropAfter starts a full answer with content under an ETag, then closes
+// the connection after n bytes
+func dropAfter(t *testing.T, content []byte, etag string, n int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(content[:n])
+
+		rc := http.NewResponseController(w)
+		if err := rc.Flush(); err != nil {
+			t.Errorf("flush: %v", err)
+			return
+		}
+		conn, _, err := rc.Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		conn.Close()
+	}
+}
+
+// testContent returns n bytes that differ with seed
+func testContent(n int, seed byte) []byte {
+	content := make([]byte, n)
+	for i := range content {
+		content[i] = byte(i*7) + seed
+	}
+	return content
+}
+
+// fastRetries retries without waiting for the rest of the test
+func fastRetries(t *testing.T) {
+	saved := retryPolicy
+	retryPolicy = func() httpclient.RetryPolicy {
+		return httpclient.RetryPolicy{Attempts: 4, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	}
+	t.Cleanup(func() { retryPolicy = saved })
+}
+
+func download(t *testing.T, url string) string {
+	t.Helper()
+	dest := filepath.Join(t.TempDir(), "file.zip")
+	if err := NewDownloader().Download(context.Background(), url, dest, nil); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	return dest
+}
+
+func assertFile(t *testing.T, path string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("downloaded %d bytes that don't match the %d served", len(got), len(want))
+	}
+	for _, leftover := range []string{path + ".part", path + ".part.json"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("%s left behind", filepath.Base(leftover))
+		}
+	}
+}
+
+func assertHeader(t *testing.T, r *http.Request, name, want string) {
+	t.Helper()
+	if got := r.Header.Get(name); got != want {
+		t.Errorf("request %s: %s = %q, want %q", r.URL.Path, name, got, want)
+	}
+}
+
+func TestDownloadResumesAfterDroppedConnection(t *testing.T) {
+	fastRetries(t)
+	content := testContent(100000, 0)
+	s := newTestServer(t,
+		dropAfter(t, content, `"v1"`, 40000),
+		serve(content, `"v1"`),
+	)
+
+	dest := download(t, s.URL+"/file.zip")
+
+	assertHeader(t, s.request(1), "Range", "")
+	assertHeader(t, s.request(2), "Range", "bytes=40000-")
+	assertHeader(t, s.request(2), "If-Range", `"v1"`)
+	assertFile(t, dest, content)
+}
+
+func TestDownloadRestartsWhenFileChanged(t *testing.T) {
+	fastRetries(t)
+	old := testContent(100000, 0)
+	changed := testContent(120000, 1)
+	s := newTestServer(t,
+		dropAfter(t, old, `"v1"`, 40000),
+		// If-Range no longer matches, so the server sends the whole file
+		serve(changed, `"v2"`),
+	)
+
+	dest := download(t, s.URL+"/file.zip")
+
+	assertHeader(t, s.request(2), "If-Range", `"v1"`)
+	assertFile(t, dest, changed)
+}
+
+func TestDownloadRestartsOnBadContentRange(t *testing.T) {
+	fastRetries(t)
+	content := testContent(100000, 0)
+	s := newTestServer(t,
+		dropAfter(t, content, `"v1"`, 40000),
+		func(w http.ResponseWriter, r *http.Request) {
+			// A range other than the one asked for
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-99/%d", len(content)))
+			w.Header().Set("Content-Length", "100")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[:100])
+		},
+		serve(content, `"v1"`),
+	)
+
+	dest := download(t, s.URL+"/file.zip")
+
+	assertHeader(t, s.request(2), "Range", "bytes=40000-")
+	assertHeader(t, s.request(3), "Range", "")
+	assertFile(t, dest, content)
+}
+
+func TestDownloadRestartsOnRangeNotSatisfiable(t *testing.T) {
+	fastRetries(t)
+	content := testContent(100000, 0)
+	s := newTestServer(t,
+		dropAfter(t, content, `"v1"`, 40000),
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Range", "bytes */100")
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		},
+		serve(content, `"v1"`),
+	)
+
+	dest := download(t, s.URL+"/file.zip")
+
+	assertHeader(t, s.request(2), "Range", "bytes=40000-")
+	assertHeader(t, s.request(3), "Range", "")
+	assertFile(t, dest, content)
+}
+
+func TestDownloadResumesEarlierRun(t *testing.T) {
+	fastRetries(t)
+	content := testContent(100000, 0)
+	s := newTestServer(t,
+		dropAfter(t, content, `"v1"`, 40000),
+		// An answer that isn't retried ends the first run
+		func(w http.ResponseWriter, r *http.Request) {
+			http.NotFound(w, r)
+		},
+		serve(content, `"v1"`),
+	)
+	url := s.URL + "/file.zip"
+	dest := filepath.Join(t.TempDir(), "file.zip")
+
+	if err := NewDownloader().Download(context.Background(), url, dest, nil); err == nil {
+		t.Fatal("first run succeeded, want the 404 to fail it")
+	}
+	if _, err := os.Stat(dest + ".part.json"); err != nil {
+		t.Fatalf("resumable download not recorded: %v", err)
+	}
+
+	if err := NewDownloader().Download(context.Background(), url, dest, nil); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	assertHeader(t, s.request(3), "Range", "bytes=40000-")
+	assertHeader(t, s.request(3), "If-Range", `"v1"`)
+	assertFile(t, dest, content)
+}
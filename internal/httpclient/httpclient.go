@@ -0,0 +1,166 @@
+// Package httpclient builds the HTTP clients used for every download, so
+// that proxy, TLS, timeout and retry settings apply the same way to all
+// sources
+package httpclient
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/config"
+)
+
+// Defaults used when config.yaml has no network section
+const (
+	DefaultConnectTimeout = 30 * time.Second
+	DefaultReadTimeout    = 60 * time.Second
+	DefaultRetries        = 4
+)
+
+// Options customise a client built by New
+type Options struct {
+	// TLS configures server verification; nil uses the system roots
+	TLS *tls.Config
+
+	// Proxy overrides the configured proxy, e.g. for one repository
+	Proxy *config.ProxyConfig
+}
+
+// clientSettings are the settings loaded from config.yaml
+type clientSettings struct {
+	proxy          *config.ProxyConfig
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	retry          RetryPolicy
+}
+
+var (
+	mu       sync.Mutex
+	settings = clientSettings{
+		connectTimeout: DefaultConnectTimeout,
+		readTimeout:    DefaultReadTimeout,
+		retry: RetryPolicy{
+			Attempts:  DefaultRetries + 1,
+			BaseDelay: time.Second,
+			MaxDelay:  30 * time.Second,
+		},
+	}
+	shared *http.Client
+)
+
+// LoadConfigured applies the proxy and network sections of config.yaml to
+// clients built and transfers started afterwards. An invalid setting is
+// reported and keeps its previous value; the other settings still apply.
+func LoadConfigured(mvnenvRoot string) error {
+	cfg, err := config.NewManager(mvnenvRoot).Load()
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	settings.proxy = cfg.Proxy
+	shared = nil
+
+	network := cfg.Network
+	if network == nil {
+		return nil
+	}
+
+	var errs []error
+	if network.ConnectTimeout != "" {
+		if timeout, err := parseTimeout(network.ConnectTimeout); err != nil {
+			errs = append(errs, fmt.Errorf("network.connect_timeout: %w", err))
+		} else {
+			settings.connectTimeout = timeout
+		}
+	}
+	if network.ReadTimeout != "" {
+		if timeout, err := parseTimeout(network.ReadTimeout); err != nil {
+			errs = append(errs, fmt.Errorf("network.read_timeout: %w", err))
+		} else {
+			settings.readTimeout = timeout
+		}
+	}
+	if network.Retries != nil {
+		if *network.Retries < 0 {
+			errs = append(errs, fmt.Errorf("network.retries: must not be negative"))
+		} else {
+			settings.retry.Attempts = *network.Retries + 1
+		}
+	}
+	if network.LimitRate != "" {
+		if rate, err := ParseRate(network.LimitRate); err != nil {
+			errs = append(errs, fmt.Errorf("network.limit_rate: %w", err))
+		} else {
+			SetRateLimit(rate)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseTimeout parses a duration such as "30s" or "2m"
+func parseTimeout(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration '%s' (e.g. 30s or 2m)", value)
+	}
+	return d, nil
+}
+
+// Transport returns a round tripper with the resolved proxy, the configured
+// timeouts and the given TLS settings
+func Transport(opts Options) http.RoundTripper {
+	mu.Lock()
+	s := settings
+	mu.Unlock()
+
+	proxy := opts.Proxy
+	if proxy == nil {
+		proxy = s.proxy
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   s.connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc(proxy)
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = s.connectTimeout
+	transport.ResponseHeaderTimeout = s.readTimeout
+	if opts.TLS != nil {
+		transport.TLSClientConfig = opts.TLS
+	}
+
+	return &stallTimeout{base: transport, timeout: s.readTimeout}
+}
+
+// New returns a client built with Transport. It has no overall timeout, so
+// large downloads aren't cut off; stalled connections time out instead.
+func New(opts Options) *http.Client {
+	return &http.Client{Transport: Transport(opts)}
+}
+
+// Default returns the client shared by sources without their own TLS or
+// proxy settings
+func Default() *http.Client {
+	mu.Lock()
+	client := shared
+	mu.Unlock()
+	if client != nil {
+		return client
+	}
+
+	client = New(Options{})
+	mu.Lock()
+	shared = client
+	mu.Unlock()
+	return client
+}
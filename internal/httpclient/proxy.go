@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/veenone/mvnenv-win/internal/config"
+)
+
+// DirectProxy is the proxy URL that connects without a proxy, e.g. to
+// override a global proxy for an internal repository
+const DirectProxy = "direct"
+
+// proxyEnv are the variables that make the environment decide the proxy
+var proxyEnv = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy"}
+
+// proxyFunc resolves the proxy of each request, in order of precedence from
+// cfg (the repository override or config.yaml), HTTP(S)_PROXY and NO_PROXY,
+// and the active proxies of ~/.m2/settings.xml
+func proxyFunc(cfg *config.ProxyConfig) func(*http.Request) (*url.URL, error) {
+	if cfg != nil && cfg.URL != "" {
+		if strings.EqualFold(cfg.URL, DirectProxy) {
+			return nil
+		}
+		proxyURL, err := proxyURL(cfg.URL, cfg.Username, cfg.Password)
+		if err != nil {
+			return func(*http.Request) (*url.URL, error) { return nil, err }
+		}
+		return func(req *http.Request) (*url.URL, error) {
+			if bypass(req.URL.Hostname(), cfg.NoProxy) {
+				return nil, nil
+			}
+			return proxyURL, nil
+		}
+	}
+
+	for _, name := range proxyEnv {
+		if os.Getenv(name) != "" {
+			return http.ProxyFromEnvironment
+		}
+	}
+
+	proxies := settingsProxies()
+	if len(proxies) == 0 {
+		return nil
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		p := selectProxy(proxies, req.URL.Scheme)
+		if bypass(req.URL.Hostname(), p.NoProxy) {
+			return nil, nil
+		}
+		return p.URL, p.Err
+	}
+}
+
+// proxyURL parses a proxy address, defaulting to http://, and adds the
+// credentials unless it carries its own
+func proxyURL(raw, username, password string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL '%s'", raw)
+	}
+	if username != "" && u.User == nil {
+		u.User = url.UserPassword(username, password)
+	}
+	return u, nil
+}
+
+// bypass reports whether host is reached without a proxy: loopback
+// addresses always are, and so are hosts matching a no-proxy pattern. A
+// pattern is "*", a host name, a domain suffix (".corp.example" or
+// "*.corp.example"; a bare name also matches its subdomains), an IP address
+// or a CIDR range. Ports in patterns are ignored.
+func bypass(host string, patterns []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if p == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(p); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, _, err := net.SplitHostPort(p); err == nil {
+			p = h
+		}
+		if ip != nil {
+			if pip := net.ParseIP(p); pip != nil && pip.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		p = strings.TrimPrefix(p, "*")
+		if strings.HasPrefix(p, ".") {
+			if strings.HasSuffix(host, p) || host == p[1:] {
+				return true
+			}
+		} else if host == p || strings.HasSuffix(host, "."+p) {
+			return true
+		}
+	}
+	return false
+}
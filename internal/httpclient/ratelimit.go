@@ -0,0 +1,160 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket shared by all transfers of a process, so
+// concurrent downloads and uploads together stay within one rate
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter allowing bytesPerSecond, or nil (no limit)
+// when bytesPerSecond <= 0
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &Limiter{rate: float64(bytesPerSecond), last: time.Now()}
+}
+
+// Rate returns the limit in bytes per second, 0 for a nil limiter
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	return int64(l.rate)
+}
+
+// chunk is the most a single read may take, a quarter second's worth, so
+// waits stay short enough for smooth progress and the read timeout
+func (l *Limiter) chunk() int {
+	return int(math.Max(l.rate/4, 1))
+}
+
+// Wait blocks until n more bytes may be transferred. Callers may overdraw
+// the bucket; the debt is paid by whoever comes next, which keeps a share
+// for each concurrent transfer.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	delay := l.reserve(time.Now(), n)
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes n bytes from the bucket at the time now and returns how long
+// the caller must wait before transferring them
+func (l *Limiter) reserve(now time.Time, n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Unused capacity is kept for up to a second
+	l.tokens = math.Min(l.tokens+now.Sub(l.last).Seconds()*l.rate, l.rate)
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		return time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	return 0
+}
+
+var (
+	limiterMu sync.Mutex
+	limiter   *Limiter
+)
+
+// SetRateLimit limits all transfers started afterwards to bytesPerSecond
+// in total; 0 removes the limit. It overrides network.limit_rate.
+func SetRateLimit(bytesPerSecond int64) {
+	limiterMu.Lock()
+	defer limiterMu.Unlock()
+	limiter = NewLimiter(bytesPerSecond)
+}
+
+// RateLimiter returns the shared limiter, nil when transfers are unlimited
+func RateLimiter() *Limiter {
+	limiterMu.Lock()
+	defer limiterMu.Unlock()
+	return limiter
+}
+
+// LimitReader returns a reader that stays within the shared rate limit, or
+// r itself when there is none
+func LimitReader(ctx context.Context, r io.Reader) io.Reader {
+	l := RateLimiter()
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, reader: r, limiter: l}
+}
+
+// limitedReader waits for the limiter after each read
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *Limiter
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if chunk := r.limiter.chunk(); len(p) > chunk {
+		p = p[:chunk]
+	}
+	n, err := r.reader.Read(p)
+	if waitErr := r.limiter.Wait(r.ctx, n); waitErr != nil {
+		return n, waitErr
+	}
+	return n, err
+}
+
+// ParseRate parses a rate in bytes per second, such as "500K", "2M" or
+// "1.5MB"; units are powers of 1024, and "0" or "" means no limit
+func ParseRate(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(s, "/S")
+	if s == "" {
+		return 0, nil
+	}
+
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		s = s[:len(s)-1]
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("invalid rate '%s' (e.g. 500K or 2M bytes per second)", value)
+	}
+	return int64(n * multiplier), nil
+}
@@ -0,0 +1,110 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{"", 0},
+		{"0", 0},
+		{"1000", 1000},
+		{"500K", 500 << 10},
+		{"500k", 500 << 10},
+		{"500KB", 500 << 10},
+		{"500KiB", 500 << 10},
+		{"2M", 2 << 20},
+		{"1.5MB", 3 << 19},
+		{"2M/s", 2 << 20},
+		{" 1G ", 1 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"fast", "-1M", "2T", "M", "1e400", "NaN"} {
+		if _, err := ParseRate(value); err == nil {
+			t.Errorf("ParseRate(%q) succeeded", value)
+		}
+	}
+}
+
+func TestNewLimiterUnlimited(t *testing.T) {
+	for _, rate := range []int64{0, -1} {
+		l := NewLimiter(rate)
+		if l != nil {
+			t.Errorf("NewLimiter(%d) = %v, want nil", rate, l)
+		}
+		// A nil limiter never waits
+		if err := l.Wait(context.Background(), 1<<30); err != nil || l.Rate() != 0 {
+			t.Errorf("nil limiter: Wait() = %v, Rate() = %d", err, l.Rate())
+		}
+	}
+}
+
+// The bucket is driven by a fake clock through reserve
+func TestLimiterReserve(t *testing.T) {
+	start := time.Now()
+	l := NewLimiter(1000)
+	l.last = start
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	steps := []struct {
+		at   time.Duration
+		n    int
+		want time.Duration
+	}{
+		// The bucket starts empty: 500 bytes take half a second
+		{0, 500, 500 * time.Millisecond},
+		// Half a second later the debt is paid
+		{500 * time.Millisecond, 250, 250 * time.Millisecond},
+		// A concurrent transfer queues behind the first one's debt
+		{500 * time.Millisecond, 250, 500 * time.Millisecond},
+		// After a long idle period at most a second's worth is saved up
+		{10 * time.Second, 1000, 0},
+		{10 * time.Second, 100, 100 * time.Millisecond},
+	}
+	for i, s := range steps {
+		if got := l.reserve(at(s.at), s.n); got != s.want {
+			t.Errorf("step %d: reserve(%v, %d) = %v, want %v", i, s.at, s.n, got, s.want)
+		}
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	l := NewLimiter(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.Wait(ctx, 1000); err != context.Canceled {
+		t.Errorf("Wait() = %v, want context.Canceled", err)
+	}
+}
+
+func TestLimitReader(t *testing.T) {
+	SetRateLimit(0)
+	r := bytes.NewReader(make([]byte, 100))
+	if LimitReader(context.Background(), r) != io.Reader(r) {
+		t.Error("reader wrapped without a limit")
+	}
+
+	SetRateLimit(40)
+	defer SetRateLimit(0)
+	limited := LimitReader(context.Background(), bytes.NewReader(make([]byte, 100)))
+
+	// Reads are cut to a quarter second's worth
+	buf := make([]byte, 100)
+	if n, err := limited.Read(buf); n != 10 || err != nil {
+		t.Errorf("Read() = %d, %v; want 10 bytes", n, err)
+	}
+}
@@ -0,0 +1,169 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryAfter is the longest Retry-After that is waited for; a server
+// asking for more is treated as a failure
+const maxRetryAfter = 5 * time.Minute
+
+// HTTPError is returned when a server answers with an unexpected status
+type HTTPError struct {
+	StatusCode int
+	Status     string
+
+	// RetryAfter is the delay the server asked for with Retry-After, if any
+	RetryAfter time.Duration
+}
+
+// NewHTTPError describes an unexpected response
+func NewHTTPError(resp *http.Response) *HTTPError {
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Status)
+}
+
+// Retryable reports whether the request may succeed if repeated
+func (e *HTTPError) Retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsNotFound reports whether err is an HTTP 404 answer
+func IsNotFound(err error) bool {
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound
+}
+
+// parseRetryAfter parses a Retry-After value in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// permanentError is an error that RetryPolicy.Do returns without retrying
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that retrying can't fix, such as a full disk
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// RetryPolicy is the retry and backoff policy shared by all requests
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, including the first
+	Attempts int
+
+	// BaseDelay is the wait before the first retry; it doubles with each
+	// further retry up to MaxDelay. A Retry-After from the server is used
+	// instead when present.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// OnRetry, if set, is called before waiting to make attempt
+	OnRetry func(err error, delay time.Duration, attempt int)
+}
+
+// Retry returns the configured retry policy
+func Retry() RetryPolicy {
+	mu.Lock()
+	defer mu.Unlock()
+	return settings.retry
+}
+
+// Do calls fn until it succeeds, returns an error that retrying can't fix,
+// the attempts run out or ctx is done. HTTP errors are retried for 5xx, 408
+// and 429 answers only; other errors, such as connection failures and
+// stalled transfers, are always retried.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if attempt >= p.Attempts {
+			break
+		}
+
+		delay, ok := p.delay(err, attempt)
+		if !ok {
+			return err
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(err, delay, attempt+1)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if p.Attempts > 1 {
+		return fmt.Errorf("gave up after %d attempts: %w", p.Attempts, err)
+	}
+	return err
+}
+
+// delay returns how long to wait after a failed attempt, or false if the
+// error must not be retried
+func (p RetryPolicy) delay(err error, attempt int) (time.Duration, bool) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if !httpErr.Retryable() || httpErr.RetryAfter > maxRetryAfter {
+			return 0, false
+		}
+		if httpErr.RetryAfter > 0 {
+			return httpErr.RetryAfter, true
+		}
+	}
+
+	delay := p.BaseDelay << uint(attempt-1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	// Up to 20% jitter keeps clients that failed together from retrying
+	// in lockstep
+	if delay > 0 {
+		delay += time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	}
+	return delay, true
+}
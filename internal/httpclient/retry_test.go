@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	// HTTP dates have a resolution of one second
+	date := time.Now().Add(10 * time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 9*time.Minute || got > 10*time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v, want about 10m", date, got)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := RetryPolicy{Attempts: 5, BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+
+	tests := []struct {
+		name     string
+		err      error
+		attempt  int
+		min, max time.Duration
+		retry    bool
+	}{
+		{"backoff", errors.New("connection reset"), 1, time.Second, 1200 * time.Millisecond, true},
+		{"doubled", errors.New("connection reset"), 2, 2 * time.Second, 2400 * time.Millisecond, true},
+		{"capped", errors.New("connection reset"), 10, 4 * time.Second, 4800 * time.Millisecond, true},
+		{"retry-after", &HTTPError{StatusCode: 503, RetryAfter: 2 * time.Minute}, 1, 2 * time.Minute, 2 * time.Minute, true},
+		{"retry-after at the cap", &HTTPError{StatusCode: 429, RetryAfter: maxRetryAfter}, 1, maxRetryAfter, maxRetryAfter, true},
+		{"retry-after over the cap", &HTTPError{StatusCode: 503, RetryAfter: maxRetryAfter + time.Second}, 1, 0, 0, false},
+		{"server error", &HTTPError{StatusCode: 502}, 1, time.Second, 1200 * time.Millisecond, true},
+		{"not found", &HTTPError{StatusCode: 404}, 1, 0, 0, false},
+		{"unauthorized", &HTTPError{StatusCode: 401}, 1, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		delay, retry := p.delay(tt.err, tt.attempt)
+		if retry != tt.retry || delay < tt.min || delay > tt.max {
+			t.Errorf("%s: delay() = %v, %v; want %v-%v, %v", tt.name, delay, retry, tt.min, tt.max, tt.retry)
+		}
+	}
+}
+
+func TestRetryDo(t *testing.T) {
+	p := RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	calls := 0
+	err := p.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return &HTTPError{StatusCode: 503}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Do() = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	err = p.Do(context.Background(), func() error {
+		calls++
+		return Permanent(errors.New("disk full"))
+	})
+	if err == nil || err.Error() != "disk full" || calls != 1 {
+		t.Errorf("permanent error: Do() = %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = p.Do(context.Background(), func() error {
+		calls++
+		return errors.New("connection reset")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("Do() = %v after %d calls, want failure after 3", err, calls)
+	}
+}
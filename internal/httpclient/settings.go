@@ -0,0 +1,91 @@
+package httpclient
+
+import (
+	"encoding/xml"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mavenSettings is the part of Maven's settings.xml that declares proxies
+type mavenSettings struct {
+	Proxies []struct {
+		Active        string `xml:"active"`
+		Protocol      string `xml:"protocol"`
+		Host          string `xml:"host"`
+		Port          string `xml:"port"`
+		Username      string `xml:"username"`
+		Password      string `xml:"password"`
+		NonProxyHosts string `xml:"nonProxyHosts"`
+	} `xml:"proxies>proxy"`
+}
+
+// settingsProxy is an active proxy from settings.xml
+type settingsProxy struct {
+	Protocol string
+	URL      *url.URL
+	Err      error
+	NoProxy  []string
+}
+
+// settingsProxies returns the active proxies declared in ~/.m2/settings.xml,
+// so downloads use the same proxy as Maven itself. Passwords encrypted with
+// "mvn --encrypt-password" can't be read and are not sent.
+func settingsProxies() []settingsProxy {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".m2", "settings.xml"))
+	if err != nil {
+		return nil
+	}
+
+	var settings mavenSettings
+	if err := xml.Unmarshal(data, &settings); err != nil {
+		return nil
+	}
+
+	var proxies []settingsProxy
+	for _, p := range settings.Proxies {
+		// Proxies are active unless <active>false</active>
+		if strings.TrimSpace(p.Active) == "false" || strings.TrimSpace(p.Host) == "" {
+			continue
+		}
+
+		password := strings.TrimSpace(p.Password)
+		if strings.HasPrefix(password, "{") && strings.HasSuffix(password, "}") {
+			password = ""
+		}
+
+		protocol := strings.ToLower(strings.TrimSpace(p.Protocol))
+		if protocol == "" {
+			protocol = "http"
+		}
+		address := strings.TrimSpace(p.Host)
+		if port := strings.TrimSpace(p.Port); port != "" {
+			address += ":" + port
+		}
+
+		u, err := proxyURL(address, strings.TrimSpace(p.Username), password)
+		proxies = append(proxies, settingsProxy{
+			Protocol: protocol,
+			URL:      u,
+			Err:      err,
+			NoProxy:  strings.FieldsFunc(p.NonProxyHosts, func(r rune) bool { return r == '|' || r == ',' }),
+		})
+	}
+	return proxies
+}
+
+// selectProxy picks the first proxy declared for the request scheme or,
+// like Maven, the first active proxy when none is
+func selectProxy(proxies []settingsProxy, scheme string) settingsProxy {
+	for _, p := range proxies {
+		if p.Protocol == scheme {
+			return p
+		}
+	}
+	return proxies[0]
+}
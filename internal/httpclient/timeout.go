@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// stallTimeout fails response bodies that deliver no data for timeout, so
+// a stalled transfer is retried instead of hanging forever
+type stallTimeout struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *stallTimeout) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || t.timeout <= 0 {
+		return resp, err
+	}
+	resp.Body = newStallBody(resp.Body, t.timeout)
+	return resp, nil
+}
+
+// stallBody closes the underlying body when no read completes in time,
+// which unblocks the pending read
+type stallBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	stalled atomic.Bool
+}
+
+func newStallBody(body io.ReadCloser, timeout time.Duration) *stallBody {
+	b := &stallBody{body: body, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.stalled.Store(true)
+		body.Close()
+	})
+	return b
+}
+
+func (b *stallBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if b.stalled.Load() {
+		return n, fmt.Errorf("no data received for %v", b.timeout)
+	}
+	b.timer.Reset(b.timeout)
+	return n, err
+}
+
+func (b *stallBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dripServer sends chunks of a body with a pause before each
+func dripServer(t *testing.T, chunks int, pause time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < chunks; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(pause):
+			}
+			io.WriteString(w, "chunk\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func stallClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &stallTimeout{base: http.DefaultTransport, timeout: timeout}}
+}
+
+func TestStallTimeoutFailsStalledBody(t *testing.T) {
+	server := dripServer(t, 2, time.Second)
+
+	resp, err := stallClient(200 * time.Millisecond).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	start := time.Now()
+	_, err = io.ReadAll(resp.Body)
+	if err == nil || !strings.Contains(err.Error(), "no data received") {
+		t.Fatalf("ReadAll() = %v, want a stall error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("stall detected after %s", elapsed)
+	}
+}
+
+// A slow body that keeps delivering data is not cut off, however long it
+// takes in total
+func TestStallTimeoutKeepsSlowBody(t *testing.T) {
+	server := dripServer(t, 5, 100*time.Millisecond)
+
+	resp, err := stallClient(300 * time.Millisecond).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "chunk"); got != 5 {
+		t.Errorf("received %d chunks, want 5", got)
+	}
+}
+
+func TestStallTimeoutDisabled(t *testing.T) {
+	server := dripServer(t, 1, 0)
+
+	resp, err := stallClient(0).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, ok := resp.Body.(*stallBody); ok {
+		t.Error("body wrapped without a timeout")
+	}
+}
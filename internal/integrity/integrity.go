@@ -0,0 +1,201 @@
+package integrity
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+)
+
+// Policy decides what happens when an archive's checksum can't be verified
+type Policy string
+
+const (
+	// PolicyStrict refuses archives without a matching published checksum
+	PolicyStrict Policy = "strict"
+
+	// PolicyWarn installs archives without a published checksum after a
+	// warning; a checksum that doesn't match is still an error
+	PolicyWarn Policy = "warn"
+
+	// PolicyOff skips checksum verification
+	PolicyOff Policy = "off"
+)
+
+// DefaultPolicy is used when the configuration doesn't set one
+const DefaultPolicy = PolicyWarn
+
+// ParsePolicy validates a policy name; an empty name means DefaultPolicy
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(name); p {
+	case "":
+		return DefaultPolicy, nil
+	case PolicyStrict, PolicyWarn, PolicyOff:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid integrity policy '%s' (use strict, warn or off)", name)
+	}
+}
+
+// Algorithm is a checksum algorithm published as a sidecar file
+type Algorithm struct {
+	Name   string
+	Suffix string
+	New    func() hash.Hash
+}
+
+// Algorithms lists the supported sidecars, strongest first
+var Algorithms = []Algorithm{
+	{Name: "sha512", Suffix: ".sha512", New: sha512.New},
+	{Name: "sha256", Suffix: ".sha256", New: sha256.New},
+	{Name: "sha1", Suffix: ".sha1", New: sha1.New},
+	{Name: "md5", Suffix: ".md5", New: md5.New},
+}
+
+// Status is the outcome of verifying an archive's checksum
+type Status string
+
+const (
+	// StatusVerified means the archive matches its published checksum
+	StatusVerified Status = "verified"
+
+	// StatusMismatch means the archive doesn't match its published checksum
+	StatusMismatch Status = "mismatch"
+
+	// StatusMissing means no checksum could be fetched for the archive
+	StatusMissing Status = "missing"
+)
+
+// Result describes a checksum verification
+type Result struct {
+	Status    Status `json:"status"`
+	Algorithm string `json:"algorithm,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// String formats the result for display
+func (r Result) String() string {
+	switch r.Status {
+	case StatusVerified:
+		return fmt.Sprintf("%s checksum verified (%s)", r.Algorithm, r.URL)
+	case StatusMismatch:
+		if r.Detail != "" {
+			return fmt.Sprintf("unusable %s checksum (%s): %s", r.Algorithm, r.URL, r.Detail)
+		}
+		return fmt.Sprintf("%s checksum mismatch (%s): expected %s, got %s", r.Algorithm, r.URL, r.Expected, r.Actual)
+	default:
+		if r.Detail != "" {
+			return "no checksum available: " + r.Detail
+		}
+		return "no checksum available"
+	}
+}
+
+// FetchFunc reads a sidecar file. It must report a missing file as an HTTP
+// 404 error (see httpclient.IsNotFound).
+type FetchFunc func(url string) ([]byte, error)
+
+// Verify checks the file at path against the strongest checksum published
+// next to sourceURL. Sidecars are tried from sha512 down to md5 and the
+// first one that exists decides the result. Only a 404 moves on to the next
+// algorithm: any other fetch error leaves the archive unverified, so a flaky
+// mirror can't downgrade the check to a weaker algorithm.
+func Verify(path, sourceURL string, fetch FetchFunc) Result {
+	var tried []string
+
+	for _, algo := range Algorithms {
+		url := sourceURL + algo.Suffix
+		data, err := fetch(url)
+		if httpclient.IsNotFound(err) {
+			tried = append(tried, algo.Suffix)
+			continue
+		}
+		if err != nil {
+			return Result{Status: StatusMissing, Algorithm: algo.Name, URL: url, Detail: fmt.Sprintf("fetch %s: %v", url, err)}
+		}
+
+		expected, err := parseChecksum(data, algo)
+		if err != nil {
+			return Result{Status: StatusMismatch, Algorithm: algo.Name, URL: url, Detail: err.Error()}
+		}
+
+		actual, err := FileChecksum(path, algo)
+		if err != nil {
+			return Result{Status: StatusMissing, Algorithm: algo.Name, URL: url, Detail: err.Error()}
+		}
+
+		result := Result{Status: StatusVerified, Algorithm: algo.Name, URL: url, Expected: expected, Actual: actual}
+		if actual != expected {
+			result.Status = StatusMismatch
+		}
+		return result
+	}
+
+	detail := fmt.Sprintf("tried %s next to %s", strings.Join(tried, ", "), sourceURL)
+	return Result{Status: StatusMissing, Detail: detail}
+}
+
+// Check applies a policy to a verification result. what names the archive
+// and its source. A mismatch is an error under every policy but off; a
+// missing checksum is an error under PolicyStrict and a warning otherwise.
+func Check(policy Policy, result Result, what string) error {
+	if policy == PolicyOff || result.Status == StatusVerified {
+		return nil
+	}
+
+	if result.Status == StatusMismatch {
+		return fmt.Errorf("%s: %s", what, result)
+	}
+
+	if policy == PolicyStrict {
+		return fmt.Errorf("%s: %s (integrity policy is 'strict')", what, result)
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", what, result)
+	return nil
+}
+
+// FileChecksum returns the hex checksum of a file
+func FileChecksum(path string, algo Algorithm) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := algo.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// parseChecksum reads the digest from a sidecar, which holds either the bare
+// hex digest or "digest  filename". The digest must have the length of the
+// algorithm's output.
+func parseChecksum(data []byte, algo Algorithm) (string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file")
+	}
+
+	digest := strings.ToLower(fields[0])
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", fmt.Errorf("malformed checksum file")
+	}
+	if want := algo.New().Size() * 2; len(digest) != want {
+		return "", fmt.Errorf("malformed checksum file: %d hex digits, %s has %d", len(digest), algo.Name, want)
+	}
+	return digest, nil
+}
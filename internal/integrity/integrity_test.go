@@ -0,0 +1,201 @@
+package integrity
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+)
+
+const sourceURL = "https://repo.example.org/apache-maven-3.9.6-bin.zip"
+
+var archiveContent = []byte("apache-maven-3.9.6-bin.zip")
+
+// writeArchive writes the test archive and returns its path
+func writeArchive(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	if err := os.WriteFile(path, archiveContent, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func sha1Hex(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// sidecars serves the given sidecar files by suffix, answers 404 for the
+// others and records the URLs fetched
+type sidecars struct {
+	files   map[string]string
+	errs    map[string]error
+	fetched []string
+}
+
+func (s *sidecars) fetch(url string) ([]byte, error) {
+	suffix := strings.TrimPrefix(url, sourceURL)
+	s.fetched = append(s.fetched, suffix)
+	if err, ok := s.errs[suffix]; ok {
+		return nil, err
+	}
+	if data, ok := s.files[suffix]; ok {
+		return []byte(data), nil
+	}
+	return nil, httpclient.NewHTTPError(&http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found"})
+}
+
+func TestVerifyFallsBackOnNotFound(t *testing.T) {
+	s := &sidecars{files: map[string]string{
+		".sha256": sha256Hex(archiveContent) + "  apache-maven-3.9.6-bin.zip\n",
+		".sha1":   sha1Hex(archiveContent),
+	}}
+
+	result := Verify(writeArchive(t), sourceURL, s.fetch)
+	if result.Status != StatusVerified || result.Algorithm != "sha256" {
+		t.Fatalf("Verify() = %v, want verified with sha256", result)
+	}
+	if want := []string{".sha512", ".sha256"}; !reflect.DeepEqual(s.fetched, want) {
+		t.Errorf("fetched %v, want %v", s.fetched, want)
+	}
+}
+
+func TestVerifyStopsOnFetchError(t *testing.T) {
+	s := &sidecars{
+		files: map[string]string{".sha1": sha1Hex(archiveContent)},
+		errs: map[string]error{
+			".sha256": httpclient.NewHTTPError(&http.Response{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}),
+		},
+	}
+
+	result := Verify(writeArchive(t), sourceURL, s.fetch)
+	if result.Status != StatusMissing || result.Algorithm != "sha256" {
+		t.Fatalf("Verify() = %v, want missing at sha256", result)
+	}
+	if want := []string{".sha512", ".sha256"}; !reflect.DeepEqual(s.fetched, want) {
+		t.Errorf("fetched %v, want %v", s.fetched, want)
+	}
+}
+
+func TestVerifyNoSidecars(t *testing.T) {
+	s := &sidecars{}
+
+	result := Verify(writeArchive(t), sourceURL, s.fetch)
+	if result.Status != StatusMissing {
+		t.Fatalf("Verify() = %v, want missing", result)
+	}
+	if want := []string{".sha512", ".sha256", ".sha1", ".md5"}; !reflect.DeepEqual(s.fetched, want) {
+		t.Errorf("fetched %v, want %v", s.fetched, want)
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	s := &sidecars{files: map[string]string{".sha256": sha256Hex([]byte("something else"))}}
+
+	result := Verify(writeArchive(t), sourceURL, s.fetch)
+	if result.Status != StatusMismatch || result.Detail != "" {
+		t.Fatalf("Verify() = %v, want mismatch", result)
+	}
+	if result.Actual != sha256Hex(archiveContent) {
+		t.Errorf("Actual = %s, want %s", result.Actual, sha256Hex(archiveContent))
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	digest := sha256Hex(archiveContent)
+	tests := map[string]string{
+		"empty":     "",
+		"not hex":   strings.Repeat("z", len(digest)),
+		"too short": digest[:40],
+		"too long":  digest + "00",
+		"odd":       digest[:len(digest)-1],
+	}
+
+	for name, sidecar := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := &sidecars{files: map[string]string{".sha256": sidecar}}
+
+			result := Verify(writeArchive(t), sourceURL, s.fetch)
+			if result.Status != StatusMismatch || result.Detail == "" {
+				t.Fatalf("Verify() = %v, want unusable checksum", result)
+			}
+			// A malformed sidecar must not fall back to a weaker one
+			if want := []string{".sha512", ".sha256"}; !reflect.DeepEqual(s.fetched, want) {
+				t.Errorf("fetched %v, want %v", s.fetched, want)
+			}
+		})
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sha256Algo := Algorithms[1]
+	digest := sha256Hex(archiveContent)
+
+	tests := []struct {
+		data string
+		want string
+	}{
+		{digest, digest},
+		{strings.ToUpper(digest) + "\n", digest},
+		{digest + "  apache-maven-3.9.6-bin.zip", digest},
+		{digest + " *apache-maven-3.9.6-bin.zip\r\n", digest},
+	}
+
+	for _, tt := range tests {
+		got, err := parseChecksum([]byte(tt.data), sha256Algo)
+		if err != nil || got != tt.want {
+			t.Errorf("parseChecksum(%q) = %q, %v, want %q", tt.data, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	verified := Result{Status: StatusVerified}
+	mismatch := Result{Status: StatusMismatch}
+	missing := Result{Status: StatusMissing}
+
+	tests := []struct {
+		policy  Policy
+		result  Result
+		wantErr bool
+	}{
+		{PolicyStrict, verified, false},
+		{PolicyStrict, mismatch, true},
+		{PolicyStrict, missing, true},
+		{PolicyWarn, mismatch, true},
+		{PolicyWarn, missing, false},
+		{PolicyOff, mismatch, false},
+		{PolicyOff, missing, false},
+	}
+
+	for _, tt := range tests {
+		err := Check(tt.policy, tt.result, "archive")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Check(%s, %s) = %v, want error %v", tt.policy, tt.result.Status, err, tt.wantErr)
+		}
+	}
+}
+
+// The fetch error is kept in the result
+func TestVerifyFetchErrorDetail(t *testing.T) {
+	s := &sidecars{errs: map[string]error{".sha512": errors.New("connection reset")}}
+
+	result := Verify(writeArchive(t), sourceURL, s.fetch)
+	if !strings.Contains(result.Detail, "connection reset") {
+		t.Errorf("Detail = %q, want the fetch error", result.Detail)
+	}
+}
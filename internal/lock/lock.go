@@ -0,0 +1,276 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is how long Acquire waits for another mvnenv process to
+// release a lock
+const DefaultTimeout = 10 * time.Minute
+
+// StaleAge is how old a lock held by another host must be before it is
+// considered abandoned. Locks held on this host are checked by process ID.
+const StaleAge = 2 * time.Hour
+
+// UnwrittenAge is how old a lock file without a readable owner must be before
+// it is considered abandoned. Owners are written right after the file is
+// created, so an empty file this old was left by a process that died in
+// between.
+const UnwrittenAge = 5 * time.Second
+
+// startSlack allows for Acquired being truncated to the second when
+// comparing it with a process's start time
+const startSlack = 2 * time.Second
+
+// pollInterval is how often a waiting process retries a held lock
+const pollInterval = 100 * time.Millisecond
+
+// ErrTimeout is returned when a lock could not be acquired in time
+var ErrTimeout = errors.New("timed out waiting for lock")
+
+// Owner describes the process holding a lock
+type Owner struct {
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	Acquired time.Time `json:"acquired"`
+}
+
+// same reports whether two owners describe the same lock acquisition
+func (o Owner) same(other Owner) bool {
+	return o.PID == other.PID && o.Host == other.Host && o.Acquired.Equal(other.Acquired)
+}
+
+// FileLock is a cross-process lock backed by an exclusively created file
+type FileLock struct {
+	path  string
+	owner Owner
+}
+
+// Acquire takes the named lock under <mvnenvRoot>/locks, waiting up to the
+// timeout from Timeout()
+func Acquire(mvnenvRoot, name string) (*FileLock, error) {
+	return AcquireFile(Path(mvnenvRoot, name), Timeout())
+}
+
+// Path returns the lock file for a lock name
+func Path(mvnenvRoot, name string) string {
+	return filepath.Join(mvnenvRoot, "locks", name+".lock")
+}
+
+// VersionLockName returns the lock name guarding one installed version of a
+// tool, so installs of different versions can run concurrently
+func VersionLockName(toolName, version string) string {
+	return "version-" + sanitize(toolName) + "-" + sanitize(version)
+}
+
+// Timeout returns the lock timeout, read from MVNENV_LOCK_TIMEOUT as a Go
+// duration ("30s") or a plain number of seconds
+func Timeout() time.Duration {
+	value := os.Getenv("MVNENV_LOCK_TIMEOUT")
+	if value == "" {
+		return DefaultTimeout
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d
+	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return DefaultTimeout
+}
+
+// AcquireFile takes a lock at an explicit path. Locks left behind by crashed
+// processes are detected and removed.
+func AcquireFile(path string, timeout time.Duration) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create lock directory: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	l := &FileLock{
+		path:  path,
+		owner: Owner{PID: os.Getpid(), Host: host},
+	}
+
+	deadline := time.Now().Add(timeout)
+	waiting := false
+
+	for {
+		err := l.tryCreate()
+		if err == nil {
+			return l, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("create lock file: %w", err)
+		}
+
+		holder, readErr := readOwner(path)
+		if isStale(holder, readErr, path) {
+			removeStale(path, holder)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w %s held by process %d on %s since %s",
+				ErrTimeout, filepath.Base(path), holder.PID, holder.Host, holder.Acquired.Format(time.RFC3339))
+		}
+
+		if !waiting {
+			fmt.Fprintf(os.Stderr, "Waiting for another mvnenv process (PID %d) to release %s...\n",
+				holder.PID, strings.TrimSuffix(filepath.Base(path), ".lock"))
+			waiting = true
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Held reports whether the lock file at path is held by a live owner
+func Held(path string) bool {
+	holder, err := readOwner(path)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return !isStale(holder, err, path)
+}
+
+// Release removes the lock file if it is still owned by this lock
+func (l *FileLock) Release() error {
+	holder, err := readOwner(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read lock file: %w", err)
+	}
+	if !holder.same(l.owner) {
+		// Our lock was broken as stale and taken by someone else
+		return nil
+	}
+
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove lock file: %w", err)
+	}
+	return nil
+}
+
+// tryCreate atomically creates the lock file and records this process as owner
+func (l *FileLock) tryCreate() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	l.owner.Acquired = time.Now().UTC().Truncate(time.Second)
+	data, _ := json.Marshal(l.owner)
+	_, writeErr := f.Write(data)
+	closeErr := f.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		os.Remove(l.path)
+		return fmt.Errorf("write lock file: %w", writeErr)
+	}
+
+	return nil
+}
+
+// readOwner reads the owner recorded in a lock file
+func readOwner(path string) (Owner, error) {
+	var owner Owner
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return owner, err
+	}
+	if err := json.Unmarshal(data, &owner); err != nil {
+		return owner, err
+	}
+
+	return owner, nil
+}
+
+// isStale reports whether a held lock was abandoned: its process is gone or
+// is a newer process that reused its ID, it is unreadable and older than
+// UnwrittenAge, or it is held by another host and older than StaleAge
+func isStale(holder Owner, readErr error, path string) bool {
+	if os.IsNotExist(readErr) {
+		// Released between our create attempt and the read; retry at once
+		return true
+	}
+
+	if readErr != nil {
+		// The owner may still be writing the file; only give up on it when old
+		info, err := os.Stat(path)
+		return err == nil && time.Since(info.ModTime()) > UnwrittenAge
+	}
+
+	host, _ := os.Hostname()
+	if holder.Host != host {
+		return time.Since(holder.Acquired) > StaleAge
+	}
+
+	if holder.PID == os.Getpid() {
+		return false
+	}
+	if !processAlive(holder.PID) {
+		return true
+	}
+
+	// A process started after the lock was taken can't be its owner
+	started, ok := processStarted(holder.PID)
+	return ok && started.After(holder.Acquired.Add(startSlack))
+}
+
+// removeStale deletes a stale lock. The file is first renamed out of the way,
+// so of several processes breaking the same lock only one succeeds, and then
+// inspected again: a lock taken since holder was read is put back instead.
+func removeStale(path string, holder Owner) {
+	claimed := fmt.Sprintf("%s.%d-%d.stale", path, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(path, claimed); err != nil {
+		// Released or broken by someone else; retry at once
+		return
+	}
+
+	current, err := readOwner(claimed)
+	if !isStale(current, err, claimed) {
+		restore(claimed, path)
+		return
+	}
+
+	if holder.PID != 0 {
+		fmt.Fprintf(os.Stderr, "Removing stale lock %s left by process %d\n", filepath.Base(path), holder.PID)
+	}
+	os.Remove(claimed)
+}
+
+// restore puts back a live lock moved aside by removeStale. A hard link
+// fails rather than replace a lock taken in the meantime.
+func restore(claimed, path string) {
+	if err := os.Link(claimed, path); err != nil && !os.IsExist(err) {
+		// Hard links are unsupported on this volume
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			os.Rename(claimed, path)
+			return
+		}
+	}
+	os.Remove(claimed)
+}
+
+// sanitize makes a value safe to use in a lock file name
+func sanitize(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,232 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess is run as a child process by the tests that need a live
+// or dead process other than the test itself
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("MVNENV_LOCK_HELPER") != "1" {
+		return
+	}
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+// helperProcess starts a process that sleeps until killed
+func helperProcess(t *testing.T) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "MVNENV_LOCK_HELPER=1")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	return cmd
+}
+
+// deadPID returns the ID of a process that has exited
+func deadPID(t *testing.T) int {
+	t.Helper()
+	cmd := helperProcess(t)
+	cmd.Process.Kill()
+	cmd.Wait()
+	return cmd.Process.Pid
+}
+
+// writeLock writes a lock file recording owner
+func writeLock(t *testing.T, path string, owner Owner) {
+	t.Helper()
+	data, err := json.Marshal(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hostname() string {
+	host, _ := os.Hostname()
+	return host
+}
+
+func TestAcquireContention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+
+	var mu sync.Mutex
+	holders, maxHolders := 0, 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := AcquireFile(path, 10*time.Second)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mu.Lock()
+			holders++
+			if holders > maxHolders {
+				maxHolders = holders
+			}
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			l.Release()
+		}()
+	}
+	wg.Wait()
+
+	if maxHolders != 1 {
+		t.Errorf("%d holders at once, want 1", maxHolders)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	held, err := AcquireFile(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Release()
+
+	start := time.Now()
+	_, err = AcquireFile(path, 300*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("AcquireFile() = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("gave up after %s, before the timeout", elapsed)
+	}
+}
+
+func TestAcquireStale(t *testing.T) {
+	live := helperProcess(t)
+	recent := time.Now().UTC().Truncate(time.Second)
+
+	tests := []struct {
+		name  string
+		owner *Owner
+		age   time.Duration
+		stale bool
+	}{
+		{"dead process", &Owner{PID: deadPID(t), Host: hostname(), Acquired: recent}, 0, true},
+		{"live process", &Owner{PID: live.Process.Pid, Host: hostname(), Acquired: recent}, 0, false},
+		{"other host", &Owner{PID: 1, Host: "elsewhere", Acquired: recent}, 0, false},
+		{"old lock of other host", &Owner{PID: 1, Host: "elsewhere", Acquired: recent.Add(-StaleAge - time.Minute)}, 0, true},
+		{"empty file being written", nil, 0, false},
+		{"empty file", nil, 2 * UnwrittenAge, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.lock")
+			if tt.owner != nil {
+				writeLock(t, path, *tt.owner)
+			} else if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			modified := time.Now().Add(-tt.age)
+			if err := os.Chtimes(path, modified, modified); err != nil {
+				t.Fatal(err)
+			}
+
+			l, err := AcquireFile(path, 200*time.Millisecond)
+			if tt.stale {
+				if err != nil {
+					t.Fatalf("AcquireFile() = %v, want the stale lock broken", err)
+				}
+				l.Release()
+			} else if !errors.Is(err, ErrTimeout) {
+				t.Fatalf("AcquireFile() = %v, want ErrTimeout", err)
+			}
+		})
+	}
+}
+
+// A live process whose ID was reused from the lock's dead owner started
+// after the lock was taken
+func TestAcquireReusedPID(t *testing.T) {
+	live := helperProcess(t)
+	if _, ok := processStarted(live.Process.Pid); !ok {
+		t.Skip("process start times are not available")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.lock")
+	writeLock(t, path, Owner{PID: live.Process.Pid, Host: hostname(), Acquired: time.Now().Add(-time.Hour).UTC()})
+
+	l, err := AcquireFile(path, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("AcquireFile() = %v, want the stale lock broken", err)
+	}
+	l.Release()
+}
+
+// removeStale must not delete a lock taken after the stale one was inspected
+func TestRemoveStaleKeepsNewLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	stale := Owner{PID: deadPID(t), Host: hostname(), Acquired: time.Now().UTC().Truncate(time.Second)}
+
+	// The stale lock was inspected, then broken and taken by someone else
+	taken, err := AcquireFile(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removeStale(path, stale)
+
+	holder, err := readOwner(path)
+	if err != nil || !holder.same(taken.owner) {
+		t.Fatalf("lock after removeStale = %+v, %v; want %+v", holder, err, taken.owner)
+	}
+	if err := taken.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 0 {
+		t.Errorf("files left behind: %v", entries)
+	}
+}
+
+func TestHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	if Held(path) {
+		t.Error("Held() = true without a lock file")
+	}
+
+	l, err := AcquireFile(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Held(path) {
+		t.Error("Held() = false while held")
+	}
+	l.Release()
+
+	writeLock(t, path, Owner{PID: deadPID(t), Host: hostname(), Acquired: time.Now().UTC()})
+	if Held(path) {
+		t.Error("Held() = true for a dead owner")
+	}
+}
@@ -0,0 +1,210 @@
+package matrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/shim"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+// Status is the outcome of a command run against one Maven version
+type Status string
+
+const (
+	StatusPass  Status = "pass"
+	StatusFail  Status = "fail"
+	StatusError Status = "error" // command could not be started
+)
+
+// Result holds the outcome of running the command under one Maven version
+type Result struct {
+	Version  string        `json:"version"`
+	Status   Status        `json:"status"`
+	ExitCode int           `json:"exit_code"`
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"duration_seconds"`
+	LogFile  string        `json:"log_file"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Summary is the machine-readable report of a matrix run
+type Summary struct {
+	Command   []string  `json:"command"`
+	StartedAt time.Time `json:"started_at"`
+	Seconds   float64   `json:"duration_seconds"`
+	Passed    int       `json:"passed"`
+	Failed    int       `json:"failed"`
+	Results   []Result  `json:"results"`
+}
+
+// Runner runs a command once per Maven version
+type Runner struct {
+	resolver   *versionpkg.VersionResolver
+	reportsDir string
+	parallel   int
+	onStart    func(version string)
+	onFinish   func(result Result)
+}
+
+// NewRunner creates a matrix runner writing logs to reportsDir
+func NewRunner(mvnenvRoot, reportsDir string) *Runner {
+	return &Runner{
+		resolver:   versionpkg.NewVersionResolver(mvnenvRoot),
+		reportsDir: reportsDir,
+		parallel:   1,
+	}
+}
+
+// SetParallel sets how many versions may run at the same time
+func (r *Runner) SetParallel(n int) {
+	if n < 1 {
+		n = 1
+	}
+	r.parallel = n
+}
+
+// OnStart registers a callback invoked when a version starts running
+func (r *Runner) OnStart(fn func(version string)) {
+	r.onStart = fn
+}
+
+// OnFinish registers a callback invoked when a version finishes
+func (r *Runner) OnFinish(fn func(result Result)) {
+	r.onFinish = fn
+}
+
+// Run executes command under each version and returns the summary. Results
+// are ordered like versions regardless of completion order.
+func (r *Runner) Run(versions []string, command []string) (*Summary, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no command given")
+	}
+
+	if err := os.MkdirAll(r.reportsDir, 0755); err != nil {
+		return nil, fmt.Errorf("create reports directory: %w", err)
+	}
+
+	summary := &Summary{
+		Command:   command,
+		StartedAt: time.Now(),
+		Results:   make([]Result, len(versions)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, r.parallel)
+
+	for idx, version := range versions {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(idx int, version string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			mu.Lock()
+			if r.onStart != nil {
+				r.onStart(version)
+			}
+			mu.Unlock()
+
+			result := r.runVersion(version, command)
+
+			mu.Lock()
+			summary.Results[idx] = result
+			if r.onFinish != nil {
+				r.onFinish(result)
+			}
+			mu.Unlock()
+		}(idx, version)
+	}
+
+	wg.Wait()
+
+	for _, result := range summary.Results {
+		if result.Status == StatusPass {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+	}
+	summary.Seconds = time.Since(summary.StartedAt).Seconds()
+
+	return summary, nil
+}
+
+// runVersion runs the command for a single version, logging to its own file
+func (r *Runner) runVersion(version string, command []string) Result {
+	result := Result{
+		Version: version,
+		LogFile: filepath.Join(r.reportsDir, fmt.Sprintf("maven-%s.log", version)),
+	}
+	start := time.Now()
+
+	fail := func(err error) Result {
+		result.Status = StatusError
+		result.ExitCode = -1
+		result.Error = err.Error()
+		result.Duration = time.Since(start)
+		result.Seconds = result.Duration.Seconds()
+		return result
+	}
+
+	resolved, err := r.resolver.ResolveExplicitVersion(version)
+	if err != nil {
+		return fail(err)
+	}
+
+	commandPath, err := shim.LookCommand(resolved, command[0])
+	if err != nil {
+		return fail(err)
+	}
+
+	logFile, err := os.Create(result.LogFile)
+	if err != nil {
+		return fail(fmt.Errorf("create log file: %w", err))
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(commandPath, command[1:]...)
+	cmd.Env = shim.BuildEnvironment(resolved)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Dir, _ = os.Getwd()
+
+	err = cmd.Run()
+	result.Duration = time.Since(start)
+	result.Seconds = result.Duration.Seconds()
+
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.Status = StatusFail
+			result.ExitCode = exitErr.ExitCode()
+			return result
+		}
+		return fail(err)
+	}
+
+	result.Status = StatusPass
+	return result
+}
+
+// WriteSummary writes the summary as JSON to path
+func WriteSummary(summary *Summary, path string) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal summary: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package matrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess stands in for Maven: it prints its MAVEN_HOME and fails
+// with exit code 3 under version 3.8.8
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("MVNENV_MATRIX_HELPER") != "1" {
+		return
+	}
+	home := os.Getenv("MAVEN_HOME")
+	fmt.Println("MAVEN_HOME=" + home)
+	time.Sleep(100 * time.Millisecond)
+	if filepath.Base(home) == "3.8.8" {
+		os.Exit(3)
+	}
+	os.Exit(0)
+}
+
+// helperCommand runs TestHelperProcess
+func helperCommand(t *testing.T) []string {
+	t.Setenv("MVNENV_MATRIX_HELPER", "1")
+	return []string{os.Args[0], "-test.run=^TestHelperProcess$"}
+}
+
+// installVersions creates an mvnenv root with the given Maven versions
+// installed
+func installVersions(t *testing.T, versions ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, v := range versions {
+		launcher := filepath.Join(root, "versions", v, "bin", "mvn.cmd")
+		if err := os.MkdirAll(filepath.Dir(launcher), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(launcher, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestRunResults(t *testing.T) {
+	root := installVersions(t, "3.9.6", "3.8.8")
+	reportsDir := filepath.Join(t.TempDir(), "reports")
+	runner := NewRunner(root, reportsDir)
+
+	summary, err := runner.Run([]string{"3.9.6", "3.8.8", "3.6.3"}, helperCommand(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		version  string
+		status   Status
+		exitCode int
+	}{
+		{"3.9.6", StatusPass, 0},
+		{"3.8.8", StatusFail, 3},
+		{"3.6.3", StatusError, -1}, // not installed
+	}
+	if len(summary.Results) != len(want) {
+		t.Fatalf("%d results, want %d", len(summary.Results), len(want))
+	}
+	for i, w := range want {
+		r := summary.Results[i]
+		if r.Version != w.version || r.Status != w.status || r.ExitCode != w.exitCode {
+			t.Errorf("result %d = %s %s (exit %d), want %s %s (exit %d)",
+				i, r.Version, r.Status, r.ExitCode, w.version, w.status, w.exitCode)
+		}
+	}
+	if summary.Passed != 1 || summary.Failed != 2 {
+		t.Errorf("passed %d, failed %d; want 1 and 2", summary.Passed, summary.Failed)
+	}
+
+	// Each version logs to its own file, under its own MAVEN_HOME
+	for _, v := range []string{"3.9.6", "3.8.8"} {
+		data, err := os.ReadFile(filepath.Join(reportsDir, "maven-"+v+".log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		home := filepath.Join(root, "versions", v)
+		if !strings.Contains(string(data), "MAVEN_HOME="+home+"\n") {
+			t.Errorf("log of %s = %q, want MAVEN_HOME=%s", v, data, home)
+		}
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	versions := []string{"3.9.6", "3.9.5", "3.9.4", "3.9.3", "3.9.2"}
+	root := installVersions(t, versions...)
+
+	for _, parallel := range []int{1, 2} {
+		t.Run(fmt.Sprint(parallel), func(t *testing.T) {
+			runner := NewRunner(root, t.TempDir())
+			runner.SetParallel(parallel)
+
+			var mu sync.Mutex
+			running, maxRunning := 0, 0
+			runner.OnStart(func(string) {
+				mu.Lock()
+				defer mu.Unlock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+			})
+			runner.OnFinish(func(Result) {
+				mu.Lock()
+				defer mu.Unlock()
+				running--
+			})
+
+			summary, err := runner.Run(versions, helperCommand(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if maxRunning != parallel {
+				t.Errorf("%d versions ran at once, want %d", maxRunning, parallel)
+			}
+			// Results keep the order of the versions
+			for i, r := range summary.Results {
+				if r.Version != versions[i] || r.Status != StatusPass {
+					t.Errorf("result %d = %s %s, want %s pass", i, r.Version, r.Status, versions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	root := installVersions(t, "3.9.6", "3.8.8")
+	reportsDir := t.TempDir()
+
+	summary, err := NewRunner(root, reportsDir).Run([]string{"3.9.6", "3.8.8"}, helperCommand(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(reportsDir, "summary.json")
+	if err := WriteSummary(summary, path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Command []string `json:"command"`
+		Passed  int      `json:"passed"`
+		Failed  int      `json:"failed"`
+		Results []struct {
+			Version  string  `json:"version"`
+			Status   string  `json:"status"`
+			ExitCode int     `json:"exit_code"`
+			Seconds  float64 `json:"duration_seconds"`
+			LogFile  string  `json:"log_file"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Passed != 1 || got.Failed != 1 || len(got.Results) != 2 || len(got.Command) != 2 {
+		t.Fatalf("summary.json = %s", data)
+	}
+	failed := got.Results[1]
+	if failed.Version != "3.8.8" || failed.Status != "fail" || failed.ExitCode != 3 || failed.Seconds <= 0 {
+		t.Errorf("failed result = %+v", failed)
+	}
+	if failed.LogFile != filepath.Join(reportsDir, "maven-3.8.8.log") {
+		t.Errorf("log_file = %s", failed.LogFile)
+	}
+}
+
+func TestRunWithoutCommand(t *testing.T) {
+	if _, err := NewRunner(t.TempDir(), t.TempDir()).Run([]string{"3.9.6"}, nil); err == nil {
+		t.Error("Run() without a command succeeded")
+	}
+}
@@ -9,7 +9,11 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"time"
+	"strings"
+
+	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/download"
+	"github.com/veenone/mvnenv-win/internal/httpclient"
 )
 
 // Client represents a Nexus repository client
@@ -18,6 +22,12 @@ type Client struct {
 	username   string
 	password   string
 	httpClient *http.Client
+	preflight  download.PreflightFunc
+}
+
+// SetPreflight sets a check run before artifact data is written
+func (c *Client) SetPreflight(preflight download.PreflightFunc) {
+	c.preflight = preflight
 }
 
 // TLSConfig holds TLS configuration options
@@ -37,16 +47,15 @@ type MavenMetadata struct {
 	} `xml:"versioning"`
 }
 
-// NewClient creates a new Nexus client
-func NewClient(baseURL, username, password string, tlsConfig *TLSConfig) (*Client, error) {
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{},
-	}
+// NewClient creates a new Nexus client. proxy overrides the configured
+// proxy for this repository and may be nil.
+func NewClient(baseURL, username, password string, tlsConfig *TLSConfig, proxy *config.ProxyConfig) (*Client, error) {
+	tlsClientConfig := &tls.Config{}
 
 	// Configure TLS
 	if tlsConfig != nil {
 		if tlsConfig.InsecureSkipVerify {
-			transport.TLSClientConfig.InsecureSkipVerify = true
+			tlsClientConfig.InsecureSkipVerify = true
 		}
 
 		if tlsConfig.CAFile != "" {
@@ -60,14 +69,16 @@ func NewClient(baseURL, username, password string, tlsConfig *TLSConfig) (*Clien
 				return nil, fmt.Errorf("failed to parse CA certificate")
 			}
 
-			transport.TLSClientConfig.RootCAs = caCertPool
+			tlsClientConfig.RootCAs = caCertPool
 		}
 	}
 
-	client := &http.Client{
-		Timeout:   30 * time.Second,
-		Transport: transport,
-	}
+	// No overall timeout: distributions take longer than any fixed limit on
+	// slow links, so only connecting and stalled transfers time out
+	client := httpclient.New(httpclient.Options{
+		TLS:   tlsClientConfig,
+		Proxy: proxy,
+	})
 
 	return &Client{
 		baseURL:    baseURL,
@@ -79,40 +90,24 @@ func NewClient(baseURL, username, password string, tlsConfig *TLSConfig) (*Clien
 
 // ListVersions retrieves available Maven versions from Nexus metadata
 func (c *Client) ListVersions(ctx context.Context) ([]string, error) {
-	// Construct maven-metadata.xml URL
-	// Format: {baseURL}/org/apache/maven/apache-maven/maven-metadata.xml
-	metadataURL := fmt.Sprintf("%s/org/apache/maven/apache-maven/maven-metadata.xml", c.baseURL)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", metadataURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+	return c.ListArtifactVersions(ctx, "org/apache/maven", "apache-maven")
+}
 
-	// Add authentication if configured
-	if c.username != "" && c.password != "" {
-		req.SetBasicAuth(c.username, c.password)
-	}
+// ListArtifactVersions retrieves available versions of any artifact from its
+// maven-metadata.xml. groupPath uses slashes (e.g. "org/apache/maven").
+func (c *Client) ListArtifactVersions(ctx context.Context, groupPath, artifactID string) ([]string, error) {
+	// Construct maven-metadata.xml URL
+	// Format: {baseURL}/{groupPath}/{artifactID}/maven-metadata.xml
+	metadataURL := fmt.Sprintf("%s/%s/%s/maven-metadata.xml", c.baseURL, groupPath, artifactID)
 
-	resp, err := c.httpClient.Do(req)
+	body, err := c.Fetch(ctx, metadataURL)
 	if err != nil {
+		// Metadata doesn't exist yet in an empty repository
+		if download.IsNotFound(err) {
+			return []string{}, nil
+		}
 		return nil, fmt.Errorf("failed to fetch metadata: %w", err)
 	}
-	defer resp.Body.Close()
-
-	// Handle 404 - metadata doesn't exist yet (empty repository)
-	if resp.StatusCode == http.StatusNotFound {
-		return []string{}, nil
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, string(body))
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
 
 	var metadata MavenMetadata
 	if err := xml.Unmarshal(body, &metadata); err != nil {
@@ -124,73 +119,45 @@ func (c *Client) ListVersions(ctx context.Context) ([]string, error) {
 
 // DownloadVersion downloads a Maven distribution from Nexus
 func (c *Client) DownloadVersion(ctx context.Context, version, destPath string, progress func(downloaded, total int64)) error {
-	// Construct artifact URL
-	// Format: {baseURL}/org/apache/maven/apache-maven/{version}/apache-maven-{version}-bin.zip
-	artifactURL := fmt.Sprintf("%s/org/apache/maven/apache-maven/%s/apache-maven-%s-bin.zip",
-		c.baseURL, version, version)
+	fileName := fmt.Sprintf("apache-maven-%s-bin.zip", version)
+	return c.DownloadArtifact(ctx, "org/apache/maven", "apache-maven", version, fileName, destPath, progress)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", artifactURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
+// ArtifactURL returns the URL of a file of an artifact version
+// Format: {baseURL}/{groupPath}/{artifactID}/{version}/{fileName}
+func (c *Client) ArtifactURL(groupPath, artifactID, version, fileName string) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", c.baseURL, groupPath, artifactID, version, fileName)
+}
 
-	// Add authentication if configured
-	if c.username != "" && c.password != "" {
-		req.SetBasicAuth(c.username, c.password)
-	}
+// DownloadArtifact downloads a file of any artifact version from Nexus.
+// Interrupted transfers are retried and resumed where the server allows it.
+func (c *Client) DownloadArtifact(ctx context.Context, groupPath, artifactID, version, fileName, destPath string, progress func(downloaded, total int64)) error {
+	artifactURL := c.ArtifactURL(groupPath, artifactID, version, fileName)
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
+	downloader := download.NewDownloaderWithClient(c.httpClient, c.authenticate)
+	downloader.SetPreflight(c.preflight)
+	if err := downloader.Download(ctx, artifactURL, destPath, progress); err != nil {
 		return fmt.Errorf("failed to download: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, string(body))
-	}
 
-	// Create destination file
-	out, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer out.Close()
-
-	// Download with progress tracking
-	totalSize := resp.ContentLength
-	downloaded := int64(0)
-
-	buf := make([]byte, 32*1024)
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	return nil
+}
 
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			_, writeErr := out.Write(buf[:n])
-			if writeErr != nil {
-				return fmt.Errorf("failed to write file: %w", writeErr)
-			}
+// Owns reports whether a URL points into this Nexus repository
+func (c *Client) Owns(url string) bool {
+	return strings.HasPrefix(url, strings.TrimSuffix(c.baseURL, "/")+"/")
+}
 
-			downloaded += int64(n)
-			if progress != nil {
-				progress(downloaded, totalSize)
-			}
-		}
+// Fetch reads a small file from Nexus, such as a checksum or signature
+func (c *Client) Fetch(ctx context.Context, url string) ([]byte, error) {
+	return download.NewDownloaderWithClient(c.httpClient, c.authenticate).Fetch(ctx, url)
+}
 
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("failed to read response: %w", err)
-		}
+// authenticate adds credentials to a request if configured
+func (c *Client) authenticate(req *http.Request) {
+	if c.username != "" && c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
 	}
-
-	return nil
 }
 
 // UploadVersion uploads a Maven distribution to Nexus repository
@@ -214,19 +181,27 @@ func (c *Client) UploadVersion(ctx context.Context, version, archivePath string,
 	uploadURL := fmt.Sprintf("%s/org/apache/maven/apache-maven/%s/apache-maven-%s-bin.zip",
 		c.baseURL, version, version)
 
-	// Create progress reader wrapper
-	var reader io.Reader = file
-	if progress != nil {
-		reader = &progressReader{
-			reader:   file,
-			total:    totalSize,
-			callback: progress,
+	// Retried uploads start over from the beginning of the file
+	return httpclient.Retry().Do(ctx, func() error {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return httpclient.Permanent(fmt.Errorf("failed to read archive: %w", err))
 		}
+		return c.upload(ctx, uploadURL, file, totalSize, progress)
+	})
+}
+
+// upload makes a single attempt to PUT a file
+func (c *Client) upload(ctx context.Context, uploadURL string, file io.Reader, totalSize int64, progress func(uploaded, total int64)) error {
+	// Track progress and stay within the shared rate limit
+	reader := &progressReader{
+		reader:   httpclient.LimitReader(ctx, file),
+		total:    totalSize,
+		callback: progress,
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "PUT", uploadURL, reader)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return httpclient.Permanent(fmt.Errorf("failed to create request: %w", err))
 	}
 
 	// Set headers
@@ -234,9 +209,7 @@ func (c *Client) UploadVersion(ctx context.Context, version, archivePath string,
 	req.ContentLength = totalSize
 
 	// Add authentication if configured
-	if c.username != "" && c.password != "" {
-		req.SetBasicAuth(c.username, c.password)
-	}
+	c.authenticate(req)
 
 	// Perform upload
 	resp, err := c.httpClient.Do(req)
@@ -248,13 +221,14 @@ func (c *Client) UploadVersion(ctx context.Context, version, archivePath string,
 	// Check response status
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("upload failed: %w: %s", httpclient.NewHTTPError(resp), string(body))
 	}
 
 	return nil
 }
 
-// progressReader wraps an io.Reader to track upload progress
+// progressReader wraps an io.Reader to track upload progress; the reader
+// it wraps applies the rate limit
 type progressReader struct {
 	reader   io.Reader
 	total    int64
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package progress
+
+import "os"
+
+// enableColor reports whether a terminal takes ANSI colors, which all
+// terminals outside Windows do
+func enableColor(f *os.File) bool {
+	return true
+}
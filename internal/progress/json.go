@@ -0,0 +1,148 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// jsonInterval is the shortest time between two progress events of a task
+const jsonInterval = 250 * time.Millisecond
+
+// Event types
+const (
+	EventStart    = "start"
+	EventProgress = "progress"
+	EventDone     = "done"
+	EventFail     = "fail"
+	EventRetry    = "retry"
+	EventNote     = "note"
+)
+
+// Event is one line of the NDJSON stream. Task numbers the tasks of a run
+// so that events of concurrent tasks can be told apart.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Task    int64     `json:"task"`
+	Kind    string    `json:"kind"`
+	Label   string    `json:"label"`
+	Current int64     `json:"current"`
+	// Total is omitted while unknown
+	Total int64 `json:"total,omitempty"`
+	// Rate is in bytes per second
+	Rate       int64   `json:"rate,omitempty"`
+	ETASeconds float64 `json:"eta_seconds,omitempty"`
+	Elapsed    float64 `json:"elapsed_seconds"`
+	Summary    string  `json:"summary,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	// Message is the text of a note
+	Message string `json:"message,omitempty"`
+	// Attempt, Attempts and DelaySeconds describe a retry
+	Attempt      int     `json:"attempt,omitempty"`
+	Attempts     int     `json:"attempts,omitempty"`
+	DelaySeconds float64 `json:"delay_seconds,omitempty"`
+}
+
+var taskIDs atomic.Int64
+
+// jsonReporter writes events as NDJSON
+type jsonReporter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (r *jsonReporter) Start(kind, label string) Task {
+	t := &jsonTask{reporter: r, id: taskIDs.Add(1), kind: kind, label: label, meter: newMeter()}
+	t.emit(Event{Event: EventStart})
+	return t
+}
+
+func (r *jsonReporter) write(e Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.out.Write(append(data, '\n'))
+}
+
+// jsonTask is a task reported by jsonReporter
+type jsonTask struct {
+	reporter *jsonReporter
+	id       int64
+	kind     string
+	label    string
+	meter    meter
+	last     time.Time
+}
+
+func (t *jsonTask) Update(current, total int64) {
+	t.meter.update(current, total)
+	if time.Since(t.last) < jsonInterval && current != total {
+		return
+	}
+	t.last = time.Now()
+
+	e := Event{Event: EventProgress, Current: current, Rate: int64(t.meter.rate())}
+	if total > 0 {
+		e.Total = total
+	}
+	if eta, ok := t.meter.eta(); ok {
+		e.ETASeconds = seconds(eta)
+	}
+	t.emit(e)
+}
+
+func (t *jsonTask) Done(summary string) {
+	t.emit(Event{
+		Event:   EventDone,
+		Current: t.meter.current,
+		Summary: summary,
+	})
+}
+
+func (t *jsonTask) Fail(err error) {
+	t.emit(Event{
+		Event:   EventFail,
+		Current: t.meter.current,
+		Error:   err.Error(),
+	})
+}
+
+func (t *jsonTask) Retry(err error, delay time.Duration, attempt, attempts int) {
+	t.emit(Event{
+		Event:        EventRetry,
+		Current:      t.meter.current,
+		Error:        err.Error(),
+		Attempt:      attempt,
+		Attempts:     attempts,
+		DelaySeconds: seconds(delay),
+	})
+}
+
+func (t *jsonTask) Note(message string) {
+	t.emit(Event{
+		Event:   EventNote,
+		Current: t.meter.current,
+		Message: message,
+	})
+}
+
+// seconds returns a duration in seconds to a tenth
+func seconds(d time.Duration) float64 {
+	return math.Round(d.Seconds()*10) / 10
+}
+
+func (t *jsonTask) emit(e Event) {
+	e.Time = time.Now().UTC()
+	e.Task = t.id
+	e.Kind = t.kind
+	e.Label = t.label
+	e.Elapsed = seconds(time.Since(t.meter.started))
+	t.reporter.write(e)
+}
@@ -0,0 +1,88 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// plainStep is the share of a known total between two lines
+	plainStep = 25
+	// plainInterval is the longest time between two lines
+	plainInterval = 30 * time.Second
+)
+
+// plainReporter prints a line when a task starts, at every plainStep
+// percent or plainInterval, and when it ends, for logs without a terminal
+type plainReporter struct {
+	out io.Writer
+}
+
+func (r *plainReporter) Start(kind, label string) Task {
+	present, _ := verbs(kind)
+	fmt.Fprintf(r.out, "%s %s...\n", present, label)
+	now := time.Now()
+	return &plainTask{reporter: r, kind: kind, label: label, meter: newMeter(), lastLine: now}
+}
+
+// plainTask is a task printed by plainReporter
+type plainTask struct {
+	reporter *plainReporter
+	kind     string
+	label    string
+	meter    meter
+	step     int64
+	lastLine time.Time
+}
+
+func (t *plainTask) Update(current, total int64) {
+	t.meter.update(current, total)
+
+	due := time.Since(t.lastLine) >= plainInterval
+	if total > 0 {
+		step := current * 100 / total / plainStep
+		if step > t.step && current < total {
+			t.step = step
+			due = true
+		}
+	}
+	if !due {
+		return
+	}
+	t.lastLine = time.Now()
+
+	present, _ := verbs(t.kind)
+	line := fmt.Sprintf("%s %s: %s", present, t.label, formatSize(current))
+	if total > 0 {
+		line = fmt.Sprintf("%s %s: %d%% (%s of %s", present, t.label, current*100/total, formatSize(current), formatSize(total))
+		if rate := t.meter.rate(); rate > 0 {
+			line += fmt.Sprintf(", %s/s", formatSize(int64(rate)))
+		}
+		line += ")"
+	} else if rate := t.meter.rate(); rate > 0 {
+		line += fmt.Sprintf(" (%s/s)", formatSize(int64(rate)))
+	}
+	fmt.Fprintln(t.reporter.out, line)
+}
+
+func (t *plainTask) Done(summary string) {
+	if summary == "" {
+		summary = t.meter.summary()
+	}
+	_, past := verbs(t.kind)
+	fmt.Fprintf(t.reporter.out, "%s %s (%s)\n", past, t.label, summary)
+}
+
+func (t *plainTask) Fail(err error) {
+	present, _ := verbs(t.kind)
+	fmt.Fprintf(t.reporter.out, "%s %s failed: %v\n", present, t.label, err)
+}
+
+func (t *plainTask) Retry(err error, delay time.Duration, attempt, attempts int) {
+	fmt.Fprintln(t.reporter.out, retryLine(t.kind, t.label, err, delay, attempt, attempts))
+}
+
+func (t *plainTask) Note(message string) {
+	fmt.Fprintf(t.reporter.out, "%s: %s\n", t.label, message)
+}
@@ -0,0 +1,304 @@
+// Package progress reports the progress of long-running tasks such as
+// downloads, either for people (a redrawn bar on terminals, plain lines
+// otherwise) or as NDJSON events for wrappers and IDE plugins
+package progress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode selects how progress is shown
+type Mode string
+
+const (
+	// ModeAuto uses ModeTTY on a terminal and ModePlain otherwise
+	ModeAuto Mode = "auto"
+	// ModeTTY redraws a progress bar with rate and ETA on one line
+	ModeTTY Mode = "tty"
+	// ModePlain prints occasional lines, suitable for CI logs
+	ModePlain Mode = "plain"
+	// ModeJSON writes one JSON event per line to stderr
+	ModeJSON Mode = "json"
+	// ModeNone shows no progress
+	ModeNone Mode = "none"
+)
+
+// ModeEnv selects the mode when --progress isn't given
+const ModeEnv = "MVNENV_PROGRESS"
+
+// Task kinds
+const (
+	KindDownload = "download"
+	KindUpload   = "upload"
+	KindExtract  = "extract"
+	KindList     = "list"
+)
+
+// ParseMode parses a mode name; "" means ModeAuto
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", ModeAuto:
+		return ModeAuto, nil
+	case ModeTTY:
+		return ModeTTY, nil
+	case ModePlain:
+		return ModePlain, nil
+	case ModeJSON:
+		return ModeJSON, nil
+	case ModeNone:
+		return ModeNone, nil
+	default:
+		return "", fmt.Errorf("invalid progress mode '%s' (use auto, tty, plain, json or none)", s)
+	}
+}
+
+// Reporter shows the progress of tasks
+type Reporter interface {
+	// Start begins a task of a kind (e.g. KindDownload); label names what
+	// it works on, such as an archive
+	Start(kind, label string) Task
+}
+
+// Task is a task in progress. Update matches download.ProgressCallback.
+type Task interface {
+	// Update reports current of total bytes; total <= 0 when unknown
+	Update(current, total int64)
+
+	// Done completes the task; summary replaces the byte count and rate
+	// in the final report when not empty
+	Done(summary string)
+
+	// Fail ends the task with an error
+	Fail(err error)
+
+	// Retry reports that the task failed and is tried again after delay,
+	// as attempt of attempts
+	Retry(err error, delay time.Duration, attempt, attempts int)
+
+	// Note reports something the user should know about the task, such as
+	// a fallback to another source
+	Note(message string)
+}
+
+type taskKey struct{}
+
+// WithTask returns a context carrying a task, so code deeper in the call,
+// such as a download's retries, can report on it
+func WithTask(ctx context.Context, task Task) context.Context {
+	return context.WithValue(ctx, taskKey{}, task)
+}
+
+// TaskFrom returns the task carried by ctx, or nil
+func TaskFrom(ctx context.Context) Task {
+	task, _ := ctx.Value(taskKey{}).(Task)
+	return task
+}
+
+var (
+	mu   sync.Mutex
+	mode = modeFromEnv()
+)
+
+// modeFromEnv returns the mode set in ModeEnv, or ModeAuto
+func modeFromEnv() Mode {
+	m, err := ParseMode(os.Getenv(ModeEnv))
+	if err != nil {
+		return ModeAuto
+	}
+	return m
+}
+
+// SetMode sets the mode used by Default and Quiet, overriding ModeEnv
+func SetMode(m Mode) {
+	mu.Lock()
+	defer mu.Unlock()
+	mode = m
+}
+
+// CurrentMode returns the mode in effect
+func CurrentMode() Mode {
+	mu.Lock()
+	defer mu.Unlock()
+	return mode
+}
+
+// Default returns a reporter for the current mode: people's output goes to
+// stdout next to the command's messages, JSON events to stderr
+func Default() Reporter {
+	m := CurrentMode()
+	if m == ModeJSON {
+		return New(m, os.Stderr)
+	}
+	return New(m, os.Stdout)
+}
+
+// Quiet returns the reporter for commands run with --quiet: JSON events are
+// still written, since a program asked for them, but nothing else is shown
+func Quiet() Reporter {
+	if CurrentMode() == ModeJSON {
+		return Default()
+	}
+	return Discard
+}
+
+// New returns a reporter writing to out in a mode
+func New(m Mode, out io.Writer) Reporter {
+	if m == ModeAuto {
+		m = ModePlain
+		if isTerminal(out) && os.Getenv("TERM") != "dumb" {
+			m = ModeTTY
+		}
+	}
+
+	switch m {
+	case ModeTTY:
+		return &ttyReporter{out: out, color: colorEnabled(out)}
+	case ModePlain:
+		return &plainReporter{out: out}
+	case ModeJSON:
+		return &jsonReporter{out: out}
+	default:
+		return Discard
+	}
+}
+
+// Discard is a reporter that shows nothing
+var Discard Reporter = discard{}
+
+type discard struct{}
+
+func (discard) Start(kind, label string) Task                               { return discard{} }
+func (discard) Update(current, total int64)                                 {}
+func (discard) Done(summary string)                                         {}
+func (discard) Fail(err error)                                              {}
+func (discard) Retry(err error, delay time.Duration, attempt, attempts int) {}
+func (discard) Note(message string)                                         {}
+
+// isTerminal reports whether w is a console rather than a file or pipe
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// colorEnabled reports whether colors may be written to a terminal
+func colorEnabled(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	return ok && enableColor(f)
+}
+
+// verbs returns the present and past verb for a task kind
+func verbs(kind string) (string, string) {
+	switch kind {
+	case KindDownload:
+		return "Downloading", "Downloaded"
+	case KindUpload:
+		return "Uploading", "Uploaded"
+	case KindExtract:
+		return "Extracting", "Extracted"
+	case KindList:
+		return "Fetching", "Fetched"
+	default:
+		return "Processing", "Processed"
+	}
+}
+
+// retryLine describes a retry, e.g. "Downloading x.zip: retrying in 2s
+// (attempt 2/5): connection reset"
+func retryLine(kind, label string, err error, delay time.Duration, attempt, attempts int) string {
+	present, _ := verbs(kind)
+	return fmt.Sprintf("%s %s: retrying in %s (attempt %d/%d): %v", present, label, formatDuration(delay), attempt, attempts, err)
+}
+
+// meter tracks the amount done of a task and its rate
+type meter struct {
+	started time.Time
+	// since and base are when and at which amount the rate is measured
+	// from; they restart when the amount goes back, e.g. when a download
+	// falls back to another source
+	since   time.Time
+	base    int64
+	current int64
+	total   int64
+}
+
+func newMeter() meter {
+	now := time.Now()
+	return meter{started: now, since: now}
+}
+
+func (m *meter) update(current, total int64) {
+	if current < m.current {
+		m.since, m.base = time.Now(), current
+	}
+	m.current, m.total = current, total
+}
+
+// rate returns bytes per second, or 0 until it can be estimated
+func (m *meter) rate() float64 {
+	elapsed := time.Since(m.since).Seconds()
+	if elapsed < 0.5 || m.current <= m.base {
+		return 0
+	}
+	return float64(m.current-m.base) / elapsed
+}
+
+// eta returns the estimated time left, if known
+func (m *meter) eta() (time.Duration, bool) {
+	rate := m.rate()
+	if m.total <= 0 || rate == 0 {
+		return 0, false
+	}
+	return time.Duration(float64(m.total-m.current) / rate * float64(time.Second)), true
+}
+
+// summary describes a finished task, e.g. "9.0 MB in 3s, 2.9 MB/s"
+func (m *meter) summary() string {
+	elapsed := time.Since(m.started)
+	if m.current <= 0 {
+		return "in " + formatDuration(elapsed)
+	}
+	s := formatSize(m.current) + " in " + formatDuration(elapsed)
+	if secs := elapsed.Seconds(); secs >= 0.5 {
+		s += ", " + formatSize(int64(float64(m.current)/secs)) + "/s"
+	}
+	return s
+}
+
+// formatSize formats a byte count for display (e.g. "9.2 MB")
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration formats a duration in whole seconds, e.g. "3s" or "1m05s"
+func formatDuration(d time.Duration) string {
+	secs := int(d.Round(time.Second).Seconds())
+	if secs < 60 {
+		return fmt.Sprintf("%ds", secs)
+	}
+	if secs < 3600 {
+		return fmt.Sprintf("%dm%02ds", secs/60, secs%60)
+	}
+	return fmt.Sprintf("%dh%02dm", secs/3600, secs%3600/60)
+}
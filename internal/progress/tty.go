@@ -0,0 +1,204 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// redrawInterval is how often the line is redrawn, which also keeps the
+	// spinner moving while no data arrives
+	redrawInterval = 120 * time.Millisecond
+	barWidth       = 16
+	// maxLabel keeps the line within 80 columns
+	maxLabel = 30
+)
+
+var spinner = []string{"|", "/", "-", "\\"}
+
+const (
+	ansiGreen = "\x1b[32m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// ttyReporter redraws one line per task on a terminal
+type ttyReporter struct {
+	out   io.Writer
+	color bool
+}
+
+func (r *ttyReporter) Start(kind, label string) Task {
+	t := &ttyTask{
+		reporter: r,
+		kind:     kind,
+		label:    label,
+		meter:    newMeter(),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	t.draw()
+	go t.run()
+	return t
+}
+
+// ttyTask is a task drawn by ttyReporter
+type ttyTask struct {
+	reporter *ttyReporter
+	kind     string
+	label    string
+
+	mu      sync.Mutex
+	meter   meter
+	frame   int
+	width   int
+	stop    chan struct{}
+	stopped chan struct{}
+	ended   bool
+}
+
+// run redraws the line until the task ends
+func (t *ttyTask) run() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			t.frame++
+			t.draw()
+			t.mu.Unlock()
+		}
+	}
+}
+
+func (t *ttyTask) Update(current, total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.meter.update(current, total)
+}
+
+func (t *ttyTask) Done(summary string) {
+	if summary == "" {
+		t.mu.Lock()
+		summary = t.meter.summary()
+		t.mu.Unlock()
+	}
+	_, past := verbs(t.kind)
+	t.end(t.mark("✓", ansiGreen) + " " + past + " " + t.label + " (" + summary + ")")
+}
+
+func (t *ttyTask) Fail(err error) {
+	present, _ := verbs(t.kind)
+	t.end(t.mark("✗", ansiRed) + " " + present + " " + t.label + " failed: " + err.Error())
+}
+
+// Retry prints a line above the bar, which keeps being drawn
+func (t *ttyTask) Retry(err error, delay time.Duration, attempt, attempts int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ended {
+		return
+	}
+	t.printAbove(retryLine(t.kind, t.label, err, delay, attempt, attempts))
+}
+
+// Note prints a line above the bar, like Retry
+func (t *ttyTask) Note(message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.ended {
+		return
+	}
+	t.printAbove(t.label + ": " + message)
+}
+
+// printAbove replaces the bar with a line and draws the bar again below it;
+// the caller holds t.mu
+func (t *ttyTask) printAbove(line string) {
+	fmt.Fprintf(t.reporter.out, "\r%s\r%s\n", strings.Repeat(" ", t.width), line)
+	t.width = 0
+	t.draw()
+}
+
+// end stops redrawing and replaces the line with a final one
+func (t *ttyTask) end(line string) {
+	t.mu.Lock()
+	if t.ended {
+		t.mu.Unlock()
+		return
+	}
+	t.ended = true
+	t.mu.Unlock()
+
+	close(t.stop)
+	<-t.stopped
+
+	// clear the whole line first, since color codes throw off write's width
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.reporter.out, "\r%s\r%s\n", strings.Repeat(" ", t.width), line)
+}
+
+// mark returns a status mark, colored when the terminal allows
+func (t *ttyTask) mark(mark, color string) string {
+	if t.reporter.color {
+		return color + mark + ansiReset
+	}
+	return mark
+}
+
+// draw renders the current line; the caller holds t.mu
+func (t *ttyTask) draw() {
+	label := t.label
+	if utf8.RuneCountInString(label) > maxLabel {
+		label = string([]rune(label)[:maxLabel-3]) + "..."
+	}
+
+	m := &t.meter
+	var b strings.Builder
+	if m.total > 0 {
+		percent := float64(m.current) / float64(m.total)
+		if percent > 1 {
+			percent = 1
+		}
+		filled := int(percent * barWidth)
+		bar := strings.Repeat("=", filled)
+		if filled < barWidth {
+			bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+		}
+		fmt.Fprintf(&b, "%s [%s] %3.0f%% %s/%s", label, bar, percent*100, formatSize(m.current), formatSize(m.total))
+	} else {
+		present, _ := verbs(t.kind)
+		fmt.Fprintf(&b, "%s %s %s", present, label, spinner[t.frame%len(spinner)])
+		if m.current > 0 {
+			fmt.Fprintf(&b, " %s", formatSize(m.current))
+		}
+	}
+	if rate := m.rate(); rate > 0 {
+		fmt.Fprintf(&b, " %s/s", formatSize(int64(rate)))
+	}
+	if eta, ok := m.eta(); ok {
+		fmt.Fprintf(&b, " ETA %s", formatDuration(eta))
+	}
+	t.write(b.String())
+}
+
+// write overwrites the line, padding with spaces to clear a longer one
+// without relying on escape sequences
+func (t *ttyTask) write(line string) {
+	width := utf8.RuneCountInString(line)
+	pad := ""
+	if width < t.width {
+		pad = strings.Repeat(" ", t.width-width)
+	}
+	t.width = width
+	fmt.Fprintf(t.reporter.out, "\r%s%s", line, pad)
+}
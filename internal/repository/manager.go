@@ -3,25 +3,41 @@ package repository
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/veenone/mvnenv-win/internal/config"
 	"github.com/veenone/mvnenv-win/internal/download"
+	"github.com/veenone/mvnenv-win/internal/integrity"
 	"github.com/veenone/mvnenv-win/internal/nexus"
+	"github.com/veenone/mvnenv-win/internal/progress"
+	"github.com/veenone/mvnenv-win/internal/tool"
 )
 
 // Manager manages multiple repository sources
 type Manager struct {
-	apache      *ApacheArchive
+	upstreams   map[string]*Upstream
 	nexusClient *nexus.Client
 	config      *config.Manager
 	mvnenvRoot  string
 	offlineMode bool
+	preflight   download.PreflightFunc
+}
+
+// Download describes a downloaded archive
+type Download struct {
+	// Source is the URL the archive was downloaded from
+	Source string
+
+	// Integrity is the checksum verification of the archive; nil when the
+	// integrity policy is off
+	Integrity *integrity.Result
 }
 
 // NewManager creates a new repository manager
 func NewManager(mvnenvRoot string) *Manager {
 	return &Manager{
-		apache:      NewApacheArchive(),
+		upstreams:   make(map[string]*Upstream),
 		config:      config.NewManager(mvnenvRoot),
 		mvnenvRoot:  mvnenvRoot,
 		offlineMode: false,
@@ -33,6 +49,12 @@ func (m *Manager) SetOfflineMode(offline bool) {
 	m.offlineMode = offline
 }
 
+// SetPreflight sets a check run before archive data is written, with the
+// size the source advertises
+func (m *Manager) SetPreflight(preflight download.PreflightFunc) {
+	m.preflight = preflight
+}
+
 // initializeNexus initializes Nexus client if configured
 func (m *Manager) initializeNexus() error {
 	if m.nexusClient != nil {
@@ -68,6 +90,7 @@ func (m *Manager) initializeNexus() error {
 		nexusCfg.Username,
 		nexusCfg.Password,
 		tlsConfig,
+		nexusCfg.Proxy,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create Nexus client: %w", err)
@@ -77,36 +100,180 @@ func (m *Manager) initializeNexus() error {
 	return nil
 }
 
-// ListVersions returns available versions from all configured sources
-func (m *Manager) ListVersions() ([]string, error) {
+// CheckNexus reports whether Nexus is configured and, if it is, reads its
+// Maven metadata to check that it is reachable with the configured
+// credentials and TLS settings
+func (m *Manager) CheckNexus(ctx context.Context) (bool, error) {
+	if err := m.initializeNexus(); err != nil {
+		return true, err
+	}
+	if m.nexusClient == nil {
+		return false, nil
+	}
+	_, err := m.nexusClient.ListVersions(ctx)
+	return true, err
+}
+
+// upstream returns the public source client of a tool
+func (m *Manager) upstream(def *tool.Definition) *Upstream {
+	u, ok := m.upstreams[def.Name]
+	if !ok {
+		u = NewUpstream(def)
+		m.upstreams[def.Name] = u
+	}
+	u.SetPreflight(m.preflight)
+	return u
+}
+
+// FetchSidecar reads a file published next to a downloaded archive, such as
+// its signature (suffix ".asc"). sourceURL is the archive URL returned by
+// DownloadToolVersion; Nexus credentials are used when it points into Nexus.
+func (m *Manager) FetchSidecar(ctx context.Context, sourceURL, suffix string) ([]byte, error) {
+	return m.fetch(ctx, sourceURL+suffix)
+}
+
+// fetch reads a small file, with Nexus credentials when it lives in Nexus
+func (m *Manager) fetch(ctx context.Context, url string) ([]byte, error) {
+	if m.ownedByNexus(url) {
+		return m.nexusClient.Fetch(ctx, url)
+	}
+
+	return download.NewDownloader().Fetch(ctx, url)
+}
+
+// ownedByNexus reports whether url points into the configured Nexus
+func (m *Manager) ownedByNexus(url string) bool {
+	return m.initializeNexus() == nil && m.nexusClient != nil && m.nexusClient.Owns(url)
+}
+
+// IntegrityPolicy returns the configured integrity policy
+func (m *Manager) IntegrityPolicy() (integrity.Policy, error) {
+	cfg, err := m.config.Load()
+	if err != nil || cfg.Verification == nil {
+		return integrity.DefaultPolicy, nil
+	}
+	return integrity.ParsePolicy(cfg.Verification.Integrity)
+}
+
+// CheckIntegrity verifies an archive downloaded from sourceURL against the
+// strongest checksum published next to it and applies the integrity policy.
+// The result is nil when the policy is off; it is returned together with the
+// error when verification fails.
+func (m *Manager) CheckIntegrity(ctx context.Context, def *tool.Definition, version, sourceURL, path string) (*integrity.Result, error) {
+	policy, err := m.IntegrityPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if policy == integrity.PolicyOff {
+		return nil, nil
+	}
+
+	sourceName := def.UpstreamName
+	if m.ownedByNexus(sourceURL) {
+		sourceName = "Nexus"
+	}
+	what := fmt.Sprintf("%s %s from %s (%s)", def.DisplayName, version, sourceName, sourceURL)
+
+	return m.verify(ctx, policy, what, sourceURL, path)
+}
+
+// verify checks a file against the checksum published next to sourceURL
+func (m *Manager) verify(ctx context.Context, policy integrity.Policy, what, sourceURL, path string) (*integrity.Result, error) {
+	result := integrity.Verify(path, sourceURL, func(url string) ([]byte, error) {
+		return m.fetch(ctx, url)
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return &result, integrity.Check(policy, result, what)
+}
+
+// DownloadArtifact downloads a file of any artifact, such as an extension
+// jar, from Nexus and verifies it under the integrity policy. groupID uses
+// dots (e.g. "org.apache.maven.extensions"). There is no public fallback.
+func (m *Manager) DownloadArtifact(ctx context.Context, groupID, artifactID, version, fileName, destPath string, progress download.ProgressCallback) (*Download, error) {
+	policy, err := m.IntegrityPolicy()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.initializeNexus(); err != nil {
+		return nil, err
+	}
+	if m.nexusClient == nil {
+		return nil, fmt.Errorf("Nexus is not configured (set repositories.nexus in config.yaml)")
+	}
+
+	groupPath := strings.ReplaceAll(groupID, ".", "/")
+	m.nexusClient.SetPreflight(m.preflight)
+	if err := m.nexusClient.DownloadArtifact(ctx, groupPath, artifactID, version, fileName, destPath, progress); err != nil {
+		return nil, err
+	}
+
+	url := m.nexusClient.ArtifactURL(groupPath, artifactID, version, fileName)
+	if policy == integrity.PolicyOff {
+		return &Download{Source: url}, nil
+	}
+	what := fmt.Sprintf("%s:%s:%s from Nexus (%s)", groupID, artifactID, version, url)
+	result, err := m.verify(ctx, policy, what, url, destPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Download{Source: url, Integrity: result}, nil
+}
+
+// ListVersions returns available Maven versions from all configured sources
+func (m *Manager) ListVersions(ctx context.Context) ([]string, error) {
+	return m.ListToolVersions(ctx, tool.Maven)
+}
+
+// DownloadVersion downloads a Maven version from the first available source
+func (m *Manager) DownloadVersion(ctx context.Context, version string, destPath string, progress download.ProgressCallback) error {
+	_, err := m.DownloadToolVersion(ctx, tool.Maven, version, destPath, progress)
+	return err
+}
+
+// ListToolVersions returns available versions of a tool from all configured sources
+func (m *Manager) ListToolVersions(ctx context.Context, def *tool.Definition) ([]string, error) {
 	var allVersions []string
 	seen := make(map[string]bool)
 
 	// Try Nexus first if configured
-	if err := m.initializeNexus(); err == nil && m.nexusClient != nil {
-		ctx := context.Background()
-		nexusVersions, err := m.nexusClient.ListVersions(ctx)
-		if err != nil {
-			fmt.Printf("Warning: Failed to fetch versions from Nexus: %v\n", err)
-		} else {
-			for _, v := range nexusVersions {
-				if !seen[v] {
-					allVersions = append(allVersions, v)
-					seen[v] = true
+	if def.ArtifactID != "" {
+		if err := m.initializeNexus(); err == nil && m.nexusClient != nil {
+			nexusVersions, err := m.nexusClient.ListArtifactVersions(ctx, def.GroupPath(), def.ArtifactID)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to fetch versions from Nexus: %v\n", err)
+			} else {
+				for _, v := range nexusVersions {
+					if !seen[v] {
+						allVersions = append(allVersions, v)
+						seen[v] = true
+					}
 				}
 			}
 		}
 	}
 
-	// Get versions from Apache archive
-	apacheVersions, err := m.apache.ListVersions()
+	if len(def.ListURLs) == 0 {
+		return allVersions, nil
+	}
+
+	// Get versions from the tool's public source
+	upstreamVersions, err := m.upstream(def).ListVersions(ctx)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
 		if len(allVersions) == 0 {
-			return nil, fmt.Errorf("failed to fetch versions from Apache: %w", err)
+			return nil, fmt.Errorf("failed to fetch versions from %s: %w", def.UpstreamName, err)
 		}
-		fmt.Printf("Warning: Failed to fetch versions from Apache archive: %v\n", err)
+		note(ctx, "Warning: Failed to fetch versions from %s: %v", def.UpstreamName, err)
 	} else {
-		for _, v := range apacheVersions {
+		for _, v := range upstreamVersions {
 			if !seen[v] {
 				allVersions = append(allVersions, v)
 				seen[v] = true
@@ -117,38 +284,82 @@ func (m *Manager) ListVersions() ([]string, error) {
 	return allVersions, nil
 }
 
-// DownloadVersion downloads a version from the first available source
-func (m *Manager) DownloadVersion(version string, destPath string, progress download.ProgressCallback) error {
+// DownloadToolVersion downloads a version of a tool from the first available
+// source and verifies it under the integrity policy. An archive from Nexus
+// that fails verification is downloaded again from the public source.
+func (m *Manager) DownloadToolVersion(ctx context.Context, def *tool.Definition, version string, destPath string, progress download.ProgressCallback) (*Download, error) {
+	name := def.DisplayName
+
+	if _, err := m.IntegrityPolicy(); err != nil {
+		return nil, err
+	}
+
 	// Try Nexus first if configured
-	if err := m.initializeNexus(); err == nil && m.nexusClient != nil {
-		fmt.Printf("Attempting to download Maven %s from Nexus...\n", version)
-		ctx := context.Background()
+	if def.ArtifactID != "" {
+		if err := m.initializeNexus(); err == nil && m.nexusClient != nil {
+			note(ctx, "Attempting to download %s %s from Nexus", name, version)
 
-		nexusProgress := func(downloaded, total int64) {
-			if progress != nil {
-				progress(downloaded, total)
+			nexusProgress := func(downloaded, total int64) {
+				if progress != nil {
+					progress(downloaded, total)
+				}
 			}
-		}
 
-		err := m.nexusClient.DownloadVersion(ctx, version, destPath, nexusProgress)
-		if err == nil {
-			return nil
-		}
+			fileName := def.NexusArchiveName(version)
+			m.nexusClient.SetPreflight(m.preflight)
+			err := m.nexusClient.DownloadArtifact(ctx, def.GroupPath(), def.ArtifactID, version,
+				fileName, destPath, nexusProgress)
+			if err == nil {
+				url := m.nexusClient.ArtifactURL(def.GroupPath(), def.ArtifactID, version, fileName)
+				var result *integrity.Result
+				if result, err = m.CheckIntegrity(ctx, def, version, url, destPath); err == nil {
+					return &Download{Source: url, Integrity: result}, nil
+				}
+				if m.offlineMode {
+					return nil, err
+				}
+			}
 
-		// In offline mode, don't fall back to Apache archive
-		if m.offlineMode {
-			return fmt.Errorf("offline mode: Maven %s not available in Nexus and mirrors disabled", version)
-		}
+			// Don't fall back when the user interrupted the download
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 
-		fmt.Printf("Nexus download failed: %v\n", err)
-		fmt.Println("Falling back to Apache archive...")
+			// In offline mode, don't fall back to the public source
+			if m.offlineMode {
+				return nil, fmt.Errorf("offline mode: %s %s not available in Nexus and mirrors disabled", name, version)
+			}
+
+			note(ctx, "Nexus download failed (%v), falling back to %s", err, def.UpstreamName)
+		}
 	}
 
 	// If offline mode and no Nexus configured, fail
 	if m.offlineMode {
-		return fmt.Errorf("offline mode enabled but Nexus is not configured")
+		return nil, fmt.Errorf("offline mode enabled but Nexus is not configured")
 	}
 
-	// Fall back to Apache archive
-	return m.apache.DownloadVersion(version, destPath, progress)
+	// Fall back to the tool's public source
+	if err := m.upstream(def).DownloadVersion(ctx, version, destPath, progress); err != nil {
+		return nil, err
+	}
+
+	url := def.DownloadURLFor(version)
+	result, err := m.CheckIntegrity(ctx, def, version, url, destPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Download{Source: url, Integrity: result}, nil
+}
+
+// note reports a message on the progress task carried by ctx, which shows it
+// the way the user asked for (or not at all under --quiet). Without a task
+// it goes to stderr, keeping stdout for the command's output.
+func note(ctx context.Context, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if task := progress.TaskFrom(ctx); task != nil {
+		task.Note(message)
+		return
+	}
+	fmt.Fprintln(os.Stderr, message)
 }
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/veenone/mvnenv-win/internal/download"
+	"github.com/veenone/mvnenv-win/internal/tool"
+)
+
+// Upstream handles downloads from a tool's public release location, such as
+// the Apache archive for Maven or GitHub releases for mvnd
+type Upstream struct {
+	tool       *tool.Definition
+	downloader *download.Downloader
+}
+
+// NewUpstream creates a client for the public source of a tool
+func NewUpstream(def *tool.Definition) *Upstream {
+	return &Upstream{
+		tool:       def,
+		downloader: download.NewDownloader(),
+	}
+}
+
+// NewApacheArchive creates a new Apache archive client for Maven
+func NewApacheArchive() *Upstream {
+	return NewUpstream(tool.Maven)
+}
+
+// SetPreflight sets a check run before archive data is written
+func (u *Upstream) SetPreflight(preflight download.PreflightFunc) {
+	u.downloader.SetPreflight(preflight)
+}
+
+// ListVersions discovers the versions published upstream under every list
+// URL. A list that can't be fetched is reported and skipped, unless none
+// can.
+func (u *Upstream) ListVersions(ctx context.Context) ([]string, error) {
+	if len(u.tool.ListURLs) == 0 {
+		return nil, fmt.Errorf("%s does not declare a version list URL", u.tool.DisplayName)
+	}
+
+	var versions []string
+	var lastErr error
+	listed := 0
+	for _, url := range u.tool.ListURLs {
+		found, err := u.listVersions(ctx, url)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		versions = append(versions, found...)
+		listed++
+	}
+
+	if listed == 0 {
+		return nil, lastErr
+	}
+	if lastErr != nil {
+		note(ctx, "Warning: Failed to fetch some versions from %s: %v", u.tool.UpstreamName, lastErr)
+	}
+	return versions, nil
+}
+
+// listVersions discovers the versions published in one list
+func (u *Upstream) listVersions(ctx context.Context, url string) ([]string, error) {
+	body, err := u.downloader.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.tool.ListFormat {
+	case tool.ListFormatGitHub:
+		return parseGitHubReleases(body)
+	default:
+		return parseDirectoryListing(body, u.tool.ListPattern)
+	}
+}
+
+// DownloadVersion downloads a version from upstream. The archive is not
+// verified; see Manager.CheckIntegrity.
+func (u *Upstream) DownloadVersion(ctx context.Context, version string, destPath string, progress download.ProgressCallback) error {
+	if u.tool.DownloadURL == "" {
+		return fmt.Errorf("%s does not declare a download URL", u.tool.DisplayName)
+	}
+
+	// e.g. https://archive.apache.org/dist/maven/maven-3/3.9.4/binaries/apache-maven-3.9.4-bin.zip
+	// or .../maven-4/4.0.0-rc-2/binaries/apache-maven-4.0.0-rc-2-bin.zip
+	url := u.tool.DownloadURLFor(version)
+
+	note(ctx, "Downloading %s %s from %s", u.tool.DisplayName, version, u.tool.UpstreamName)
+
+	return u.downloader.Download(ctx, url, destPath, progress)
+}
+
+// parseDirectoryListing extracts versions from an HTML directory listing.
+// The first group of pattern captures the version, e.g. for Maven:
+// <a href="3.9.4/">3.9.4/</a>
+func parseDirectoryListing(body []byte, pattern string) ([]string, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("no version list pattern declared")
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version list pattern: %w", err)
+	}
+	matches := re.FindAllStringSubmatch(string(body), -1)
+
+	var versions []string
+	seen := make(map[string]bool)
+
+	for _, match := range matches {
+		if len(match) > 1 {
+			version := match[1]
+			if !seen[version] {
+				versions = append(versions, version)
+				seen[version] = true
+			}
+		}
+	}
+
+	return versions, nil
+}
+
+// parseGitHubReleases extracts versions from a GitHub releases API response
+func parseGitHubReleases(body []byte) ([]string, error) {
+	var releases []struct {
+		TagName string `json:"tag_name"`
+		Draft   bool   `json:"draft"`
+	}
+	if err := json.Unmarshal(body, &releases); err != nil {
+		return nil, fmt.Errorf("parse releases: %w", err)
+	}
+
+	var versions []string
+	for _, r := range releases {
+		if r.Draft || r.TagName == "" {
+			continue
+		}
+		versions = append(versions, strings.TrimPrefix(r.TagName, "v"))
+	}
+
+	return versions, nil
+}
@@ -0,0 +1,96 @@
+package shim
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/veenone/mvnenv-win/internal/tool"
+	versionpkg "github.com/veenone/mvnenv-win/internal/version"
+)
+
+// launcherExtensions are tried, in order, when looking up a command in a
+// Maven installation's bin directory
+var launcherExtensions = []string{".cmd", ".bat", ".exe", ""}
+
+// BuildEnvironment returns the environment a tool command runs with:
+// the current environment plus the tool's home variables (MAVEN_HOME for
+// Maven; MVND_HOME and the bundled MAVEN_HOME for mvnd) and the version's bin
+// directory prepended to PATH. No other variables are set; MAVEN_OPTS and the
+// like come from the caller's environment.
+func BuildEnvironment(resolved *versionpkg.ResolvedVersion) []string {
+	def := resolved.Tool
+	if def == nil {
+		def = tool.Maven
+	}
+
+	env := os.Environ()
+	homeEnv := def.HomeEnvironment(resolved.Path)
+	names := make([]string, 0, len(homeEnv))
+	for name := range homeEnv {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = setEnv(env, name, homeEnv[name])
+	}
+
+	binDir := filepath.Join(resolved.Path, "bin")
+	path := getEnv(env, "PATH")
+	if path == "" {
+		path = binDir
+	} else {
+		path = binDir + string(os.PathListSeparator) + path
+	}
+	env = setEnv(env, "PATH", path)
+
+	return env
+}
+
+// LookCommand finds a command for the resolved version. Bare names are looked
+// up in the version's bin directory first, then on PATH.
+func LookCommand(resolved *versionpkg.ResolvedVersion, command string) (string, error) {
+	if strings.ContainsAny(command, `/\`) {
+		return command, nil
+	}
+
+	binDir := filepath.Join(resolved.Path, "bin")
+	for _, ext := range launcherExtensions {
+		candidate := filepath.Join(binDir, command+ext)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return "", fmt.Errorf("command not found: %s", command)
+	}
+	return path, nil
+}
+
+// getEnv returns the value of key in env, matching names case-insensitively
+// as Windows does
+func getEnv(env []string, key string) string {
+	for _, kv := range env {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.EqualFold(name, key) {
+			return value
+		}
+	}
+	return ""
+}
+
+// setEnv sets key in env, replacing any existing entries regardless of case
+func setEnv(env []string, key, value string) []string {
+	result := make([]string, 0, len(env)+1)
+	for _, kv := range env {
+		if name, _, ok := strings.Cut(kv, "="); ok && strings.EqualFold(name, key) {
+			continue
+		}
+		result = append(result, kv)
+	}
+	return append(result, key+"="+value)
+}
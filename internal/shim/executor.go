@@ -7,25 +7,57 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"syscall"
+	"strconv"
 	"time"
 
+	"github.com/veenone/mvnenv-win/internal/httpclient"
+	"github.com/veenone/mvnenv-win/internal/tool"
 	versionpkg "github.com/veenone/mvnenv-win/internal/version"
 )
 
+// WrapperCommand is the shim name that intercepts Maven Wrapper (mvnw) calls
+const WrapperCommand = "mvnw"
+
+// DefaultGracePeriod is how long the shim waits for Maven to exit after
+// forwarding an interrupt before force-killing it
+const DefaultGracePeriod = 10 * time.Second
+
 // ShimExecutor executes Maven commands with version resolution
 type ShimExecutor struct {
-	resolver *versionpkg.VersionResolver
-	debug    bool
+	resolver    *versionpkg.VersionResolver
+	debug       bool
+	gracePeriod time.Duration
 }
 
 // NewShimExecutor creates a shim executor
 func NewShimExecutor(resolver *versionpkg.VersionResolver) *ShimExecutor {
 	debug := os.Getenv("MVNENV_DEBUG") == "1"
 	return &ShimExecutor{
-		resolver: resolver,
-		debug:    debug,
+		resolver:    resolver,
+		debug:       debug,
+		gracePeriod: gracePeriodFromEnv(),
+	}
+}
+
+// SetGracePeriod sets how long Maven may take to shut down after an interrupt
+func (e *ShimExecutor) SetGracePeriod(d time.Duration) {
+	e.gracePeriod = d
+}
+
+// gracePeriodFromEnv reads MVNENV_SHIM_GRACE_PERIOD as a Go duration ("30s")
+// or a plain number of seconds, falling back to DefaultGracePeriod
+func gracePeriodFromEnv() time.Duration {
+	value := os.Getenv("MVNENV_SHIM_GRACE_PERIOD")
+	if value == "" {
+		return DefaultGracePeriod
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d
 	}
+	if secs, err := strconv.Atoi(value); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return DefaultGracePeriod
 }
 
 // Execute resolves Maven version and executes command
@@ -33,20 +65,37 @@ func (e *ShimExecutor) Execute(command string, args []string) (int, error) {
 	startTime := time.Now()
 
 	// Resolve active Maven version
-	resolved, err := e.resolver.ResolveVersion()
+	var resolved *versionpkg.ResolvedVersion
+	var err error
+	// Tools declared in config.yaml are only loaded for commands that don't
+	// belong to a built-in tool, keeping the common path free of YAML parsing
+	if command != WrapperCommand && !tool.IsBuiltinCommand(command) {
+		if err := tool.LoadConfigured(e.resolver.MvnenvRoot()); err != nil && e.debug {
+			fmt.Fprintf(os.Stderr, "[mvnenv] Failed to load configured tools: %v\n", err)
+		}
+	}
+
+	def := tool.ForCommand(command)
+	if command == WrapperCommand {
+		// Maven Wrapper projects run the pinned version's mvn directly
+		resolved, err = e.resolveWrapper()
+		command = "mvn"
+	} else {
+		resolved, err = e.resolverFor(def).ResolveVersion()
+	}
 	if err != nil {
-		return 1, e.formatResolutionError(err)
+		return 1, e.formatResolutionError(def, err)
 	}
 
 	resolutionTime := time.Since(startTime)
 
-	// Construct path to Maven command
-	mavenPath := e.constructMavenPath(resolved.Path, command)
+	// Construct path to the tool's command
+	mavenPath := e.constructMavenPath(resolved, command)
 
-	// Verify Maven binary exists
+	// Verify binary exists
 	if _, err := os.Stat(mavenPath); err != nil {
-		return 1, fmt.Errorf("Maven binary not found at %s\nVersion %s may be corrupted. Try reinstalling with: mvnenv install %s",
-			mavenPath, resolved.Version, resolved.Version)
+		return 1, fmt.Errorf("%s binary not found at %s\nVersion %s may be corrupted. Try reinstalling with: mvnenv install %s%s",
+			def.DisplayName, mavenPath, resolved.Version, toolFlag(def), resolved.Version)
 	}
 
 	if e.debug {
@@ -54,7 +103,7 @@ func (e *ShimExecutor) Execute(command string, args []string) (int, error) {
 	}
 
 	// Execute Maven with I/O forwarding
-	exitCode, err := e.executeMaven(mavenPath, args, resolved)
+	exitCode, err := e.run(mavenPath, args, resolved)
 
 	if e.debug {
 		executionTime := time.Since(startTime)
@@ -64,21 +113,102 @@ func (e *ShimExecutor) Execute(command string, args []string) (int, error) {
 	return exitCode, err
 }
 
-// constructMavenPath builds path to Maven command binary
-func (e *ShimExecutor) constructMavenPath(versionPath string, command string) string {
-	return filepath.Join(versionPath, "bin", command+".cmd")
+// constructMavenPath builds path to the command's launcher script
+func (e *ShimExecutor) constructMavenPath(resolved *versionpkg.ResolvedVersion, command string) string {
+	if resolved.Tool != nil {
+		return resolved.Tool.LauncherPath(resolved.Path, command)
+	}
+	return filepath.Join(resolved.Path, "bin", command+".cmd")
 }
 
-// executeMaven spawns Maven process with I/O forwarding
-func (e *ShimExecutor) executeMaven(mavenPath string, args []string, resolved *versionpkg.ResolvedVersion) (int, error) {
-	// Create command with context for cancellation
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+// resolverFor returns a resolver for the tool owning a shimmed command
+func (e *ShimExecutor) resolverFor(def *tool.Definition) *versionpkg.VersionResolver {
+	if def == e.resolver.Tool() {
+		return e.resolver
+	}
+	return versionpkg.NewToolResolver(e.resolver.MvnenvRoot(), def)
+}
 
-	cmd := exec.CommandContext(ctx, mavenPath, args...)
+// toolFlag returns the --tool argument needed in hints for non-Maven tools
+func toolFlag(def *tool.Definition) string {
+	if def.IsMaven() {
+		return ""
+	}
+	return "--tool " + def.Name + " "
+}
+
+// resolveWrapper resolves the version pinned by the project's Maven Wrapper
+// properties, installing it through mvnenv's configured repositories (Nexus
+// first) instead of letting mvnw download it into ~/.m2/wrapper. A
+// distributionSha256Sum in the properties must match the archive the version
+// is installed from.
+func (e *ShimExecutor) resolveWrapper() (*versionpkg.ResolvedVersion, error) {
+	dist, err := e.resolver.WrapperDistribution()
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := e.resolver.ResolveWrapperVersion()
+	installed := err == nil
+	if !installed && !versionpkg.IsVersionNotInstalledError(err) {
+		return nil, err
+	}
+	if installed && dist.SHA256 == "" {
+		return resolved, nil
+	}
 
-	// Set MAVEN_HOME environment variable
-	cmd.Env = append(os.Environ(), fmt.Sprintf("MAVEN_HOME=%s", resolved.Path))
+	if err := httpclient.LoadConfigured(e.resolver.MvnenvRoot()); err != nil && e.debug {
+		fmt.Fprintf(os.Stderr, "[mvnenv] Failed to load proxy settings: %v\n", err)
+	}
+
+	installer := versionpkg.NewVersionInstaller(e.resolver.MvnenvRoot())
+	installer.SetSkipExisting(true)
+	installer.SetQuiet(true)
+	installer.SetArchiveSHA256(dist.SHA256)
+	// Ctrl+C stops the install cleanly instead of leaving partial files
+	ctx, stop := signal.NotifyContext(context.Background(), forwardedSignals...)
+	defer stop()
+
+	if !installed {
+		fmt.Fprintf(os.Stderr, "[mvnenv] Maven Wrapper requires Maven %s, installing it...\n", dist.Version)
+		if err := installer.InstallVersion(ctx, dist.Version); err != nil {
+			return nil, fmt.Errorf("install Maven %s for Maven Wrapper: %w", dist.Version, err)
+		}
+	}
+	// Also when another process installed the version first
+	if dist.SHA256 != "" {
+		if err := installer.CheckArchiveSHA256(ctx, dist.Version, dist.SHA256); err != nil {
+			return nil, fmt.Errorf("check Maven %s against distributionSha256Sum: %w", dist.Version, err)
+		}
+	}
+
+	if installed {
+		return resolved, nil
+	}
+	return e.resolver.ResolveWrapperVersion()
+}
+
+// ExecuteCommand runs an arbitrary command under an already resolved version,
+// with the same environment, signal handling and exit code propagation as a
+// Maven shim
+func (e *ShimExecutor) ExecuteCommand(resolved *versionpkg.ResolvedVersion, command string, args []string) (int, error) {
+	commandPath, err := LookCommand(resolved, command)
+	if err != nil {
+		return 1, err
+	}
+
+	if e.debug {
+		e.logDebug(command, args, resolved, commandPath, 0)
+	}
+
+	return e.run(commandPath, args, resolved)
+}
+
+// run spawns a process with I/O forwarding
+func (e *ShimExecutor) run(commandPath string, args []string, resolved *versionpkg.ResolvedVersion) (int, error) {
+	cmd := exec.Command(commandPath, args...)
+
+	// Set MAVEN_HOME and prepend the version's bin directory to PATH
+	cmd.Env = BuildEnvironment(resolved)
 
 	// Forward stdin/stdout/stderr (no buffering)
 	cmd.Stdin = os.Stdin
@@ -87,26 +217,28 @@ func (e *ShimExecutor) executeMaven(mavenPath string, args []string, resolved *v
 
 	// Set working directory to current directory
 	cmd.Dir, _ = os.Getwd()
+	configureProcess(cmd)
 
-	// Handle signals (Ctrl+C, etc.)
+	// Catch signals (Ctrl+C, etc.) before starting so none slip through
 	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-signalChan
-		cancel()
-	}()
+	signal.Notify(signalChan, forwardedSignals...)
+	defer signal.Stop(signalChan)
 
-	// Start Maven process
+	// Start process
 	if err := cmd.Start(); err != nil {
-		return 1, fmt.Errorf("failed to start Maven: %w", err)
+		return 1, fmt.Errorf("failed to start %s: %w", filepath.Base(commandPath), err)
 	}
 
-	// Wait for Maven to complete
-	if err := cmd.Wait(); err != nil {
+	done := make(chan struct{})
+	go e.forwardSignals(cmd.Process, signalChan, done)
+
+	// Wait for process to complete
+	err := cmd.Wait()
+	close(done)
+
+	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
-				return status.ExitStatus(), nil
-			}
+			return exitCode(exitErr.ProcessState), nil
 		}
 		return 1, err
 	}
@@ -114,18 +246,59 @@ func (e *ShimExecutor) executeMaven(mavenPath string, args []string, resolved *v
 	return 0, nil
 }
 
+// forwardSignals relays interrupts to Maven so it can shut down its forks
+// cleanly. If Maven is still running after the grace period, or a second
+// interrupt arrives, the process tree is force-killed.
+func (e *ShimExecutor) forwardSignals(process *os.Process, signalChan <-chan os.Signal, done <-chan struct{}) {
+	var deadline <-chan time.Time
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case sig := <-signalChan:
+			if deadline != nil {
+				if e.debug {
+					fmt.Fprintf(os.Stderr, "[mvnenv] Received %v again, killing Maven\n", sig)
+				}
+				killProcessTree(process)
+				return
+			}
+
+			if e.debug {
+				fmt.Fprintf(os.Stderr, "[mvnenv] Forwarding %v to Maven (grace period %v)\n", sig, e.gracePeriod)
+			}
+			if err := forwardSignal(process, sig); err != nil && e.debug {
+				fmt.Fprintf(os.Stderr, "[mvnenv] Failed to forward %v: %v\n", sig, err)
+			}
+			deadline = time.After(e.gracePeriod)
+
+		case <-deadline:
+			if e.debug {
+				fmt.Fprintf(os.Stderr, "[mvnenv] Maven did not exit within %v, killing it\n", e.gracePeriod)
+			}
+			killProcessTree(process)
+			return
+		}
+	}
+}
+
 // formatResolutionError creates user-friendly error messages
-func (e *ShimExecutor) formatResolutionError(err error) error {
+func (e *ShimExecutor) formatResolutionError(def *tool.Definition, err error) error {
+	name := def.DisplayName
+	flag := toolFlag(def)
+
 	switch {
 	case versionpkg.IsVersionNotInstalledError(err):
 		ver := versionpkg.ExtractVersionFromError(err)
-		return fmt.Errorf("Maven version '%s' is set but not installed.\nInstall it with: mvnenv install %s", ver, ver)
+		return fmt.Errorf("%s version '%s' is set but not installed.\nInstall it with: mvnenv install %s%s", name, ver, flag, ver)
 
 	case versionpkg.IsNoVersionSetError(err):
-		return fmt.Errorf("No Maven version is set.\nSet a global version with: mvnenv global <version>\nOr see available versions with: mvnenv install -l")
+		return fmt.Errorf("No %s version is set.\nSet a global version with: mvnenv global %s<version>\nOr see available versions with: mvnenv install %s-l", name, flag, flag)
 
 	default:
-		return fmt.Errorf("Failed to resolve Maven version: %w", err)
+		return fmt.Errorf("Failed to resolve %s version: %w", name, err)
 	}
 }
 
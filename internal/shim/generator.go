@@ -6,26 +6,41 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/lock"
+	"github.com/veenone/mvnenv-win/internal/tool"
 )
 
 // ShimGenerator creates and manages Maven command shims
 type ShimGenerator struct {
-	shimsDir    string
-	shimBinary  string
-	versionsDir string
+	mvnenvRoot    string
+	shimsDir      string
+	shimBinary    string
+	versionsDir   string
+	configManager *config.Manager
 }
 
 // NewShimGenerator creates a shim generator
 func NewShimGenerator(mvnenvRoot string) *ShimGenerator {
 	return &ShimGenerator{
-		shimsDir:    filepath.Join(mvnenvRoot, "shims"),
-		shimBinary:  filepath.Join(mvnenvRoot, "bin", "shim.exe"),
-		versionsDir: filepath.Join(mvnenvRoot, "versions"),
+		mvnenvRoot:    mvnenvRoot,
+		shimsDir:      filepath.Join(mvnenvRoot, "shims"),
+		shimBinary:    filepath.Join(mvnenvRoot, "bin", "shim.exe"),
+		versionsDir:   filepath.Join(mvnenvRoot, "versions"),
+		configManager: config.NewManager(mvnenvRoot),
 	}
 }
 
 // GenerateShims creates shim executables for all Maven commands
 func (g *ShimGenerator) GenerateShims() ([]string, error) {
+	// Serialize with rehashes run by other mvnenv processes
+	fileLock, err := lock.Acquire(g.mvnenvRoot, "shims")
+	if err != nil {
+		return nil, fmt.Errorf("lock shims: %w", err)
+	}
+	defer fileLock.Release()
+
 	// Ensure shims directory exists
 	if err := os.MkdirAll(g.shimsDir, 0755); err != nil {
 		return nil, fmt.Errorf("create shims directory: %w", err)
@@ -40,6 +55,31 @@ func (g *ShimGenerator) GenerateShims() ([]string, error) {
 		commands = append(commands, additionalCmds...)
 	}
 
+	// Other tools (mvnd and tools declared in config.yaml) get shims once
+	// any version is installed
+	if err := tool.LoadConfigured(g.mvnenvRoot); err != nil {
+		return nil, err
+	}
+	for _, def := range tool.All() {
+		if def.IsMaven() {
+			continue
+		}
+		if g.hasInstallations(def) {
+			commands = append(commands, def.Launchers...)
+		} else {
+			for _, launcher := range def.Launchers {
+				g.removeShim(launcher)
+			}
+		}
+	}
+
+	// Maven Wrapper interception is opt-in
+	if g.wrapperInterceptEnabled() {
+		commands = append(commands, WrapperCommand)
+	} else {
+		g.removeShim(WrapperCommand)
+	}
+
 	var generatedPaths []string
 
 	for _, cmd := range commands {
@@ -61,6 +101,44 @@ func (g *ShimGenerator) GenerateShims() ([]string, error) {
 	return generatedPaths, nil
 }
 
+// ShimsDir returns the directory shims are generated in
+func (g *ShimGenerator) ShimsDir() string {
+	return g.shimsDir
+}
+
+// ShimBinary returns the shim executable copied for each command
+func (g *ShimGenerator) ShimBinary() string {
+	return g.shimBinary
+}
+
+// CheckShims returns the shims of Maven and of installed tools that are
+// missing or differ in size from shim.exe; a rehash fixes them
+func (g *ShimGenerator) CheckShims() ([]string, error) {
+	binary, err := os.Stat(g.shimBinary)
+	if err != nil {
+		return nil, fmt.Errorf("shim.exe not found at %s: %w", g.shimBinary, err)
+	}
+
+	commands := []string{"mvn", "mvnDebug"}
+	for _, def := range tool.All() {
+		if !def.IsMaven() && g.hasInstallations(def) {
+			commands = append(commands, def.Launchers...)
+		}
+	}
+
+	var stale []string
+	for _, cmd := range commands {
+		exe, err := os.Stat(filepath.Join(g.shimsDir, cmd+".exe"))
+		if err != nil || exe.Size() != binary.Size() {
+			stale = append(stale, cmd+".exe")
+		}
+		if _, err := os.Stat(filepath.Join(g.shimsDir, cmd+".cmd")); err != nil {
+			stale = append(stale, cmd+".cmd")
+		}
+	}
+	return stale, nil
+}
+
 // generateShimFile creates executable shim by copying shim.exe
 func (g *ShimGenerator) generateShimFile(command string, ext string) (string, error) {
 	destPath := filepath.Join(g.shimsDir, command+ext)
@@ -94,6 +172,37 @@ exit /b %%ERRORLEVEL%%
 	return destPath, nil
 }
 
+// wrapperInterceptEnabled reports whether mvnw shims are enabled in config
+func (g *ShimGenerator) wrapperInterceptEnabled() bool {
+	cfg, err := g.configManager.Load()
+	if err != nil {
+		return false
+	}
+	return cfg.Wrapper != nil && cfg.Wrapper.Intercept
+}
+
+// hasInstallations reports whether at least one version of a tool is installed
+func (g *ShimGenerator) hasInstallations(def *tool.Definition) bool {
+	versionsDir := def.VersionsPath(g.mvnenvRoot)
+	entries, err := os.ReadDir(versionsDir)
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && def.IsValidInstallation(filepath.Join(versionsDir, entry.Name())) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeShim deletes the .exe and .cmd shims for a command if present
+func (g *ShimGenerator) removeShim(command string) {
+	os.Remove(filepath.Join(g.shimsDir, command+".exe"))
+	os.Remove(filepath.Join(g.shimsDir, command+".cmd"))
+}
+
 // discoverAdditionalCommands scans installed versions for commands like mvnyjp
 func (g *ShimGenerator) discoverAdditionalCommands() ([]string, error) {
 	entries, err := os.ReadDir(g.versionsDir)
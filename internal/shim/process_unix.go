@@ -0,0 +1,74 @@
+//go:build !windows
+// +build !windows
+
+package shim
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+// forwardedSignals are the signals the shim relays to Maven
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// configureProcess starts Maven in a process group of its own, so a signal
+// or kill reaches the JVM and every fork it started. When the shim runs in
+// the foreground of a terminal, Maven stays in its group instead: Ctrl+C
+// already reaches the whole group there, and a background group would be
+// stopped as soon as Maven reads from the terminal, e.g. to prompt.
+func configureProcess(cmd *exec.Cmd) {
+	if !terminalForeground() {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+}
+
+// terminalForeground reports whether stdin is a terminal whose foreground
+// process group is the shim's
+func terminalForeground() bool {
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	return errno == 0 && int(pgrp) == syscall.Getpgrp()
+}
+
+// ownGroup reports whether the Maven process leads a process group of its
+// own, as configureProcess arranges outside a terminal
+func ownGroup(process *os.Process) bool {
+	pgid, err := syscall.Getpgid(process.Pid)
+	return err == nil && pgid == process.Pid
+}
+
+// forwardSignal delivers the received signal to Maven's process group, or to
+// the Maven process when it shares the shim's group. An interrupt is not
+// forwarded in the shared case: it came from the terminal, which delivered
+// it to Maven as well, and a second one would make Maven skip its orderly
+// shutdown.
+func forwardSignal(process *os.Process, sig os.Signal) error {
+	if s, ok := sig.(syscall.Signal); ok && ownGroup(process) {
+		return syscall.Kill(-process.Pid, s)
+	}
+	if sig == os.Interrupt {
+		return nil
+	}
+	return process.Signal(sig)
+}
+
+// killProcessTree force-kills Maven's process group, which holds the JVM and
+// its forks. In a terminal's foreground group only the Maven process can be
+// killed without taking the shim and its parent down too.
+func killProcessTree(process *os.Process) error {
+	if ownGroup(process) {
+		return syscall.Kill(-process.Pid, syscall.SIGKILL)
+	}
+	return process.Kill()
+}
+
+// exitCode extracts the exit status, mapping signal deaths to 128+signal
+// like a POSIX shell does
+func exitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
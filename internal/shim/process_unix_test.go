@@ -0,0 +1,151 @@
+//go:build !windows
+// +build !windows
+
+package shim
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess stands in for Maven. MVNENV_SHIM_HELPER selects what it
+// does: "exit" exits with code 3, "die" kills itself with SIGTERM, "trap"
+// exits with code 7 on SIGINT or SIGTERM, and "ignore" ignores both.
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("MVNENV_SHIM_HELPER")
+	switch mode {
+	case "":
+		return
+	case "exit":
+		os.Exit(3)
+	case "die":
+		syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		time.Sleep(time.Minute)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	os.Stdout.WriteString("ready\n")
+	for range signals {
+		if mode == "trap" {
+			os.Exit(7)
+		}
+	}
+}
+
+// startHelper starts TestHelperProcess in a mode, in a process group of its
+// own or in the test's, and waits until it handles signals
+func startHelper(t *testing.T, mode string, ownGroup bool) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "MVNENV_SHIM_HELPER="+mode)
+	if ownGroup {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill() })
+
+	if line, err := bufio.NewReader(stdout).ReadString('\n'); err != nil || line != "ready\n" {
+		t.Fatalf("helper did not start: %q, %v", line, err)
+	}
+	return cmd
+}
+
+// forward runs forwardSignals for a helper, sending it signals, and returns
+// the helper's exit state and how long it took to exit
+func forward(t *testing.T, cmd *exec.Cmd, gracePeriod time.Duration, signals ...os.Signal) (*os.ProcessState, time.Duration) {
+	t.Helper()
+	e := &ShimExecutor{gracePeriod: gracePeriod}
+	signalChan := make(chan os.Signal, len(signals))
+	done := make(chan struct{})
+	defer close(done)
+
+	start := time.Now()
+	go e.forwardSignals(cmd.Process, signalChan, done)
+	for _, sig := range signals {
+		signalChan <- sig
+	}
+
+	cmd.Wait()
+	return cmd.ProcessState, time.Since(start)
+}
+
+func TestExitCode(t *testing.T) {
+	for mode, want := range map[string]int{"exit": 3, "die": 128 + int(syscall.SIGTERM)} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmd.Env = append(os.Environ(), "MVNENV_SHIM_HELPER="+mode)
+		cmd.Run()
+		if got := exitCode(cmd.ProcessState); got != want {
+			t.Errorf("%s: exitCode() = %d, want %d", mode, got, want)
+		}
+	}
+}
+
+func TestForwardSignalsToOwnGroup(t *testing.T) {
+	for _, sig := range []os.Signal{os.Interrupt, syscall.SIGTERM} {
+		cmd := startHelper(t, "trap", true)
+
+		state, _ := forward(t, cmd, time.Minute, sig)
+		if state.ExitCode() != 7 {
+			t.Errorf("%v: helper exited with %v, want it to handle the signal", sig, state)
+		}
+	}
+}
+
+// In a terminal's foreground group, Ctrl+C already reached Maven; the shim
+// must not send a second one but still kills Maven after the grace period
+func TestForwardSignalsSharedGroupInterrupt(t *testing.T) {
+	cmd := startHelper(t, "trap", false)
+
+	state, elapsed := forward(t, cmd, 300*time.Millisecond, os.Interrupt)
+	if exitCode(state) != 128+int(syscall.SIGKILL) {
+		t.Errorf("helper exited with %v, want it killed after the grace period", state)
+	}
+	if elapsed < 300*time.Millisecond {
+		t.Errorf("killed after %s, before the grace period", elapsed)
+	}
+}
+
+func TestForwardSignalsSharedGroupTerminate(t *testing.T) {
+	cmd := startHelper(t, "trap", false)
+
+	state, _ := forward(t, cmd, time.Minute, syscall.SIGTERM)
+	if state.ExitCode() != 7 {
+		t.Errorf("helper exited with %v, want it to handle SIGTERM", state)
+	}
+}
+
+func TestForwardSignalsGracePeriod(t *testing.T) {
+	cmd := startHelper(t, "ignore", true)
+
+	state, elapsed := forward(t, cmd, 300*time.Millisecond, syscall.SIGTERM)
+	if exitCode(state) != 128+int(syscall.SIGKILL) {
+		t.Errorf("helper exited with %v, want it killed", state)
+	}
+	if elapsed < 300*time.Millisecond {
+		t.Errorf("killed after %s, before the grace period", elapsed)
+	}
+}
+
+func TestForwardSignalsSecondInterruptKills(t *testing.T) {
+	cmd := startHelper(t, "ignore", true)
+
+	state, elapsed := forward(t, cmd, time.Minute, os.Interrupt, os.Interrupt)
+	if exitCode(state) != 128+int(syscall.SIGKILL) {
+		t.Errorf("helper exited with %v, want it killed", state)
+	}
+	if elapsed > 10*time.Second {
+		t.Errorf("killed after %s, want at once", elapsed)
+	}
+}
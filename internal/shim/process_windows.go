@@ -0,0 +1,42 @@
+//go:build windows
+// +build windows
+
+package shim
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// forwardedSignals are the console events the shim intercepts
+var forwardedSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// forwardSignal is a no-op on Windows: console control events (Ctrl+C,
+// Ctrl+Break) are already delivered to every process attached to the
+// console, including mvn.cmd and its JVM. The shim only has to stay alive
+// and wait for Maven to finish shutting down.
+func forwardSignal(process *os.Process, sig os.Signal) error {
+	return nil
+}
+
+// configureProcess needs no changes on Windows, where killProcessTree finds
+// Maven's forks by their parent process
+func configureProcess(cmd *exec.Cmd) {}
+
+// killProcessTree force-kills mvn.cmd together with the JVM and any forks it
+// started. Process.Kill alone would only terminate cmd.exe.
+func killProcessTree(process *os.Process) error {
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(process.Pid))
+	if err := kill.Run(); err != nil {
+		return process.Kill()
+	}
+	return nil
+}
+
+// exitCode extracts the exit status. Processes terminated by Ctrl+C report
+// STATUS_CONTROL_C_EXIT, which is passed through unchanged.
+func exitCode(state *os.ProcessState) int {
+	return state.ExitCode()
+}
@@ -0,0 +1,210 @@
+package signature
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/veenone/mvnenv-win/internal/lock"
+)
+
+// armorBegin starts every ASCII-armored block in a KEYS file
+const armorBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+// Key describes a public key in the keyring
+type Key struct {
+	KeyID       string    `json:"key_id"`
+	Fingerprint string    `json:"fingerprint"`
+	UserIDs     []string  `json:"user_ids"`
+	Created     time.Time `json:"created"`
+}
+
+// Keyring is the pinned set of public keys trusted to sign distributions. It
+// lives in <MVNENV_ROOT>/keys/pubring.asc and only changes through Import.
+type Keyring struct {
+	mvnenvRoot string
+	path       string
+}
+
+// NewKeyring opens the keyring of an mvnenv root
+func NewKeyring(mvnenvRoot string) *Keyring {
+	return &Keyring{
+		mvnenvRoot: mvnenvRoot,
+		path:       filepath.Join(mvnenvRoot, "keys", "pubring.asc"),
+	}
+}
+
+// Path returns the keyring file
+func (k *Keyring) Path() string {
+	return k.path
+}
+
+// Keys lists the keys in the keyring
+func (k *Keyring) Keys() ([]Key, error) {
+	entities, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]Key, 0, len(entities))
+	for _, e := range entities {
+		keys = append(keys, describe(e))
+	}
+	return keys, nil
+}
+
+// Import adds the public keys found in data, which may be an Apache KEYS file
+// (free text with any number of armored key blocks) or a binary keyring.
+// Keys already present are replaced by the imported copy so updated
+// expiration dates and revocations take effect. It returns the imported keys
+// and how many of them are new.
+func (k *Keyring) Import(data []byte) ([]Key, int, error) {
+	imported, err := parseKeys(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(imported) == 0 {
+		return nil, 0, fmt.Errorf("no public keys found")
+	}
+
+	fileLock, err := lock.Acquire(k.mvnenvRoot, "keys")
+	if err != nil {
+		return nil, 0, fmt.Errorf("lock keyring: %w", err)
+	}
+	defer fileLock.Release()
+
+	existing, err := k.load()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byFingerprint := make(map[string]*openpgp.Entity)
+	var order []string
+	for _, e := range append(existing, imported...) {
+		fp := fingerprint(e)
+		if _, ok := byFingerprint[fp]; !ok {
+			order = append(order, fp)
+		}
+		byFingerprint[fp] = e
+	}
+	added := len(order) - len(existing)
+
+	var merged openpgp.EntityList
+	for _, fp := range order {
+		merged = append(merged, byFingerprint[fp])
+	}
+	if err := k.save(merged); err != nil {
+		return nil, 0, err
+	}
+
+	keys := make([]Key, 0, len(imported))
+	for _, e := range imported {
+		keys = append(keys, describe(e))
+	}
+	return keys, added, nil
+}
+
+// load reads the keyring; a missing keyring is empty
+func (k *Keyring) load() (openpgp.EntityList, error) {
+	f, err := os.Open(k.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open keyring: %w", err)
+	}
+	defer f.Close()
+
+	entities, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("read keyring %s: %w", k.path, err)
+	}
+	return entities, nil
+}
+
+// save writes the keyring as a single armored block
+func (k *Keyring) save(entities openpgp.EntityList) error {
+	if err := os.MkdirAll(filepath.Dir(k.path), 0755); err != nil {
+		return fmt.Errorf("create keys directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return err
+	}
+	for _, e := range entities {
+		if err := e.Serialize(w); err != nil {
+			return fmt.Errorf("serialize key %s: %w", e.PrimaryKey.KeyIdString(), err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+
+	// Atomic write
+	tmpPath := k.path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write keyring: %w", err)
+	}
+	if err := os.Rename(tmpPath, k.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename keyring: %w", err)
+	}
+
+	return nil
+}
+
+// parseKeys reads every key from armored blocks in free text, or from a
+// binary keyring. Blocks that can't be parsed (e.g. obsolete v3 keys found in
+// old KEYS files) are skipped.
+func parseKeys(data []byte) (openpgp.EntityList, error) {
+	text := string(data)
+	if !strings.Contains(text, armorBegin) {
+		return openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+
+	var entities openpgp.EntityList
+	var lastErr error
+	blocks := strings.Split(text, armorBegin)[1:]
+	for _, block := range blocks {
+		list, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armorBegin + block))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		entities = append(entities, list...)
+	}
+
+	if len(entities) == 0 && lastErr != nil {
+		return nil, fmt.Errorf("parse keys: %w", lastErr)
+	}
+	return entities, nil
+}
+
+// describe summarizes an entity for display
+func describe(e *openpgp.Entity) Key {
+	key := Key{
+		KeyID:       e.PrimaryKey.KeyIdString(),
+		Fingerprint: fingerprint(e),
+		Created:     e.PrimaryKey.CreationTime,
+	}
+	for name := range e.Identities {
+		key.UserIDs = append(key.UserIDs, name)
+	}
+	sort.Strings(key.UserIDs)
+	return key
+}
+
+// fingerprint returns the upper-case hex fingerprint of an entity
+func fingerprint(e *openpgp.Entity) string {
+	return strings.ToUpper(hex.EncodeToString(e.PrimaryKey.Fingerprint))
+}
@@ -0,0 +1,198 @@
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// Policy decides what happens when an archive's signature can't be verified
+type Policy string
+
+const (
+	// PolicyRequire refuses archives without a valid signature
+	PolicyRequire Policy = "require"
+
+	// PolicyWarn installs archives without a valid signature after a warning
+	PolicyWarn Policy = "warn"
+
+	// PolicyOff skips signature verification
+	PolicyOff Policy = "off"
+)
+
+// DefaultPolicy is used when the configuration doesn't set one
+const DefaultPolicy = PolicyWarn
+
+// ParsePolicy validates a policy name; an empty name means DefaultPolicy
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(name); p {
+	case "":
+		return DefaultPolicy, nil
+	case PolicyRequire, PolicyWarn, PolicyOff:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid signature policy '%s' (use require, warn or off)", name)
+	}
+}
+
+// Status is the outcome of verifying an archive's signature
+type Status string
+
+const (
+	// StatusValid means the signature verified against a key in the keyring
+	StatusValid Status = "valid"
+
+	// StatusInvalid means the signature doesn't match the archive
+	StatusInvalid Status = "invalid"
+
+	// StatusUnknownKey means the signing key is not in the keyring
+	StatusUnknownKey Status = "unknown-key"
+
+	// StatusMissing means no signature is published for the archive
+	StatusMissing Status = "missing"
+)
+
+// Result describes a signature verification
+type Result struct {
+	Status Status `json:"status"`
+	KeyID  string `json:"key_id,omitempty"`
+	Signer string `json:"signer,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// String formats the result for display
+func (r Result) String() string {
+	switch r.Status {
+	case StatusValid:
+		return fmt.Sprintf("valid signature from %s (key %s)", r.Signer, r.KeyID)
+	case StatusUnknownKey:
+		return fmt.Sprintf("signed by key %s, which is not in the keyring", r.KeyID)
+	case StatusMissing:
+		if r.Detail != "" {
+			return "no signature available: " + r.Detail
+		}
+		return "no signature available"
+	default:
+		return fmt.Sprintf("invalid signature: %s", r.Detail)
+	}
+}
+
+// Verify checks an armored detached signature of the file at path. Key
+// expiry is judged as of the signature's creation time, so releases signed
+// before a key expired still verify, but revocations are judged now: a key
+// revoked after the fact no longer vouches for anything, including
+// signatures backdated to before the revocation.
+func (k *Keyring) Verify(path string, sig []byte) Result {
+	entities, err := k.load()
+	if err != nil {
+		return Result{Status: StatusInvalid, Detail: err.Error()}
+	}
+
+	sigPacket, err := readSignature(sig)
+	if err != nil {
+		return Result{Status: StatusInvalid, Detail: err.Error()}
+	}
+
+	result := Result{}
+	if sigPacket.IssuerKeyId != nil {
+		result.KeyID = fmt.Sprintf("%016X", *sigPacket.IssuerKeyId)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		result.Status = StatusInvalid
+		result.Detail = err.Error()
+		return result
+	}
+	defer f.Close()
+
+	block, err := armor.Decode(bytes.NewReader(sig))
+	if err != nil {
+		result.Status = StatusInvalid
+		result.Detail = err.Error()
+		return result
+	}
+
+	signedAt := sigPacket.CreationTime
+	config := &packet.Config{Time: func() time.Time { return signedAt }}
+
+	signer, err := openpgp.CheckDetachedSignature(entities, f, block.Body, config)
+	switch {
+	case err == nil && sigPacket.IssuerKeyId != nil && revoked(entities, *sigPacket.IssuerKeyId, time.Now()):
+		result.Status = StatusInvalid
+		result.Detail = fmt.Sprintf("key %s has been revoked", result.KeyID)
+	case err == nil:
+		result.Status = StatusValid
+		result.Signer = primaryName(signer)
+	case errors.Is(err, pgperrors.ErrUnknownIssuer):
+		result.Status = StatusUnknownKey
+	default:
+		result.Status = StatusInvalid
+		result.Detail = err.Error()
+	}
+
+	return result
+}
+
+// Check applies a policy to a verification result. Under PolicyWarn a
+// warning is printed and nil returned.
+func Check(policy Policy, result Result, what string) error {
+	if policy == PolicyOff || result.Status == StatusValid {
+		return nil
+	}
+
+	if policy == PolicyRequire {
+		return fmt.Errorf("%s: %s (signature policy is 'require')", what, result)
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", what, result)
+	if result.Status == StatusUnknownKey {
+		fmt.Fprintln(os.Stderr, "Import the publisher's keys with: mvnenv keys import")
+	}
+	return nil
+}
+
+// readSignature parses the signature packet of an armored signature
+func readSignature(sig []byte) (*packet.Signature, error) {
+	block, err := armor.Decode(bytes.NewReader(sig))
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+
+	p, err := packet.Read(block.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read signature: %w", err)
+	}
+
+	sigPacket, ok := p.(*packet.Signature)
+	if !ok {
+		return nil, fmt.Errorf("not a signature")
+	}
+	return sigPacket, nil
+}
+
+// revoked reports whether the key with the given ID, or the entity it
+// belongs to, is revoked at now
+func revoked(entities openpgp.EntityList, keyID uint64, now time.Time) bool {
+	for _, key := range entities.KeysById(keyID) {
+		if key.Entity.Revoked(now) || key.Revoked(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryName returns the primary user ID of an entity
+func primaryName(e *openpgp.Entity) string {
+	if id := e.PrimaryIdentity(); id != nil {
+		return id.Name
+	}
+	return e.PrimaryKey.KeyIdString()
+}
@@ -0,0 +1,138 @@
+package signature
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// at returns a config whose clock is fixed at t
+func at(t time.Time) *packet.Config {
+	return &packet.Config{Time: func() time.Time { return t }}
+}
+
+// newKey generates a signing key created at created, valid for lifetime
+// (zero means it never expires)
+func newKey(t *testing.T, created time.Time, lifetime time.Duration) *openpgp.Entity {
+	t.Helper()
+	config := at(created)
+	config.KeyLifetimeSecs = uint32(lifetime / time.Second)
+	e, err := openpgp.NewEntity("Release Manager", "", "rm@example.org", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// trust imports the public part of e into a fresh keyring
+func trust(t *testing.T, e *openpgp.Entity) *Keyring {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	keyring := NewKeyring(t.TempDir())
+	if _, _, err := keyring.Import(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+// sign writes an archive and returns its path and a detached signature made
+// by e at signedAt
+func sign(t *testing.T, e *openpgp.Entity, signedAt time.Time) (string, []byte) {
+	t.Helper()
+	content := []byte("apache-maven-3.9.6-bin.zip")
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, e, bytes.NewReader(content), at(signedAt)); err != nil {
+		t.Fatal(err)
+	}
+	return path, sig.Bytes()
+}
+
+func TestVerifyValid(t *testing.T) {
+	now := time.Now()
+	key := newKey(t, now.Add(-48*time.Hour), 0)
+	path, sig := sign(t, key, now.Add(-24*time.Hour))
+
+	result := trust(t, key).Verify(path, sig)
+	if result.Status != StatusValid {
+		t.Fatalf("Verify() = %v, want valid", result)
+	}
+	if want := "Release Manager <rm@example.org>"; result.Signer != want {
+		t.Errorf("Signer = %q, want %q", result.Signer, want)
+	}
+}
+
+func TestVerifyModifiedArchive(t *testing.T) {
+	now := time.Now()
+	key := newKey(t, now.Add(-48*time.Hour), 0)
+	path, sig := sign(t, key, now.Add(-24*time.Hour))
+	if err := os.WriteFile(path, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := trust(t, key).Verify(path, sig); result.Status != StatusInvalid {
+		t.Fatalf("Verify() = %v, want invalid", result)
+	}
+}
+
+func TestVerifyUnknownKey(t *testing.T) {
+	now := time.Now()
+	key := newKey(t, now.Add(-48*time.Hour), 0)
+	other := newKey(t, now.Add(-48*time.Hour), 0)
+	path, sig := sign(t, key, now.Add(-24*time.Hour))
+
+	if result := trust(t, other).Verify(path, sig); result.Status != StatusUnknownKey {
+		t.Fatalf("Verify() = %v, want unknown-key", result)
+	}
+}
+
+// A signature made while the key was valid still verifies after the key
+// expired
+func TestVerifyExpiredKey(t *testing.T) {
+	now := time.Now()
+	key := newKey(t, now.Add(-30*24*time.Hour), 10*24*time.Hour)
+
+	path, sig := sign(t, key, now.Add(-25*24*time.Hour))
+
+	if result := trust(t, key).Verify(path, sig); result.Status != StatusValid {
+		t.Fatalf("Verify() = %v, want valid", result)
+	}
+}
+
+// A revoked key verifies nothing, not even signatures dated before the
+// revocation
+func TestVerifyRevokedKey(t *testing.T) {
+	now := time.Now()
+	key := newKey(t, now.Add(-30*24*time.Hour), 0)
+	path, sig := sign(t, key, now.Add(-25*24*time.Hour))
+
+	if err := key.RevokeKey(packet.KeyRetired, "retired", at(now.Add(-20*24*time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	result := trust(t, key).Verify(path, sig)
+	if result.Status != StatusInvalid {
+		t.Fatalf("Verify() = %v, want invalid", result)
+	}
+}
@@ -0,0 +1,80 @@
+package tool
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veenone/mvnenv-win/internal/config"
+)
+
+// LoadConfigured registers the tools declared under "tools" in config.yaml
+func LoadConfigured(mvnenvRoot string) error {
+	cfg, err := config.NewManager(mvnenvRoot).Load()
+	if err != nil {
+		return err
+	}
+
+	for _, tc := range cfg.Tools {
+		if err := Register(FromConfig(tc)); err != nil {
+			return fmt.Errorf("tools: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FromConfig builds a definition from its config.yaml declaration, filling in
+// conventional defaults: tools/<name>/versions, .<name>-version,
+// MVNENV_<NAME>_VERSION, .cmd launchers and <NAME>_HOME.
+func FromConfig(tc config.ToolConfig) *Definition {
+	name := strings.ToLower(tc.Name)
+	envName := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+
+	def := &Definition{
+		Name:            name,
+		DisplayName:     tc.DisplayName,
+		GroupID:         tc.GroupID,
+		ArtifactID:      tc.ArtifactID,
+		Archive:         tc.Archive,
+		NexusArchive:    tc.NexusArchive,
+		UpstreamName:    tc.UpstreamName,
+		DownloadURL:     tc.DownloadURL,
+		SignatureSuffix: tc.SignatureSuffix,
+		KeysURL:         tc.KeysURL,
+		ListFormat:      tc.ListFormat,
+		ListPattern:     tc.ListPattern,
+		VersionsDir:     ToolVersionsDir(name),
+		VersionFile:     tc.VersionFile,
+		VersionEnv:      tc.VersionEnv,
+		Launchers:       tc.Launchers,
+		LauncherExt:     tc.LauncherExt,
+		HomeEnv:         tc.HomeEnv,
+	}
+
+	if tc.ListURL != "" {
+		def.ListURLs = []string{tc.ListURL}
+	}
+	if def.DisplayName == "" {
+		def.DisplayName = tc.Name
+	}
+	if def.UpstreamName == "" {
+		def.UpstreamName = "upstream"
+	}
+	if def.ListFormat == "" {
+		def.ListFormat = ListFormatHTML
+	}
+	if def.VersionFile == "" {
+		def.VersionFile = "." + name + "-version"
+	}
+	if def.VersionEnv == "" {
+		def.VersionEnv = "MVNENV_" + envName + "_VERSION"
+	}
+	if def.LauncherExt == "" {
+		def.LauncherExt = ".cmd"
+	}
+	if len(def.HomeEnv) == 0 {
+		def.HomeEnv = map[string]string{envName + "_HOME": ""}
+	}
+
+	return def
+}
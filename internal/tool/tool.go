@@ -0,0 +1,393 @@
+package tool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tool names
+const (
+	NameMaven = "maven"
+	NameMvnd  = "mvnd"
+)
+
+// List formats understood by repository sources
+const (
+	// ListFormatHTML scrapes a directory listing page with ListPattern
+	ListFormatHTML = "html"
+
+	// ListFormatGitHub reads tag names from the GitHub releases API
+	ListFormatGitHub = "github"
+)
+
+// Definition declaratively describes a tool managed by mvnenv: where its
+// distributions come from, how they are named, and how installations are laid
+// out and selected. Templates may use {version} and {platform}.
+type Definition struct {
+	// Name identifies the tool on the command line (--tool) and in config
+	Name string
+
+	// DisplayName is used in user-facing messages
+	DisplayName string
+
+	// GroupID and ArtifactID are the coordinates of the distribution in a
+	// Maven repository such as Nexus
+	GroupID    string
+	ArtifactID string
+
+	// Archive is the distribution file name, also used in the download cache
+	Archive string
+
+	// NexusArchive is the file name in Maven repositories when it differs
+	// from Archive
+	NexusArchive string
+
+	// UpstreamName describes the public source in messages
+	UpstreamName string
+
+	// DownloadURL is the public download location of a version. Like the
+	// other templates it may use {version}, {major} (the version's first
+	// number, e.g. "4" for 4.0.0-rc-2) and {platform}.
+	DownloadURL string
+
+	// SignatureSuffix is appended to the download URL of an archive, from
+	// any source, to fetch its detached OpenPGP signature (e.g. ".asc")
+	SignatureSuffix string
+
+	// KeysURL publishes the keys that sign the tool's releases, imported
+	// with "mvnenv keys import"
+	KeysURL string
+
+	// ListURLs, ListFormat and ListPattern describe how to discover public
+	// versions: every URL is listed in the same format and the versions
+	// combined, for sources with a directory per major version. ListPattern
+	// is a regular expression whose first group is the version, used with
+	// ListFormatHTML.
+	ListURLs    []string
+	ListFormat  string
+	ListPattern string
+
+	// VersionsDir is where installations live, relative to MVNENV_ROOT
+	VersionsDir string
+
+	// VersionFile is the per-project version file searched for upwards
+	VersionFile string
+
+	// VersionEnv is the environment variable that sets the shell version
+	VersionEnv string
+
+	// Launchers are the commands shimmed for this tool. The first one must
+	// exist in bin/ for an installation to be considered valid.
+	Launchers []string
+
+	// LauncherExt is the extension of launcher scripts in bin/ (".cmd")
+	LauncherExt string
+
+	// HomeEnv maps environment variables to directories relative to the
+	// installation root ("" is the root itself)
+	HomeEnv map[string]string
+}
+
+// Maven is the built-in Apache Maven definition
+var Maven = &Definition{
+	Name:            NameMaven,
+	DisplayName:     "Maven",
+	GroupID:         "org.apache.maven",
+	ArtifactID:      "apache-maven",
+	Archive:         "apache-maven-{version}-bin.zip",
+	UpstreamName:    "Apache archive",
+	DownloadURL:     "https://archive.apache.org/dist/maven/maven-{major}/{version}/binaries/apache-maven-{version}-bin.zip",
+	SignatureSuffix: ".asc",
+	KeysURL:         "https://downloads.apache.org/maven/KEYS",
+	ListURLs: []string{
+		"https://archive.apache.org/dist/maven/maven-3/",
+		"https://archive.apache.org/dist/maven/maven-4/",
+	},
+	ListFormat:      ListFormatHTML,
+	ListPattern:     `<a href="(\d+\.\d+\.\d+(?:-[^"/]+)?)/">`,
+	VersionsDir:     "versions",
+	VersionFile:     ".maven-version",
+	VersionEnv:      "MVNENV_MAVEN_VERSION",
+	Launchers:       []string{"mvn", "mvnDebug"},
+	LauncherExt:     ".cmd",
+	HomeEnv:         map[string]string{"MAVEN_HOME": ""},
+}
+
+// Mvnd is the built-in Maven Daemon definition. mvnd ships its own Maven in
+// mvn/, so MAVEN_HOME points there to keep it consistent with the daemon.
+var Mvnd = &Definition{
+	Name:            NameMvnd,
+	DisplayName:     "mvnd",
+	GroupID:         "org.apache.maven.daemon",
+	ArtifactID:      "mvnd",
+	Archive:         "maven-mvnd-{version}-{platform}.zip",
+	NexusArchive:    "mvnd-{version}-{platform}.zip",
+	UpstreamName:    "GitHub releases",
+	DownloadURL:     "https://github.com/apache/maven-mvnd/releases/download/{version}/maven-mvnd-{version}-{platform}.zip",
+	SignatureSuffix: ".asc",
+	KeysURL:         "https://downloads.apache.org/maven/KEYS",
+	ListURLs:        []string{"https://api.github.com/repos/apache/maven-mvnd/releases?per_page=100"},
+	ListFormat:      ListFormatGitHub,
+	VersionsDir:     ToolVersionsDir(NameMvnd),
+	VersionFile:     ".mvnd-version",
+	VersionEnv:      "MVNENV_MVND_VERSION",
+	Launchers:       []string{"mvnd"},
+	LauncherExt:     ".cmd",
+	HomeEnv:         map[string]string{"MVND_HOME": "", "MAVEN_HOME": "mvn"},
+}
+
+var (
+	registryMu  sync.RWMutex
+	definitions = map[string]*Definition{
+		NameMaven: Maven,
+		NameMvnd:  Mvnd,
+	}
+)
+
+// Register adds or replaces a tool definition. Built-in tools cannot be
+// replaced, and a launcher can belong to only one tool.
+func Register(def *Definition) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	name := strings.ToLower(def.Name)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if existing, ok := definitions[name]; ok && (existing == Maven || existing == Mvnd) {
+		return fmt.Errorf("tool '%s' is built in and cannot be redefined", def.Name)
+	}
+	for otherName, other := range definitions {
+		if otherName == name {
+			continue
+		}
+		for _, launcher := range def.Launchers {
+			if other.hasLauncher(launcher) {
+				return fmt.Errorf("tool '%s' declares launcher '%s', which belongs to '%s'", def.Name, launcher, other.Name)
+			}
+		}
+	}
+	definitions[name] = def
+	return nil
+}
+
+// Get returns the definition for a tool name. An empty name means Maven.
+func Get(name string) (*Definition, error) {
+	if name == "" {
+		return Maven, nil
+	}
+
+	registryMu.RLock()
+	def, ok := definitions[strings.ToLower(name)]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown tool '%s' (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return def, nil
+}
+
+// Names returns the names of all known tools, sorted
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// All returns all known tool definitions, Maven first
+func All() []*Definition {
+	defs := []*Definition{Maven}
+	for _, name := range Names() {
+		if name == NameMaven {
+			continue
+		}
+		def, _ := Get(name)
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// ForCommand returns the tool that owns a shimmed command. Commands not
+// declared as a launcher by another tool belong to Maven, which also covers
+// extra scripts such as mvnyjp discovered in Maven's bin directory. Register
+// keeps launchers unique; tools are still searched in the stable order of
+// All.
+func ForCommand(command string) *Definition {
+	for _, def := range All() {
+		if def != Maven && def.hasLauncher(command) {
+			return def
+		}
+	}
+	return Maven
+}
+
+// hasLauncher reports whether a command is one of the tool's launchers
+func (d *Definition) hasLauncher(command string) bool {
+	for _, launcher := range d.Launchers {
+		if strings.EqualFold(launcher, command) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBuiltinCommand reports whether a command is a launcher of a built-in tool,
+// i.e. can be mapped to its tool without loading configured tools
+func IsBuiltinCommand(command string) bool {
+	for _, def := range []*Definition{Maven, Mvnd} {
+		for _, launcher := range def.Launchers {
+			if strings.EqualFold(launcher, command) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsMaven reports whether the definition is the built-in Maven tool
+func (d *Definition) IsMaven() bool {
+	return d.Name == NameMaven
+}
+
+// ArchiveName returns the distribution file name of a version
+func (d *Definition) ArchiveName(version string) string {
+	return d.expand(d.Archive, version)
+}
+
+// NexusArchiveName returns the distribution file name of a version in Maven
+// repositories
+func (d *Definition) NexusArchiveName(version string) string {
+	if d.NexusArchive == "" {
+		return d.ArchiveName(version)
+	}
+	return d.expand(d.NexusArchive, version)
+}
+
+// GroupPath returns the group ID as a repository path ("org/apache/maven")
+func (d *Definition) GroupPath() string {
+	return strings.ReplaceAll(d.GroupID, ".", "/")
+}
+
+// DownloadURLFor returns the public download URL of a version
+func (d *Definition) DownloadURLFor(version string) string {
+	return d.expand(d.DownloadURL, version)
+}
+
+// ToolVersionsDir returns where a tool other than Maven keeps its
+// installations, relative to MVNENV_ROOT: tools/<name>/versions, beside
+// Maven's versions/ so tool names and Maven versions can't clash
+func ToolVersionsDir(name string) string {
+	return filepath.Join("tools", name, "versions")
+}
+
+// MigrateInstallations moves installations of tools other than Maven from
+// versions/<name>, where earlier releases kept them, to their own
+// directory. A tool whose new directory already exists, or a Maven version
+// that happens to share its name, is left alone.
+func MigrateInstallations(mvnenvRoot string) error {
+	for _, def := range All() {
+		if def.IsMaven() {
+			continue
+		}
+
+		legacy := filepath.Join(mvnenvRoot, Maven.VersionsDir, def.Name)
+		if info, err := os.Stat(legacy); err != nil || !info.IsDir() || Maven.IsValidInstallation(legacy) {
+			continue
+		}
+		versionsPath := def.VersionsPath(mvnenvRoot)
+		if _, err := os.Stat(versionsPath); !os.IsNotExist(err) {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(versionsPath), 0755); err != nil {
+			return fmt.Errorf("create %s directory: %w", def.DisplayName, err)
+		}
+		// Another process may have moved it first
+		if err := os.Rename(legacy, versionsPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("move %s installations to %s: %w", def.DisplayName, versionsPath, err)
+		}
+	}
+	return nil
+}
+
+// VersionsPath returns the directory holding all installations of the tool
+func (d *Definition) VersionsPath(mvnenvRoot string) string {
+	return filepath.Join(mvnenvRoot, d.VersionsDir)
+}
+
+// InstallPath returns the installation directory for a version
+func (d *Definition) InstallPath(mvnenvRoot, version string) string {
+	return filepath.Join(d.VersionsPath(mvnenvRoot), version)
+}
+
+// LauncherPath returns the path of a launcher script inside an installation
+func (d *Definition) LauncherPath(home, launcher string) string {
+	ext := d.LauncherExt
+	if ext == "" {
+		ext = ".cmd"
+	}
+	return filepath.Join(home, "bin", launcher+ext)
+}
+
+// IsValidInstallation reports whether home contains the tool's primary launcher
+func (d *Definition) IsValidInstallation(home string) bool {
+	_, err := os.Stat(d.LauncherPath(home, d.Launchers[0]))
+	return err == nil
+}
+
+// HomeEnvironment returns the tool's home variables for an installation
+func (d *Definition) HomeEnvironment(home string) map[string]string {
+	env := make(map[string]string, len(d.HomeEnv))
+	for name, rel := range d.HomeEnv {
+		if rel == "" {
+			env[name] = home
+		} else {
+			env[name] = filepath.Join(home, rel)
+		}
+	}
+	return env
+}
+
+// expand substitutes {version} and {platform} in a template
+func (d *Definition) expand(template, version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return strings.NewReplacer("{version}", version, "{major}", major, "{platform}", Platform()).Replace(template)
+}
+
+// validate checks that a definition has the fields every pipeline stage needs
+func (d *Definition) validate() error {
+	switch {
+	case d.Name == "":
+		return fmt.Errorf("tool definition is missing a name")
+	case len(d.Launchers) == 0:
+		return fmt.Errorf("tool '%s' declares no launchers", d.Name)
+	case d.Archive == "":
+		return fmt.Errorf("tool '%s' declares no archive name", d.Name)
+	case d.DownloadURL == "" && (d.GroupID == "" || d.ArtifactID == ""):
+		return fmt.Errorf("tool '%s' needs a download URL or Nexus coordinates", d.Name)
+	}
+	return nil
+}
+
+// Platform returns the platform classifier used in archive names, such as
+// "windows-amd64" or "darwin-aarch64"
+func Platform() string {
+	arch := runtime.GOARCH
+	if arch == "arm64" {
+		arch = "aarch64"
+	}
+	return runtime.GOOS + "-" + arch
+}
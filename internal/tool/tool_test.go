@@ -0,0 +1,71 @@
+package tool
+
+import "testing"
+
+func TestMavenDownloadURLFor(t *testing.T) {
+	tests := map[string]string{
+		"3.9.6":      "https://archive.apache.org/dist/maven/maven-3/3.9.6/binaries/apache-maven-3.9.6-bin.zip",
+		"4.0.0-rc-2": "https://archive.apache.org/dist/maven/maven-4/4.0.0-rc-2/binaries/apache-maven-4.0.0-rc-2-bin.zip",
+	}
+
+	for version, want := range tests {
+		if got := Maven.DownloadURLFor(version); got != want {
+			t.Errorf("DownloadURLFor(%s) = %s, want %s", version, got, want)
+		}
+	}
+}
+
+// register registers a tool for the duration of a test
+func register(t *testing.T, def *Definition) error {
+	t.Helper()
+	err := Register(def)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		if definitions[def.Name] == def {
+			delete(definitions, def.Name)
+		}
+	})
+	return err
+}
+
+func testTool(name string, launchers ...string) *Definition {
+	return &Definition{Name: name, Archive: name + "-{version}.zip", DownloadURL: "https://example.org/" + name, Launchers: launchers}
+}
+
+func TestRegisterRejectsSharedLaunchers(t *testing.T) {
+	if err := register(t, testTool("ant", "ant")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, def := range []*Definition{testTool("ant2", "ANT"), testTool("mymvn", "mvn"), testTool("daemon", "mvnd")} {
+		if err := register(t, def); err == nil {
+			t.Errorf("Register(%s) with launchers %v succeeded", def.Name, def.Launchers)
+		}
+	}
+
+	// Redefining a tool may keep its own launchers
+	if err := register(t, testTool("ant", "ant", "antRun")); err != nil {
+		t.Errorf("redefining ant: %v", err)
+	}
+}
+
+func TestForCommand(t *testing.T) {
+	gradle := testTool("gradle", "gradle")
+	if err := register(t, gradle); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]*Definition{
+		"mvn":      Maven,
+		"mvnDebug": Maven,
+		"mvnyjp":   Maven,
+		"mvnd":     Mvnd,
+		"Gradle":   gradle,
+	}
+	for command, want := range tests {
+		if got := ForCommand(command); got != want {
+			t.Errorf("ForCommand(%s) = %s, want %s", command, got.Name, want.Name)
+		}
+	}
+}
@@ -103,11 +103,16 @@ func IsInvalidVersionError(err error) bool {
 }
 
 // ExtractVersionFromError extracts the version string from a VersionNotInstalledError
+// or a resolution VersionError
 func ExtractVersionFromError(err error) string {
 	var vErr *VersionNotInstalledError
 	if errors.As(err, &vErr) {
 		return vErr.Version
 	}
+	var resErr *VersionError
+	if errors.As(err, &resErr) {
+		return resErr.Version
+	}
 	return ""
 }
 
@@ -0,0 +1,376 @@
+package version
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/cache"
+	"github.com/veenone/mvnenv-win/internal/integrity"
+	"github.com/veenone/mvnenv-win/internal/progress"
+	"github.com/veenone/mvnenv-win/internal/tool"
+)
+
+// ExtensionsDir is where Maven loads core extensions from, relative to an
+// installation
+const ExtensionsDir = "lib/ext"
+
+// ExtensionStorePrefix marks extension jars in the archive store, where
+// they are indexed as "ext:<groupId>:<artifactId>" and version
+const ExtensionStorePrefix = "ext:"
+
+// coordinatePattern matches one part of Maven coordinates
+var coordinatePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Extension is a core extension jar added to an installation by
+// "mvnenv ext add"
+type Extension struct {
+	GroupID    string `json:"group_id"`
+	ArtifactID string `json:"artifact_id"`
+	Version    string `json:"version"`
+
+	// File is the jar's slash-separated path in the installation
+	File      string            `json:"file"`
+	Source    string            `json:"source"`
+	SHA512    string            `json:"sha512"`
+	Integrity *integrity.Result `json:"integrity,omitempty"`
+	AddedAt   time.Time         `json:"added_at"`
+}
+
+// ParseExtension parses "groupId:artifactId:version". Without
+// requireVersion, "groupId:artifactId" is accepted as well.
+func ParseExtension(coords string, requireVersion bool) (*Extension, error) {
+	parts, valid := splitCoordinates(coords, requireVersion)
+	if !valid {
+		if requireVersion {
+			return nil, fmt.Errorf("invalid extension '%s' (use groupId:artifactId:version)", coords)
+		}
+		return nil, fmt.Errorf("invalid extension '%s' (use groupId:artifactId[:version])", coords)
+	}
+
+	ext := &Extension{GroupID: parts[0], ArtifactID: parts[1]}
+	if len(parts) == 3 {
+		ext.Version = parts[2]
+		ext.File = ExtensionsDir + "/" + ext.fileName()
+	}
+	return ext, nil
+}
+
+// splitCoordinates splits "groupId:artifactId:version", or without
+// requireVersion also "groupId:artifactId", and reports whether it is valid
+func splitCoordinates(coords string, requireVersion bool) ([]string, bool) {
+	parts := strings.Split(strings.TrimSpace(coords), ":")
+	valid := len(parts) == 3 || (len(parts) == 2 && !requireVersion)
+	for _, part := range parts {
+		if !coordinatePattern.MatchString(part) || strings.Contains(part, "..") {
+			valid = false
+		}
+	}
+	return parts, valid
+}
+
+// GAV returns the extension's coordinates, without the version if unset
+func (e *Extension) GAV() string {
+	if e.Version == "" {
+		return e.GroupID + ":" + e.ArtifactID
+	}
+	return e.GroupID + ":" + e.ArtifactID + ":" + e.Version
+}
+
+// fileName returns the name of the extension's jar
+func (e *Extension) fileName() string {
+	return e.ArtifactID + "-" + e.Version + ".jar"
+}
+
+// storeTool returns the name the jar is indexed under in the archive store
+func (e *Extension) storeTool() string {
+	return ExtensionStorePrefix + e.GroupID + ":" + e.ArtifactID
+}
+
+// matches reports whether e is the extension other names; other's version
+// is ignored when unset
+func (e *Extension) matches(other *Extension) bool {
+	return e.GroupID == other.GroupID && e.ArtifactID == other.ArtifactID &&
+		(other.Version == "" || e.Version == other.Version)
+}
+
+// ListExtensions returns the extensions recorded for an installation
+func ListExtensions(installPath string) ([]Extension, error) {
+	manifest, err := ReadManifest(installPath)
+	if err != nil || manifest == nil {
+		return nil, err
+	}
+	return manifest.Extensions, nil
+}
+
+// IsStoredArtifact reports whether an archive store entry is an extension
+// jar or overlay archive rather than a tool distribution
+func IsStoredArtifact(storeTool string) bool {
+	return strings.HasPrefix(storeTool, ExtensionStorePrefix) || strings.HasPrefix(storeTool, OverlayStorePrefix)
+}
+
+// ArtifactInUse reports whether an extension jar in the archive store is
+// used by an installed Maven version, or an overlay archive is configured
+func ArtifactInUse(mvnenvRoot, storeTool, version string) bool {
+	if strings.HasPrefix(storeTool, OverlayStorePrefix) {
+		return overlayConfigured(mvnenvRoot, storeTool, version)
+	}
+
+	installed, err := NewToolLister(mvnenvRoot, tool.Maven).ListInstalled()
+	if err != nil {
+		return false
+	}
+	for _, v := range installed {
+		extensions, _ := ListExtensions(tool.Maven.InstallPath(mvnenvRoot, v))
+		for _, ext := range extensions {
+			if ext.storeTool() == storeTool && ext.Version == version {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddExtension fetches an extension jar from Nexus, verifies it and places
+// it in lib/ext of an installed version, replacing other versions of the
+// same extension. It is recorded in the manifest so that reinstalling or
+// repairing the version puts it back.
+func (i *VersionInstaller) AddExtension(ctx context.Context, version string, ext *Extension) (*Extension, error) {
+	fileLock, err := i.lockVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Release()
+
+	installPath, manifest, err := i.installedManifest(version)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := i.applyExtension(ctx, installPath, manifest, *ext)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeManifest(installPath, manifest); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveExtension removes an extension from an installed version. ext may
+// leave the version unset. The removed extension is returned, or nil if the
+// version doesn't have it.
+func (i *VersionInstaller) RemoveExtension(version string, ext *Extension) (*Extension, error) {
+	fileLock, err := i.lockVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Release()
+
+	installPath, manifest, err := i.installedManifest(version)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, existing := range manifest.Extensions {
+		if !existing.matches(ext) {
+			continue
+		}
+		if err := removeExtension(installPath, manifest, existing); err != nil {
+			return nil, err
+		}
+		if err := writeManifest(installPath, manifest); err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	return nil, nil
+}
+
+// installedManifest returns the path and manifest of an installed version
+func (i *VersionInstaller) installedManifest(version string) (string, *Manifest, error) {
+	installPath := i.tool.InstallPath(i.mvnenvRoot, version)
+	if !i.tool.IsValidInstallation(installPath) {
+		return "", nil, fmt.Errorf("%s %s is not installed", i.tool.DisplayName, version)
+	}
+
+	manifest, err := ReadManifest(installPath)
+	if err != nil {
+		return "", nil, err
+	}
+	if manifest == nil {
+		return "", nil, fmt.Errorf("%s %s has no manifest to record changes in (reinstall with 'mvnenv install --force %s')",
+			i.tool.DisplayName, version, version)
+	}
+	return installPath, manifest, nil
+}
+
+// applyExtension copies an extension jar into an installation and records
+// it in the manifest, which the caller writes
+func (i *VersionInstaller) applyExtension(ctx context.Context, installPath string, manifest *Manifest, ext Extension) (*Extension, error) {
+	entry, jarPath, err := i.fetchExtension(ctx, &ext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only one version of an extension may be loaded
+	var replaced []Extension
+	for _, existing := range manifest.Extensions {
+		if existing.GroupID == ext.GroupID && existing.ArtifactID == ext.ArtifactID {
+			replaced = append(replaced, existing)
+		}
+	}
+	for _, existing := range replaced {
+		if err := removeExtension(installPath, manifest, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	ext.File = ExtensionsDir + "/" + ext.fileName()
+	destPath := filepath.Join(installPath, filepath.FromSlash(ext.File))
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return nil, fmt.Errorf("create %s: %w", ExtensionsDir, err)
+	}
+	if err := copyExtension(jarPath, destPath); err != nil {
+		return nil, fmt.Errorf("copy %s: %w", ext.fileName(), err)
+	}
+
+	if manifest.Files != nil {
+		sum, err := fileSHA256(destPath)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files[ext.File] = sum
+	}
+
+	ext.Source = entry.Source
+	ext.SHA512 = entry.SHA512
+	ext.Integrity = entry.Integrity
+	ext.AddedAt = time.Now().UTC()
+	manifest.Extensions = append(manifest.Extensions, ext)
+	return &ext, nil
+}
+
+// removeExtension deletes an extension jar and drops it from the manifest
+func removeExtension(installPath string, manifest *Manifest, ext Extension) error {
+	path := filepath.Join(installPath, filepath.FromSlash(ext.File))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove %s: %w", ext.File, err)
+	}
+	delete(manifest.Files, ext.File)
+
+	var kept []Extension
+	for _, e := range manifest.Extensions {
+		if e.File != ext.File {
+			kept = append(kept, e)
+		}
+	}
+	manifest.Extensions = kept
+	return nil
+}
+
+// reapplyExtensions adds the extensions of a replaced installation to its
+// reinstall. Failures are reported but don't fail the install.
+func (i *VersionInstaller) reapplyExtensions(ctx context.Context, installPath string, manifest *Manifest, extensions []Extension) {
+	for _, ext := range extensions {
+		if _, err := i.applyExtension(ctx, installPath, manifest, ext); err != nil {
+			fmt.Printf("Warning: Could not reapply extension %s: %v\n", ext.GAV(), err)
+			continue
+		}
+		if !i.quiet {
+			fmt.Printf("Reapplied extension %s\n", ext.GAV())
+		}
+	}
+}
+
+// fetchExtension returns the store entry and path of an extension jar,
+// downloading it from Nexus unless the archive store holds it
+func (i *VersionInstaller) fetchExtension(ctx context.Context, ext *Extension) (*cache.ArchiveEntry, string, error) {
+	return i.fetchArtifact(ctx, ext.storeTool(), ext.GroupID, ext.ArtifactID, ext.Version, ext.fileName())
+}
+
+// fetchArtifact returns the store entry and path of a zip or jar artifact
+// kept under storeTool in the archive store, downloading it from Nexus
+// unless the store holds it
+func (i *VersionInstaller) fetchArtifact(ctx context.Context, storeTool, groupID, artifactID, version, fileName string) (*cache.ArchiveEntry, string, error) {
+	store := cache.NewArchiveStore(i.mvnenvRoot)
+	gav := groupID + ":" + artifactID + ":" + version
+
+	entry, path, err := store.Lookup(storeTool, version)
+	if err != nil && !i.quiet {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if entry != nil {
+		return entry, path, nil
+	}
+
+	downloadPath, err := store.DownloadPath(storeTool, version)
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(downloadPath)
+
+	task := i.reporter.Start(progress.KindDownload, fileName)
+	dl, err := i.repoManager.DownloadArtifact(progress.WithTask(ctx, task), groupID, artifactID, version, fileName, downloadPath, task.Update)
+	if err == nil {
+		// Anything but a zip, such as an HTML error page, is refused
+		err = checkZip(downloadPath)
+	}
+	if err != nil {
+		task.Fail(err)
+	} else {
+		task.Done("")
+	}
+	if ctx.Err() != nil {
+		return nil, "", ctx.Err()
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("fetch %s: %w", gav, err)
+	}
+
+	entry, path, err = store.Add(cache.ArchiveEntry{
+		Tool:      storeTool,
+		Version:   version,
+		Name:      fileName,
+		Source:    dl.Source,
+		Integrity: dl.Integrity,
+	}, downloadPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("cache %s: %w", fileName, err)
+	}
+	return entry, path, nil
+}
+
+// checkZip fails unless a file is a readable zip archive, as jars are
+func checkZip(path string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("not a zip archive: %w", err)
+	}
+	return r.Close()
+}
+
+// copyExtension copies a jar from the archive store into an installation
+func copyExtension(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -2,18 +2,31 @@ package version
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
-
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/cache"
+	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/diskspace"
+	"github.com/veenone/mvnenv-win/internal/download"
+	"github.com/veenone/mvnenv-win/internal/integrity"
+	"github.com/veenone/mvnenv-win/internal/lock"
+	"github.com/veenone/mvnenv-win/internal/progress"
 	"github.com/veenone/mvnenv-win/internal/repository"
+	"github.com/veenone/mvnenv-win/internal/signature"
+	"github.com/veenone/mvnenv-win/internal/tool"
 )
 
-// VersionInstaller handles Maven version installation
+// VersionInstaller handles installation of tool versions (Maven by default)
 type VersionInstaller struct {
 	mvnenvRoot    string
+	tool          *tool.Definition
 	repoManager   *repository.Manager
 	resolver      *VersionResolver
 	autoRehash    bool
@@ -21,19 +34,30 @@ type VersionInstaller struct {
 	skipExisting  bool
 	offline       bool
 	quiet         bool
+	reporter      progress.Reporter
+
+	// archiveSHA256 is the SHA-256 the archive must have, if set
+	archiveSHA256 string
 }
 
-// NewVersionInstaller creates a new version installer
+// NewVersionInstaller creates a new version installer for Maven
 func NewVersionInstaller(mvnenvRoot string) *VersionInstaller {
+	return NewToolInstaller(mvnenvRoot, tool.Maven)
+}
+
+// NewToolInstaller creates a version installer for the given tool
+func NewToolInstaller(mvnenvRoot string, def *tool.Definition) *VersionInstaller {
 	return &VersionInstaller{
 		mvnenvRoot:  mvnenvRoot,
+		tool:        def,
 		repoManager: repository.NewManager(mvnenvRoot),
-		resolver:    NewVersionResolver(mvnenvRoot),
+		resolver:    NewToolResolver(mvnenvRoot, def),
 		autoRehash:  true, // Enable automatic shim regeneration
 		force:       false,
 		skipExisting: false,
 		offline:     false,
 		quiet:       false,
+		reporter:    progress.Default(),
 	}
 }
 
@@ -52,100 +76,148 @@ func (i *VersionInstaller) SetOffline(offline bool) {
 	i.offline = offline
 }
 
+// SetArchiveSHA256 requires the archive to have a SHA-256, such as the
+// distributionSha256Sum of a Maven Wrapper project
+func (i *VersionInstaller) SetArchiveSHA256(sum string) {
+	i.archiveSHA256 = strings.ToLower(sum)
+}
+
 // SetQuiet sets the quiet flag
 func (i *VersionInstaller) SetQuiet(quiet bool) {
 	i.quiet = quiet
+	i.reporter = progress.Default()
+	if quiet {
+		i.reporter = progress.Quiet()
+	}
 }
 
-// InstallVersion installs a Maven version
-func (i *VersionInstaller) InstallVersion(version string) error {
-	// Check if already installed
+// InstallVersion installs a version of the tool. When ctx is canceled, any
+// partly extracted files are removed; a resumable download is kept for the
+// next attempt.
+func (i *VersionInstaller) InstallVersion(ctx context.Context, version string) error {
+	name := i.tool.DisplayName
+
+	// Only one process may install or remove a given version at a time;
+	// other versions can be installed concurrently
+	fileLock, err := i.lockVersion(version)
+	if err != nil {
+		return err
+	}
+	defer fileLock.Release()
+
+	// Extensions of an installation being replaced are added back
+	var extensions []Extension
+
+	// Check if already installed (possibly by a process we waited for)
 	if i.resolver.IsVersionInstalled(version) {
 		if i.skipExisting {
 			if !i.quiet {
-				fmt.Printf("Maven %s is already installed (skipped)\n", version)
+				fmt.Printf("%s %s is already installed (skipped)\n", name, version)
 			}
 			return nil
 		}
 		if !i.force {
-			return fmt.Errorf("Maven %s is already installed (use --force to reinstall)", version)
+			return fmt.Errorf("%s %s is already installed (use --force to reinstall)", name, version)
 		}
 		// Force reinstall: remove existing version first
 		if !i.quiet {
-			fmt.Printf("Maven %s already installed, reinstalling...\n", version)
+			fmt.Printf("%s %s already installed, reinstalling...\n", name, version)
+		}
+		if extensions, err = ListExtensions(i.resolver.GetVersionPath(version)); err != nil && !i.quiet {
+			fmt.Printf("Warning: Could not read extensions to reapply: %v\n", err)
 		}
-		if err := i.UninstallVersion(version); err != nil {
+		if err := os.RemoveAll(i.resolver.GetVersionPath(version)); err != nil {
 			return fmt.Errorf("failed to remove existing version: %w", err)
 		}
 	}
 
 	// Create directories
-	cacheDir := filepath.Join(i.mvnenvRoot, "cache")
-	versionsDir := filepath.Join(i.mvnenvRoot, "versions")
+	versionsDir := i.tool.VersionsPath(i.mvnenvRoot)
 
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
-		return fmt.Errorf("create cache directory: %w", err)
-	}
 	if err := os.MkdirAll(versionsDir, 0755); err != nil {
 		return fmt.Errorf("create versions directory: %w", err)
 	}
 
-	// Check disk space (require at least 100MB for safety)
-	requiredSpace := int64(100 * 1024 * 1024) // 100MB
-	availableSpace, err := i.getAvailableDiskSpace(i.mvnenvRoot)
+	entry, archivePath, err := i.fetchArchive(ctx, version)
 	if err != nil {
-		// Warn but don't fail if we can't check disk space
-		if !i.quiet {
-			fmt.Printf("Warning: Could not check disk space: %v\n", err)
-		}
-	} else if availableSpace < requiredSpace {
-		return fmt.Errorf("insufficient disk space: required %d MB, available %d MB",
-			requiredSpace/(1024*1024), availableSpace/(1024*1024))
+		return err
 	}
-
-	// Download to cache
-	archivePath := filepath.Join(cacheDir, fmt.Sprintf("apache-maven-%s-bin.zip", version))
-
-	var progress func(int64, int64)
-	if !i.quiet {
-		progress = func(downloaded, total int64) {
-			if total > 0 {
-				percent := float64(downloaded) / float64(total) * 100
-				fmt.Printf("\rDownloading: %.1f%%", percent)
-			}
-		}
+	archiveSHA256, err := fileSHA256(archivePath)
+	if err != nil {
+		return fmt.Errorf("calculate checksum: %w", err)
 	}
-
-	// Configure repository manager for offline mode
-	if i.offline {
-		i.repoManager.SetOfflineMode(true)
+	if i.archiveSHA256 != "" && archiveSHA256 != i.archiveSHA256 {
+		return fmt.Errorf("the %s archive has SHA-256 %s, not the required %s", entry.Name, archiveSHA256, i.archiveSHA256)
 	}
 
-	if err := i.repoManager.DownloadVersion(version, archivePath, progress); err != nil {
-		return fmt.Errorf("download failed: %w", err)
+	// The zip's central directory tells exactly how much extraction needs
+	extracted, err := uncompressedSize(archivePath)
+	if err != nil {
+		return fmt.Errorf("read archive: %w", err)
 	}
-	if !i.quiet && progress != nil {
-		fmt.Println() // New line after progress
+	if err := i.checkDiskSpace(diskspace.Need{Path: versionsDir, Bytes: extracted, What: "installation"}); err != nil {
+		return err
 	}
 
 	// Extract to versions directory
 	if !i.quiet {
-		fmt.Printf("Installing Maven %s...\n", version)
+		fmt.Printf("Installing %s %s...\n", name, version)
 	}
 	versionPath := filepath.Join(versionsDir, version)
 
-	if err := i.extractZip(archivePath, versionsDir, version); err != nil {
+	task := i.reporter.Start(progress.KindExtract, entry.Name)
+	if err := i.extractZip(ctx, archivePath, versionsDir, version, task.Update); err != nil {
+		task.Fail(err)
+		os.RemoveAll(versionPath)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		return fmt.Errorf("extract failed: %w", err)
 	}
+	task.Done("")
 
 	// Verify installation
-	mvnCmd := filepath.Join(versionPath, "bin", "mvn.cmd")
-	if _, err := os.Stat(mvnCmd); err != nil {
-		return fmt.Errorf("installation verification failed: mvn.cmd not found")
+	if !i.tool.IsValidInstallation(versionPath) {
+		os.RemoveAll(versionPath)
+		return fmt.Errorf("installation verification failed: %s%s not found", i.tool.Launchers[0], i.tool.LauncherExt)
 	}
 
+	// Record where this installation came from
+	manifest := &Manifest{
+		Tool:          i.tool.Name,
+		Version:       version,
+		Source:        entry.Source,
+		Archive:       entry.Name,
+		ArchiveSize:   entry.Size,
+		ArchiveSHA512: entry.SHA512,
+		ArchiveSHA256: archiveSHA256,
+		InstalledAt:   time.Now().UTC(),
+		Integrity:     entry.Integrity,
+		Signature:     entry.Signature,
+	}
+
+	// Overlays go on top of the distribution before its files are hashed
+	overlays, err := i.applyOverlays(ctx, versionPath, manifest, version)
+	if err != nil {
+		os.RemoveAll(versionPath)
+		return err
+	}
 	if !i.quiet {
-		fmt.Printf("Maven %s installed successfully\n", version)
+		for _, o := range overlays {
+			fmt.Printf("Applied overlay %s (%d files)\n", o.Name, len(o.Files))
+		}
+	}
+	manifest.Java = detectJava(i.tool, versionPath)
+	if manifest.Files, err = hashFiles(versionPath); err == nil {
+		i.reapplyExtensions(ctx, versionPath, manifest, extensions)
+		err = writeManifest(versionPath, manifest)
+	}
+	if err != nil && !i.quiet {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	if !i.quiet {
+		fmt.Printf("%s %s installed successfully\n", name, version)
 	}
 
 	// Automatically regenerate shims
@@ -159,22 +231,233 @@ func (i *VersionInstaller) InstallVersion(version string) error {
 	return nil
 }
 
-// UninstallVersion removes a Maven version
+// fetchArchive returns the store entry and path of a verified archive for a
+// version, reusing the archive store when it holds one and downloading it
+// otherwise. The archive's checksum and signature are then checked against
+// the configured policies.
+func (i *VersionInstaller) fetchArchive(ctx context.Context, version string) (*cache.ArchiveEntry, string, error) {
+	policy, err := i.signaturePolicy()
+	if err != nil {
+		return nil, "", err
+	}
+
+	store := cache.NewArchiveStore(i.mvnenvRoot)
+
+	entry, archivePath, err := store.Lookup(i.tool.Name, version)
+	if err != nil && !i.quiet {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	if entry != nil {
+		if !i.quiet {
+			fmt.Printf("Using cached %s (fetched %s from %s)\n",
+				entry.Name, entry.FetchedAt.Local().Format("2006-01-02"), entry.Source)
+		}
+		if err := i.checkIntegrity(ctx, store, entry, archivePath); err != nil {
+			return nil, "", err
+		}
+	} else {
+		entry, archivePath, err = i.downloadArchive(ctx, store, version)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := i.checkSignature(ctx, store, entry, archivePath, policy); err != nil {
+		return nil, "", err
+	}
+
+	return entry, archivePath, nil
+}
+
+// downloadArchive downloads a version and adds it to the archive store
+func (i *VersionInstaller) downloadArchive(ctx context.Context, store *cache.ArchiveStore, version string) (*cache.ArchiveEntry, string, error) {
+	downloadPath, err := store.DownloadPath(i.tool.Name, version)
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(downloadPath)
+
+	// Configure repository manager for offline mode
+	if i.offline {
+		i.repoManager.SetOfflineMode(true)
+	}
+
+	// Before writing, make room for the advertised archive and, as a first
+	// estimate, its extraction; the two are checked separately when the
+	// cache and versions directories are on different volumes
+	versionsDir := i.tool.VersionsPath(i.mvnenvRoot)
+	i.repoManager.SetPreflight(func(size int64) error {
+		return i.checkDiskSpace(
+			diskspace.Need{Path: store.Dir(), Bytes: size, What: "download cache"},
+			diskspace.Need{Path: versionsDir, Bytes: size, What: "installation"},
+		)
+	})
+
+	task := i.reporter.Start(progress.KindDownload, i.tool.ArchiveName(version))
+	dl, err := i.repoManager.DownloadToolVersion(progress.WithTask(ctx, task), i.tool, version, downloadPath, task.Update)
+	if err != nil {
+		task.Fail(err)
+	} else {
+		task.Done("")
+	}
+	if ctx.Err() != nil {
+		return nil, "", ctx.Err()
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("download failed: %w", err)
+	}
+	i.printIntegrity(dl.Integrity)
+
+	entry, archivePath, err := store.Add(cache.ArchiveEntry{
+		Tool:      i.tool.Name,
+		Version:   version,
+		Name:      i.tool.ArchiveName(version),
+		Source:    dl.Source,
+		Integrity: dl.Integrity,
+	}, downloadPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("cache archive: %w", err)
+	}
+
+	return entry, archivePath, nil
+}
+
+// checkIntegrity verifies a cached archive against its published checksum
+// unless a successful verification is already recorded in the archive store
+func (i *VersionInstaller) checkIntegrity(ctx context.Context, store *cache.ArchiveStore, entry *cache.ArchiveEntry, archivePath string) error {
+	if entry.Integrity != nil && entry.Integrity.Status == integrity.StatusVerified {
+		return nil
+	}
+
+	result, err := i.repoManager.CheckIntegrity(ctx, i.tool, entry.Version, entry.Source, archivePath)
+	if result != nil {
+		entry.Integrity = result
+		if result.Status == integrity.StatusMismatch {
+			// The archive doesn't match what its source publishes
+			store.Remove(entry.Tool, entry.Version)
+		} else if err := store.SetIntegrity(entry.Tool, entry.Version, *result); err != nil && !i.quiet {
+			fmt.Printf("Warning: Could not record checksum status: %v\n", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	i.printIntegrity(result)
+	return nil
+}
+
+// printIntegrity reports a successful checksum verification
+func (i *VersionInstaller) printIntegrity(result *integrity.Result) {
+	if result != nil && result.Status == integrity.StatusVerified && !i.quiet {
+		fmt.Printf("Verified %s checksum (%s)\n", result.Algorithm, result.URL)
+	}
+}
+
+// checkSignature verifies an archive's OpenPGP signature unless a valid one
+// is already recorded in the archive store, records the outcome there, and
+// applies the signature policy
+func (i *VersionInstaller) checkSignature(ctx context.Context, store *cache.ArchiveStore, entry *cache.ArchiveEntry, archivePath string, policy signature.Policy) error {
+	if policy == signature.PolicyOff {
+		return nil
+	}
+
+	if entry.Signature == nil || entry.Signature.Status != signature.StatusValid {
+		result := i.verifySignature(ctx, entry.Source, archivePath)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		entry.Signature = &result
+
+		if result.Status == signature.StatusInvalid && policy == signature.PolicyRequire {
+			// A tampered or corrupted archive must not be reused
+			store.Remove(entry.Tool, entry.Version)
+		} else if err := store.SetSignature(entry.Tool, entry.Version, result); err != nil && !i.quiet {
+			fmt.Printf("Warning: Could not record signature status: %v\n", err)
+		}
+	}
+
+	what := fmt.Sprintf("%s %s", i.tool.DisplayName, entry.Version)
+	if err := signature.Check(policy, *entry.Signature, what); err != nil {
+		return err
+	}
+
+	if entry.Signature.Status == signature.StatusValid && !i.quiet {
+		fmt.Printf("Verified %s\n", entry.Signature)
+	}
+	return nil
+}
+
+// verifySignature fetches the signature published next to an archive and
+// verifies it against the keyring
+func (i *VersionInstaller) verifySignature(ctx context.Context, source, archivePath string) signature.Result {
+	if i.tool.SignatureSuffix == "" {
+		return signature.Result{
+			Status: signature.StatusMissing,
+			Detail: fmt.Sprintf("%s does not publish signatures", i.tool.DisplayName),
+		}
+	}
+
+	sig, err := i.repoManager.FetchSidecar(ctx, source, i.tool.SignatureSuffix)
+	if err != nil {
+		detail := err.Error()
+		if download.IsNotFound(err) {
+			detail = "not found at " + source + i.tool.SignatureSuffix
+		}
+		return signature.Result{Status: signature.StatusMissing, Detail: detail}
+	}
+
+	return signature.NewKeyring(i.mvnenvRoot).Verify(archivePath, sig)
+}
+
+// signaturePolicy returns the configured signature policy
+func (i *VersionInstaller) signaturePolicy() (signature.Policy, error) {
+	cfg, err := config.NewManager(i.mvnenvRoot).Load()
+	if err != nil || cfg.Verification == nil {
+		return signature.DefaultPolicy, nil
+	}
+	return signature.ParsePolicy(cfg.Verification.Signature)
+}
+
+// UninstallVersion moves a version of the tool to the trash, from which it
+// can be restored for TrashGracePeriod. A version that is selected globally,
+// in the current shell or by a project's version file is refused unless
+// force is set.
 func (i *VersionInstaller) UninstallVersion(version string) error {
+	name := i.tool.DisplayName
+
+	fileLock, err := i.lockVersion(version)
+	if err != nil {
+		return err
+	}
+	defer fileLock.Release()
+
 	// Check if installed
 	if !i.resolver.IsVersionInstalled(version) {
 		return fmt.Errorf("version '%s' not installed", version)
 	}
 
-	// Get version path
-	versionPath := i.resolver.GetVersionPath(version)
+	usages := i.resolver.FindUsages(version)
+	if len(usages) > 0 && !i.force {
+		return inUseError(name, version, usages)
+	}
+
+	if _, err := i.moveToTrash(version); err != nil {
+		return err
+	}
 
-	// Remove directory
-	if err := os.RemoveAll(versionPath); err != nil {
-		return fmt.Errorf("remove version directory: %w", err)
+	if !i.quiet {
+		fmt.Printf("%s %s uninstalled (restore it within %d days with 'mvnenv trash restore %s%s')\n",
+			name, version, int(TrashGracePeriod.Hours()/24), toolArg(i.tool), version)
+		for _, u := range usages {
+			fmt.Printf("Warning: still selected as %s\n", u)
+		}
 	}
 
-	fmt.Printf("Maven %s uninstalled successfully\n", version)
+	// Versions trashed long ago are deleted for good
+	if _, err := PurgeTrash(i.mvnenvRoot, false); err != nil && !i.quiet {
+		fmt.Printf("Warning: Could not clean up the trash: %v\n", err)
+	}
 
 	// Automatically regenerate shims
 	if i.autoRehash {
@@ -187,19 +470,73 @@ func (i *VersionInstaller) UninstallVersion(version string) error {
 	return nil
 }
 
+// toolArg returns the --tool argument to repeat in command hints
+func toolArg(def *tool.Definition) string {
+	if def.IsMaven() {
+		return ""
+	}
+	return "--tool " + def.Name + " "
+}
+
+// checkDiskSpace fails when a volume is short of space. When free space
+// can't be determined a warning is printed and the install goes ahead.
+func (i *VersionInstaller) checkDiskSpace(needs ...diskspace.Need) error {
+	err := diskspace.Check(needs...)
+	if err == nil || diskspace.IsInsufficient(err) {
+		return err
+	}
+	if !i.quiet {
+		fmt.Printf("Warning: Could not check disk space: %v\n", err)
+	}
+	return nil
+}
+
+// uncompressedSize returns the total uncompressed size of a zip archive
+func uncompressedSize(archivePath string) (int64, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var total uint64
+	for _, f := range r.File {
+		total += f.UncompressedSize64
+	}
+	return int64(total), nil
+}
+
+// lockVersion takes the cross-process lock for one version of the tool
+func (i *VersionInstaller) lockVersion(version string) (*lock.FileLock, error) {
+	fileLock, err := lock.Acquire(i.mvnenvRoot, lock.VersionLockName(i.tool.Name, version))
+	if err != nil {
+		return nil, fmt.Errorf("lock %s %s: %w", i.tool.DisplayName, version, err)
+	}
+	return fileLock, nil
+}
+
 // extractZip extracts a ZIP archive to a destination directory
-func (i *VersionInstaller) extractZip(archivePath string, destDir string, version string) error {
+func (i *VersionInstaller) extractZip(ctx context.Context, archivePath string, destDir string, version string, report download.ProgressCallback) error {
 	r, err := zip.OpenReader(archivePath)
 	if err != nil {
 		return fmt.Errorf("open archive: %w", err)
 	}
 	defer r.Close()
 
+	var total, done int64
+	for _, f := range r.File {
+		total += int64(f.UncompressedSize64)
+	}
+
 	// Maven archives have a root directory like "apache-maven-3.9.4/"
 	// We want to extract to "versions/3.9.4/" so we need to strip the root directory
 	var rootPrefix string
 
 	for _, f := range r.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Detect root prefix from first file
 		if rootPrefix == "" {
 			parts := strings.SplitN(f.Name, "/", 2)
@@ -219,8 +556,13 @@ func (i *VersionInstaller) extractZip(archivePath string, destDir string, versio
 			continue
 		}
 
-		// Construct destination path
-		destPath := filepath.Join(destDir, version, relativePath)
+		// Construct destination path, refusing entries such as
+		// "apache-maven-3.9.4/../../x" that would land outside the installation
+		entryPath, err := installPath(relativePath)
+		if err != nil {
+			return err
+		}
+		destPath := filepath.Join(destDir, version, entryPath)
 
 		// Create directory or extract file
 		if f.FileInfo().IsDir() {
@@ -237,12 +579,24 @@ func (i *VersionInstaller) extractZip(archivePath string, destDir string, versio
 			if err := i.extractFile(f, destPath); err != nil {
 				return fmt.Errorf("extract file %s: %w", f.Name, err)
 			}
+			done += int64(f.UncompressedSize64)
+			report(done, total)
 		}
 	}
 
 	return nil
 }
 
+// installPath cleans the path of an archive entry inside the installation,
+// refusing paths that would leave it
+func installPath(name string) (string, error) {
+	p := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") || strings.Contains(p, ":") {
+		return "", fmt.Errorf("archive entry %s is outside the installation", name)
+	}
+	return filepath.FromSlash(p), nil
+}
+
 // extractFile extracts a single file from ZIP archive
 func (i *VersionInstaller) extractFile(f *zip.File, destPath string) error {
 	rc, err := f.Open()
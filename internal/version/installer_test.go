@@ -0,0 +1,72 @@
+package version
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZip creates a zip archive holding the named files
+func writeZip(t *testing.T, names ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for _, name := range names {
+		entry, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write([]byte(name))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractZip(t *testing.T) {
+	archive := writeZip(t, "apache-maven-3.9.6/bin/mvn.cmd", "apache-maven-3.9.6/lib/ext/README.txt")
+	destDir := t.TempDir()
+
+	i := &VersionInstaller{}
+	if err := i.extractZip(context.Background(), archive, destDir, "3.9.6", func(int64, int64) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rel := range []string{"bin/mvn.cmd", "lib/ext/README.txt"} {
+		if _, err := os.Stat(filepath.Join(destDir, "3.9.6", filepath.FromSlash(rel))); err != nil {
+			t.Errorf("%s not extracted: %v", rel, err)
+		}
+	}
+}
+
+func TestExtractZipRefusesEscapingEntries(t *testing.T) {
+	for _, name := range []string{
+		"apache-maven-3.9.6/../../evil.txt",
+		"apache-maven-3.9.6/bin/../../../evil.txt",
+		`apache-maven-3.9.6/..\..\evil.txt`,
+		"apache-maven-3.9.6/C:/evil.txt",
+	} {
+		t.Run(name, func(t *testing.T) {
+			archive := writeZip(t, "apache-maven-3.9.6/bin/mvn.cmd", name)
+			root := t.TempDir()
+			destDir := filepath.Join(root, "versions")
+
+			i := &VersionInstaller{}
+			if err := i.extractZip(context.Background(), archive, destDir, "3.9.6", func(int64, int64) {}); err == nil {
+				t.Fatal("extractZip() accepted an entry outside the installation")
+			}
+			if _, err := os.Stat(filepath.Join(root, "evil.txt")); err == nil {
+				t.Error("entry was written outside the installation")
+			}
+		})
+	}
+}
@@ -5,26 +5,34 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/veenone/mvnenv-win/internal/tool"
 	"github.com/veenone/mvnenv-win/pkg/maven"
 )
 
-// VersionLister lists installed Maven versions
+// VersionLister lists installed versions of a tool (Maven by default)
 type VersionLister struct {
 	mvnenvRoot string
+	tool       *tool.Definition
 	resolver   *VersionResolver
 }
 
-// NewVersionLister creates a new version lister
+// NewVersionLister creates a new version lister for Maven
 func NewVersionLister(mvnenvRoot string) *VersionLister {
+	return NewToolLister(mvnenvRoot, tool.Maven)
+}
+
+// NewToolLister creates a version lister for the given tool
+func NewToolLister(mvnenvRoot string, def *tool.Definition) *VersionLister {
 	return &VersionLister{
 		mvnenvRoot: mvnenvRoot,
-		resolver:   NewVersionResolver(mvnenvRoot),
+		tool:       def,
+		resolver:   NewToolResolver(mvnenvRoot, def),
 	}
 }
 
-// ListInstalled returns a list of installed Maven versions
+// ListInstalled returns a list of installed versions
 func (l *VersionLister) ListInstalled() ([]string, error) {
-	versionsDir := filepath.Join(l.mvnenvRoot, "versions")
+	versionsDir := l.tool.VersionsPath(l.mvnenvRoot)
 
 	// Check if versions directory exists
 	if _, err := os.Stat(versionsDir); os.IsNotExist(err) {
@@ -44,9 +52,8 @@ func (l *VersionLister) ListInstalled() ([]string, error) {
 		}
 
 		version := entry.Name()
-		// Verify it's a valid Maven installation
-		mvnCmd := filepath.Join(versionsDir, version, "bin", "mvn.cmd")
-		if _, err := os.Stat(mvnCmd); err == nil {
+		// Verify it's a valid installation
+		if l.tool.IsValidInstallation(filepath.Join(versionsDir, version)) {
 			versions = append(versions, version)
 		}
 	}
@@ -0,0 +1,258 @@
+package version
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/integrity"
+	"github.com/veenone/mvnenv-win/internal/signature"
+	"github.com/veenone/mvnenv-win/internal/tool"
+)
+
+// ManifestFile is the name of the manifest written into each installation
+const ManifestFile = ".mvnenv-manifest.json"
+
+// javaProbeTimeout bounds how long "mvn -v" or "java -version" may run while
+// installing
+const javaProbeTimeout = 30 * time.Second
+
+// Manifest records where an installation came from
+type Manifest struct {
+	Tool          string            `json:"tool"`
+	Version       string            `json:"version"`
+	Source        string            `json:"source"`
+	Archive       string            `json:"archive"`
+	ArchiveSize   int64             `json:"archive_size"`
+	ArchiveSHA512 string            `json:"archive_sha512"`
+	ArchiveSHA256 string            `json:"archive_sha256,omitempty"`
+	InstalledAt   time.Time         `json:"installed_at"`
+	Integrity     *integrity.Result `json:"integrity,omitempty"`
+	Signature     *signature.Result `json:"signature,omitempty"`
+
+	// Java is the Java runtime found at install time: Maven's "-v" output,
+	// e.g. "17.0.9, vendor: Eclipse Adoptium, runtime: C:\jdk-17", or for
+	// other tools the version of the java on JAVA_HOME or PATH
+	Java string `json:"java,omitempty"`
+
+	// Files maps each installed file, by slash-separated relative path, to
+	// its SHA-256; mvnenv verify compares installations against it
+	Files map[string]string `json:"files,omitempty"`
+
+	// Extensions are the core extensions added with "mvnenv ext add"; their
+	// jars are part of Files
+	Extensions []Extension `json:"extensions,omitempty"`
+
+	// Overlays are the configured overlays applied to the installation; the
+	// files they wrote are part of Files
+	Overlays []AppliedOverlay `json:"overlays,omitempty"`
+}
+
+// ReadManifest reads the manifest of an installation. Installations made
+// before manifests existed have none; (nil, nil) is returned for them.
+func ReadManifest(installPath string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(installPath, ManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// writeManifest writes the manifest into an installation
+func writeManifest(installPath string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(installPath, ManifestFile), data, 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// detectJava returns the Java runtime an installation runs on, or "" if it
+// can't be determined. Maven reports it with -v; other tools aren't run,
+// since mvnd -v starts a daemon that keeps the installation's files open,
+// so the Java they pick up from JAVA_HOME or PATH is asked instead.
+func detectJava(def *tool.Definition, installPath string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), javaProbeTimeout)
+	defer cancel()
+
+	if !def.IsMaven() {
+		return javaVersion(ctx)
+	}
+
+	cmd := exec.CommandContext(ctx, def.LauncherPath(installPath, def.Launchers[0]), "-v")
+	cmd.Env = os.Environ()
+	for name, value := range def.HomeEnvironment(installPath) {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return ""
+	}
+
+	// Maven prints e.g. "Java version: 17.0.9, vendor: ..."
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if java, ok := strings.CutPrefix(line, "Java version:"); ok {
+			return strings.TrimSpace(java)
+		}
+	}
+	return ""
+}
+
+// javaVersion runs "java -version" from JAVA_HOME, or from PATH when it isn't
+// set, and returns e.g. "17.0.9, runtime: C:\jdk-17"
+func javaVersion(ctx context.Context) string {
+	java := "java"
+	javaHome := os.Getenv("JAVA_HOME")
+	if javaHome != "" {
+		java = filepath.Join(javaHome, "bin", "java")
+	}
+
+	output, err := exec.CommandContext(ctx, java, "-version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+
+	// The first line is e.g. 'openjdk version "17.0.9" 2023-10-17'
+	line, _, _ := strings.Cut(string(output), "\n")
+	_, rest, ok := strings.Cut(line, `version "`)
+	if !ok {
+		return ""
+	}
+	version, _, ok := strings.Cut(rest, `"`)
+	if !ok || version == "" {
+		return ""
+	}
+	if javaHome != "" {
+		return version + ", runtime: " + javaHome
+	}
+	return version
+}
+
+// FileChange kinds reported by DiffInstallation
+const (
+	FileModified = "modified"
+	FileMissing  = "missing"
+	FileAdded    = "added"
+)
+
+// FileChange is a file whose installed state differs from the archive
+type FileChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+}
+
+// DiffInstallation compares an installation with the archive it was
+// extracted from and returns the files that were modified, removed or
+// added since. Paths are relative to the installation and use forward
+// slashes. The manifest itself is not reported.
+func DiffInstallation(installPath, archivePath string) ([]FileChange, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer r.Close()
+
+	var changes []FileChange
+	inArchive := make(map[string]bool)
+
+	rootPrefix := archiveRoot(r.File)
+	for _, f := range r.File {
+		rel := strings.TrimPrefix(f.Name, rootPrefix)
+		if !strings.HasPrefix(f.Name, rootPrefix) || rel == "" || f.FileInfo().IsDir() {
+			continue
+		}
+		inArchive[rel] = true
+
+		same, err := matchesArchive(filepath.Join(installPath, filepath.FromSlash(rel)), f)
+		switch {
+		case os.IsNotExist(err):
+			changes = append(changes, FileChange{Path: rel, Change: FileMissing})
+		case err != nil:
+			return nil, err
+		case !same:
+			changes = append(changes, FileChange{Path: rel, Change: FileModified})
+		}
+	}
+
+	err = filepath.Walk(installPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(installPath, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != ManifestFile && !inArchive[rel] {
+			changes = append(changes, FileChange{Path: rel, Change: FileAdded})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan installation: %w", err)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// archiveRoot returns the top-level directory of a distribution archive,
+// including its trailing slash, which extraction strips
+func archiveRoot(files []*zip.File) string {
+	if len(files) == 0 {
+		return ""
+	}
+	return strings.SplitN(files[0].Name, "/", 2)[0] + "/"
+}
+
+// matchesArchive reports whether an installed file has the size and CRC-32
+// of its archive entry
+func matchesArchive(path string, f *zip.File) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if uint64(info.Size()) != f.UncompressedSize64 {
+		return false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, file); err != nil {
+		return false, fmt.Errorf("read %s: %w", path, err)
+	}
+	return h.Sum32() == f.CRC32, nil
+}
@@ -0,0 +1,311 @@
+package version
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/tool"
+	"github.com/veenone/mvnenv-win/pkg/maven"
+)
+
+// OverlaysDir holds local overlays, relative to MVNENV_ROOT
+const OverlaysDir = "overlays"
+
+// OverlayStorePrefix marks overlay archives from Nexus in the archive
+// store, where they are indexed as "overlay:<groupId>:<artifactId>"
+const OverlayStorePrefix = "overlay:"
+
+// AppliedOverlay records an overlay copied into an installation
+type AppliedOverlay struct {
+	Name string `json:"name"`
+
+	// Source is the overlay directory, archive or Nexus URL
+	Source string `json:"source"`
+
+	// Files are the slash-separated paths the overlay wrote
+	Files     []string  `json:"files"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// overlayFile is a file of an overlay, by slash-separated relative path
+type overlayFile struct {
+	path string
+	mode os.FileMode
+	open func() (io.ReadCloser, error)
+}
+
+// SelectOverlays returns the overlays configured for a version of a tool,
+// in the order they apply
+func SelectOverlays(mvnenvRoot string, def *tool.Definition, version string) ([]config.OverlayConfig, error) {
+	cfg, err := config.NewManager(mvnenvRoot).Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// Versions that don't parse only get overlays without a selector
+	v, _ := maven.ParseVersion(version)
+
+	var selected []config.OverlayConfig
+	for _, o := range cfg.Overlays {
+		if !coordinatePattern.MatchString(o.Name) {
+			return nil, fmt.Errorf("invalid overlay name '%s' (use letters, digits, '.', '_' and '-')", o.Name)
+		}
+		toolName := o.Tool
+		if toolName == "" {
+			toolName = tool.NameMaven
+		}
+		if toolName != def.Name {
+			continue
+		}
+		if o.Versions != "" {
+			r, err := maven.ParseRange(o.Versions)
+			if err != nil {
+				return nil, fmt.Errorf("overlay '%s': %w", o.Name, err)
+			}
+			if v == nil || !r.Contains(v) {
+				continue
+			}
+		}
+		selected = append(selected, o)
+	}
+	return selected, nil
+}
+
+// ApplyOverlays applies the overlays selected for an installed version
+// again, e.g. after they changed, and returns them
+func (i *VersionInstaller) ApplyOverlays(ctx context.Context, version string) ([]AppliedOverlay, error) {
+	fileLock, err := i.lockVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Release()
+
+	installPath, manifest, err := i.installedManifest(version)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := i.applyOverlays(ctx, installPath, manifest, version)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 {
+		if err := writeManifest(installPath, manifest); err != nil {
+			return nil, err
+		}
+	}
+	return applied, nil
+}
+
+// applyOverlays applies the overlays selected for an installation and
+// records them in the manifest, which the caller writes
+func (i *VersionInstaller) applyOverlays(ctx context.Context, installPath string, manifest *Manifest, version string) ([]AppliedOverlay, error) {
+	overlays, err := SelectOverlays(i.mvnenvRoot, i.tool, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []AppliedOverlay
+	for _, o := range overlays {
+		a, err := i.applyOverlay(ctx, installPath, manifest, o)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("apply overlay '%s': %w", o.Name, err)
+		}
+		applied = append(applied, *a)
+	}
+	return applied, nil
+}
+
+// applyOverlay copies an overlay's files into an installation and records
+// it in the manifest, replacing an earlier record of the same overlay
+func (i *VersionInstaller) applyOverlay(ctx context.Context, installPath string, manifest *Manifest, o config.OverlayConfig) (*AppliedOverlay, error) {
+	source, files, closeSource, err := i.overlaySource(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSource()
+
+	applied := AppliedOverlay{Name: o.Name, Source: source, Files: []string{}, AppliedAt: time.Now().UTC()}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		destPath := filepath.Join(installPath, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return nil, fmt.Errorf("create parent directory: %w", err)
+		}
+		if err := writeOverlayFile(f, destPath); err != nil {
+			return nil, fmt.Errorf("write %s: %w", f.path, err)
+		}
+
+		if manifest.Files != nil {
+			sum, err := fileSHA256(destPath)
+			if err != nil {
+				return nil, err
+			}
+			manifest.Files[f.path] = sum
+		}
+		applied.Files = append(applied.Files, f.path)
+	}
+	sort.Strings(applied.Files)
+
+	var kept []AppliedOverlay
+	for _, existing := range manifest.Overlays {
+		if existing.Name != o.Name {
+			kept = append(kept, existing)
+		}
+	}
+	manifest.Overlays = append(kept, applied)
+	return &applied, nil
+}
+
+// overlaySource opens an overlay: an archive from Nexus when an artifact is
+// configured, otherwise overlays/<name> or overlays/<name>.zip. It returns
+// where the files come from, the files, and a function releasing them.
+func (i *VersionInstaller) overlaySource(ctx context.Context, o config.OverlayConfig) (string, []overlayFile, func(), error) {
+	if o.Artifact != "" {
+		parts, ok := splitCoordinates(o.Artifact, true)
+		if !ok {
+			return "", nil, nil, fmt.Errorf("invalid artifact '%s' (use groupId:artifactId:version)", o.Artifact)
+		}
+		groupID, artifactID, version := parts[0], parts[1], parts[2]
+
+		entry, archivePath, err := i.fetchArtifact(ctx, OverlayStorePrefix+groupID+":"+artifactID,
+			groupID, artifactID, version, artifactID+"-"+version+".zip")
+		if err != nil {
+			return "", nil, nil, err
+		}
+		files, closeArchive, err := zipOverlayFiles(archivePath)
+		return entry.Source, files, closeArchive, err
+	}
+
+	dir := filepath.Join(i.mvnenvRoot, OverlaysDir, o.Name)
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		files, err := dirOverlayFiles(dir)
+		return dir, files, func() {}, err
+	}
+
+	archivePath := dir + ".zip"
+	if _, err := os.Stat(archivePath); err == nil {
+		files, closeArchive, err := zipOverlayFiles(archivePath)
+		return archivePath, files, closeArchive, err
+	}
+
+	return "", nil, nil, fmt.Errorf("not found (create %s or %s, or set its artifact)", dir, archivePath)
+}
+
+// dirOverlayFiles lists the files of an overlay directory
+func dirOverlayFiles(dir string) ([]overlayFile, error) {
+	var files []overlayFile
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel, err = overlayPath(rel)
+		if err != nil {
+			return err
+		}
+		files = append(files, overlayFile{
+			path: rel,
+			mode: info.Mode().Perm(),
+			open: func() (io.ReadCloser, error) { return os.Open(p) },
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read overlay: %w", err)
+	}
+	return files, nil
+}
+
+// zipOverlayFiles lists the files of an overlay archive, whose paths are
+// relative to the installation
+func zipOverlayFiles(archivePath string) ([]overlayFile, func(), error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open overlay archive: %w", err)
+	}
+
+	var files []overlayFile
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rel, err := overlayPath(f.Name)
+		if err != nil {
+			r.Close()
+			return nil, nil, err
+		}
+		files = append(files, overlayFile{path: rel, mode: f.Mode().Perm(), open: f.Open})
+	}
+	return files, func() { r.Close() }, nil
+}
+
+// overlayPath cleans an overlay file's path, refusing paths that would
+// leave the installation or replace its manifest
+func overlayPath(name string) (string, error) {
+	p := path.Clean(filepath.ToSlash(name))
+	if path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") || strings.Contains(p, ":") {
+		return "", fmt.Errorf("overlay path %s is outside the installation", name)
+	}
+	if p == ManifestFile {
+		return "", fmt.Errorf("overlays can't replace %s", ManifestFile)
+	}
+	return p, nil
+}
+
+// writeOverlayFile copies an overlay file to destPath
+func writeOverlayFile(f overlayFile, destPath string) error {
+	in, err := f.open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	mode := f.mode
+	if mode == 0 {
+		mode = 0644
+	}
+	out, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// overlayConfigured reports whether an overlay archive in the archive store
+// belongs to a configured overlay
+func overlayConfigured(mvnenvRoot, storeTool, version string) bool {
+	cfg, err := config.NewManager(mvnenvRoot).Load()
+	if err != nil {
+		return true
+	}
+	for _, o := range cfg.Overlays {
+		parts, ok := splitCoordinates(o.Artifact, true)
+		if ok && OverlayStorePrefix+parts[0]+":"+parts[1] == storeTool && parts[2] == version {
+			return true
+		}
+	}
+	return false
+}
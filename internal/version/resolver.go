@@ -7,39 +7,54 @@ import (
 	"strings"
 
 	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/tool"
 )
 
 // ResolvedVersion contains version resolution result
 type ResolvedVersion struct {
 	Version string
 	Source  Source
-	Path    string // Path to Maven installation
+	Path    string           // Path to the tool installation
+	Tool    *tool.Definition // Tool the version belongs to
 }
 
 // Source indicates where the version was resolved from
 type Source string
 
 const (
-	SourceShell  Source = "shell"
-	SourceLocal  Source = "local"
-	SourceGlobal Source = "global"
+	SourceShell    Source = "shell"
+	SourceLocal    Source = "local"
+	SourceGlobal   Source = "global"
+	SourceExplicit Source = "command line"
 )
 
-// VersionResolver resolves the active Maven version
+// VersionResolver resolves the active version of a tool (Maven by default)
 type VersionResolver struct {
 	mvnenvRoot    string
 	configManager *config.Manager
+	tool          *tool.Definition
 }
 
-// NewVersionResolver creates a new version resolver
+// NewVersionResolver creates a new version resolver for Maven
 func NewVersionResolver(mvnenvRoot string) *VersionResolver {
+	return NewToolResolver(mvnenvRoot, tool.Maven)
+}
+
+// NewToolResolver creates a version resolver for the given tool
+func NewToolResolver(mvnenvRoot string, def *tool.Definition) *VersionResolver {
 	return &VersionResolver{
 		mvnenvRoot:    mvnenvRoot,
 		configManager: config.NewManager(mvnenvRoot),
+		tool:          def,
 	}
 }
 
-// ResolveVersion resolves the active Maven version using shell > local > global hierarchy
+// Tool returns the tool this resolver resolves versions for
+func (r *VersionResolver) Tool() *tool.Definition {
+	return r.tool
+}
+
+// ResolveVersion resolves the active version using shell > local > global hierarchy
 func (r *VersionResolver) ResolveVersion() (*ResolvedVersion, error) {
 	// 1. Check shell environment variable
 	if version, ok := r.getShellVersion(); ok {
@@ -54,6 +69,7 @@ func (r *VersionResolver) ResolveVersion() (*ResolvedVersion, error) {
 			Version: version,
 			Source:  SourceShell,
 			Path:    r.getVersionPath(version),
+			Tool:    r.tool,
 		}, nil
 	}
 
@@ -70,6 +86,7 @@ func (r *VersionResolver) ResolveVersion() (*ResolvedVersion, error) {
 			Version: version,
 			Source:  SourceLocal,
 			Path:    r.getVersionPath(version),
+			Tool:    r.tool,
 		}, nil
 	}
 
@@ -86,34 +103,72 @@ func (r *VersionResolver) ResolveVersion() (*ResolvedVersion, error) {
 			Version: version,
 			Source:  SourceGlobal,
 			Path:    r.getVersionPath(version),
+			Tool:    r.tool,
 		}, nil
 	}
 
 	return nil, NewNoVersionSetError("")
 }
 
-// getShellVersion reads version from MVNENV_MAVEN_VERSION environment variable
+// ResolveExplicitVersion resolves a version given directly on the command line,
+// bypassing the shell > local > global hierarchy
+func (r *VersionResolver) ResolveExplicitVersion(version string) (*ResolvedVersion, error) {
+	if !r.isVersionInstalled(version) {
+		return nil, &VersionError{
+			Version: version,
+			Source:  SourceExplicit,
+			Err:     ErrVersionNotInstalled,
+		}
+	}
+	return &ResolvedVersion{
+		Version: version,
+		Source:  SourceExplicit,
+		Path:    r.getVersionPath(version),
+		Tool:    r.tool,
+	}, nil
+}
+
+// getShellVersion reads version from the tool's environment variable
+// (MVNENV_MAVEN_VERSION for Maven)
 func (r *VersionResolver) getShellVersion() (string, bool) {
-	version := strings.TrimSpace(os.Getenv("MVNENV_MAVEN_VERSION"))
+	version := strings.TrimSpace(os.Getenv(r.tool.VersionEnv))
 	if version != "" {
 		return version, true
 	}
 	return "", false
 }
 
-// getLocalVersion reads version from .maven-version file in current or parent directories
+// getLocalVersion reads version from the tool's version file (.maven-version
+// for Maven) in current or parent directories
 func (r *VersionResolver) getLocalVersion() (string, bool) {
 	dir, err := os.Getwd()
 	if err != nil {
 		return "", false
 	}
 
+	_, version, ok := r.findVersionFile(dir)
+	return version, ok
+}
+
+// LocalVersionFile returns the version file that sets the local version for
+// the current directory, and the version it names
+func (r *VersionResolver) LocalVersionFile() (string, string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", "", false
+	}
+	return r.findVersionFile(dir)
+}
+
+// findVersionFile looks for a non-empty version file in dir and its parents
+// and returns its path and version
+func (r *VersionResolver) findVersionFile(dir string) (string, string, bool) {
 	for {
-		versionFile := filepath.Join(dir, ".maven-version")
+		versionFile := filepath.Join(dir, r.tool.VersionFile)
 		if data, err := os.ReadFile(versionFile); err == nil {
 			version := strings.TrimSpace(string(data))
 			if version != "" {
-				return version, true
+				return versionFile, version, true
 			}
 		}
 
@@ -126,29 +181,43 @@ func (r *VersionResolver) getLocalVersion() (string, bool) {
 		dir = parent
 	}
 
-	return "", false
+	return "", "", false
 }
 
-// getGlobalVersion reads version from global configuration
+// getGlobalVersion reads version from global configuration. The precomputed
+// index is tried first so the common case avoids parsing config.yaml.
 func (r *VersionResolver) getGlobalVersion() (string, bool) {
-	version, err := r.configManager.GetGlobalVersion()
-	if err != nil || version == "" {
+	if version, ok := r.configManager.GetToolGlobalVersionFast(r.tool.Name); ok {
+		return version, version != ""
+	}
+
+	version, err := r.configManager.GetToolGlobalVersion(r.tool.Name)
+	if err != nil {
+		return "", false
+	}
+
+	// Index was missing or stale; rebuild it so the next call takes the fast path
+	r.configManager.TryRefreshGlobalVersionIndex()
+
+	if version == "" {
 		return "", false
 	}
 	return version, true
 }
 
-// IsVersionInstalled checks if a Maven version is installed
+// IsVersionInstalled checks if a version of the tool is installed
 func (r *VersionResolver) IsVersionInstalled(version string) bool {
-	versionPath := r.GetVersionPath(version)
-	mvnCmd := filepath.Join(versionPath, "bin", "mvn.cmd")
-	_, err := os.Stat(mvnCmd)
-	return err == nil
+	return r.tool.IsValidInstallation(r.GetVersionPath(version))
+}
+
+// MvnenvRoot returns the mvnenv root directory the resolver works on
+func (r *VersionResolver) MvnenvRoot() string {
+	return r.mvnenvRoot
 }
 
 // GetVersionPath returns the installation path for a version
 func (r *VersionResolver) GetVersionPath(version string) string {
-	return filepath.Join(r.mvnenvRoot, "versions", version)
+	return r.tool.InstallPath(r.mvnenvRoot, version)
 }
 
 // isVersionInstalled is a private wrapper
@@ -0,0 +1,77 @@
+package version
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/veenone/mvnenv-win/internal/config"
+)
+
+// setupGlobalVersion creates an mvnenv root with an installed global Maven
+// version and no shell or local version, and returns the root
+func setupGlobalVersion(b *testing.B, version string) string {
+	b.Helper()
+	root := b.TempDir()
+
+	launcher := filepath.Join(root, "versions", version, "bin", "mvn.cmd")
+	if err := os.MkdirAll(filepath.Dir(launcher), 0755); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(launcher, nil, 0644); err != nil {
+		b.Fatal(err)
+	}
+	if err := config.NewManager(root).SetGlobalVersion(version); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Setenv("MVNENV_MAVEN_VERSION", "")
+	wd, err := os.Getwd()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { os.Chdir(wd) })
+
+	return root
+}
+
+func BenchmarkResolveVersion(b *testing.B) {
+	const version = "3.9.6"
+
+	// The shim's common case: the global version comes from the index
+	b.Run("index", func(b *testing.B) {
+		resolver := NewVersionResolver(setupGlobalVersion(b, version))
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			resolved, err := resolver.ResolveVersion()
+			if err != nil || resolved.Version != version {
+				b.Fatalf("ResolveVersion() = %v, %v", resolved, err)
+			}
+		}
+	})
+
+	// Without the index, config.yaml is parsed and the index rewritten
+	b.Run("no-index", func(b *testing.B) {
+		root := setupGlobalVersion(b, version)
+		resolver := NewVersionResolver(root)
+		index := filepath.Join(root, "config", "global-version")
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			if err := os.Remove(index); err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+
+			resolved, err := resolver.ResolveVersion()
+			if err != nil || resolved.Version != version {
+				b.Fatalf("ResolveVersion() = %v, %v", resolved, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/veenone/mvnenv-win/internal/lock"
+)
+
+// TrashGracePeriod is how long uninstalled versions can be restored before
+// they are deleted for good
+const TrashGracePeriod = 7 * 24 * time.Hour
+
+// trashMetaFile describes a trashed installation; the installation itself is
+// kept next to it in trashInstallDir
+const (
+	trashMetaFile   = "trash.json"
+	trashInstallDir = "install"
+)
+
+// TrashEntry is an uninstalled version kept in <MVNENV_ROOT>/trash
+type TrashEntry struct {
+	ID        string    `json:"id"`
+	Tool      string    `json:"tool"`
+	Version   string    `json:"version"`
+	Path      string    `json:"path"`
+	TrashedAt time.Time `json:"trashed_at"`
+}
+
+// Expires returns when the entry will be deleted
+func (e TrashEntry) Expires() time.Time {
+	return e.TrashedAt.Add(TrashGracePeriod)
+}
+
+// trashPath returns the trash directory of an mvnenv root
+func trashPath(mvnenvRoot string) string {
+	return filepath.Join(mvnenvRoot, "trash")
+}
+
+// ListTrash returns the trashed versions, newest first
+func ListTrash(mvnenvRoot string) ([]TrashEntry, error) {
+	dirs, err := os.ReadDir(trashPath(mvnenvRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read trash: %w", err)
+	}
+
+	var entries []TrashEntry
+	for _, d := range dirs {
+		data, err := os.ReadFile(filepath.Join(trashPath(mvnenvRoot), d.Name(), trashMetaFile))
+		if err != nil {
+			continue
+		}
+		var entry TrashEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entry.ID = d.Name()
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].TrashedAt.After(entries[j].TrashedAt)
+	})
+	return entries, nil
+}
+
+// PurgeTrash deletes trashed versions whose grace period has passed, or all
+// of them, and returns the deleted entries
+func PurgeTrash(mvnenvRoot string, all bool) ([]TrashEntry, error) {
+	fileLock, err := lock.Acquire(mvnenvRoot, "trash")
+	if err != nil {
+		return nil, fmt.Errorf("lock trash: %w", err)
+	}
+	defer fileLock.Release()
+
+	entries, err := ListTrash(mvnenvRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []TrashEntry
+	for _, entry := range entries {
+		if !all && time.Now().Before(entry.Expires()) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(trashPath(mvnenvRoot), entry.ID)); err != nil {
+			return purged, fmt.Errorf("delete %s %s from trash: %w", entry.Tool, entry.Version, err)
+		}
+		purged = append(purged, entry)
+	}
+	return purged, nil
+}
+
+// moveToTrash moves an installation into the trash. The caller holds the
+// version lock.
+func (i *VersionInstaller) moveToTrash(version string) (*TrashEntry, error) {
+	entry := &TrashEntry{
+		ID:        fmt.Sprintf("%s-%s-%d", i.tool.Name, version, time.Now().UnixNano()),
+		Tool:      i.tool.Name,
+		Version:   version,
+		Path:      i.resolver.GetVersionPath(version),
+		TrashedAt: time.Now().UTC(),
+	}
+
+	dir := filepath.Join(trashPath(i.mvnenvRoot), entry.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create trash directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, trashMetaFile), data, 0644); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("write trash entry: %w", err)
+	}
+
+	// The trash lives under MVNENV_ROOT, so this is a rename on one volume.
+	// It fails as a whole if a file is in use, leaving the version intact.
+	if err := os.Rename(entry.Path, filepath.Join(dir, trashInstallDir)); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("move %s to trash (is it in use?): %w", entry.Path, err)
+	}
+
+	return entry, nil
+}
+
+// RestoreVersion moves the most recently trashed copy of a version back
+// into place
+func (i *VersionInstaller) RestoreVersion(version string) error {
+	name := i.tool.DisplayName
+
+	fileLock, err := i.lockVersion(version)
+	if err != nil {
+		return err
+	}
+	defer fileLock.Release()
+
+	entries, err := ListTrash(i.mvnenvRoot)
+	if err != nil {
+		return err
+	}
+
+	var entry *TrashEntry
+	for k := range entries {
+		if entries[k].Tool == i.tool.Name && entries[k].Version == version {
+			entry = &entries[k]
+			break
+		}
+	}
+	if entry == nil {
+		return fmt.Errorf("%s %s is not in the trash", name, version)
+	}
+
+	if i.resolver.IsVersionInstalled(version) {
+		return fmt.Errorf("%s %s is installed; uninstall it before restoring the trashed copy", name, version)
+	}
+
+	versionPath := i.resolver.GetVersionPath(version)
+	if err := os.MkdirAll(filepath.Dir(versionPath), 0755); err != nil {
+		return fmt.Errorf("create versions directory: %w", err)
+	}
+	if err := os.RemoveAll(versionPath); err != nil {
+		return fmt.Errorf("remove incomplete installation: %w", err)
+	}
+
+	dir := filepath.Join(trashPath(i.mvnenvRoot), entry.ID)
+	if err := os.Rename(filepath.Join(dir, trashInstallDir), versionPath); err != nil {
+		return fmt.Errorf("restore %s %s: %w", name, version, err)
+	}
+	os.RemoveAll(dir)
+
+	if i.autoRehash {
+		if err := i.regenerateShims(); err != nil {
+			fmt.Printf("Warning: Failed to regenerate shims: %v\n", err)
+			fmt.Println("Run 'mvnenv rehash' manually to update shims")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package version
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SourceProject is a version file in a project root registered with
+// "mvnenv local"; it is only reported by FindUsages
+const SourceProject Source = "project"
+
+// Usage is a place where an installed version is selected
+type Usage struct {
+	Source Source `json:"source"`
+	// Where names the setting or version file, e.g. C:\src\app\.maven-version
+	Where string `json:"where"`
+}
+
+// String formats the usage for display
+func (u Usage) String() string {
+	switch u.Source {
+	case SourceGlobal:
+		return "global version (" + u.Where + ")"
+	case SourceShell:
+		return "shell version (" + u.Where + ")"
+	case SourceLocal:
+		return "local version of the current directory (" + u.Where + ")"
+	default:
+		return "project version (" + u.Where + ")"
+	}
+}
+
+// FindUsages reports where a version of the resolver's tool is selected: as
+// the global version, in the current shell, by a version file above the
+// current directory, or by the version file of a project root registered
+// with "mvnenv local"
+func (r *VersionResolver) FindUsages(version string) []Usage {
+	var usages []Usage
+
+	if global, ok := r.getGlobalVersion(); ok && global == version {
+		usages = append(usages, Usage{Source: SourceGlobal, Where: "config.yaml"})
+	}
+
+	if shell, ok := r.getShellVersion(); ok && shell == version {
+		usages = append(usages, Usage{Source: SourceShell, Where: r.tool.VersionEnv})
+	}
+
+	seen := make(map[string]bool)
+	if cwd, err := os.Getwd(); err == nil {
+		if path, local, ok := r.findVersionFile(cwd); ok && local == version {
+			usages = append(usages, Usage{Source: SourceLocal, Where: path})
+			seen[strings.ToLower(path)] = true
+		}
+	}
+
+	cfg, err := r.configManager.Load()
+	if err != nil {
+		return usages
+	}
+	for _, project := range cfg.Projects {
+		path := filepath.Join(project, r.tool.VersionFile)
+		if seen[strings.ToLower(path)] {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err == nil && strings.TrimSpace(string(data)) == version {
+			usages = append(usages, Usage{Source: SourceProject, Where: path})
+			seen[strings.ToLower(path)] = true
+		}
+	}
+
+	return usages
+}
+
+// inUseError lists the usages that stop a version from being uninstalled
+func inUseError(name, version string, usages []Usage) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s is in use:", name, version)
+	for _, u := range usages {
+		fmt.Fprintf(&b, "\n  - %s", u)
+	}
+	b.WriteString("\nuse --force to uninstall it anyway")
+	return fmt.Errorf("%s", b.String())
+}
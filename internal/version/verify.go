@@ -0,0 +1,345 @@
+package version
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/veenone/mvnenv-win/internal/cache"
+	"github.com/veenone/mvnenv-win/internal/config"
+	"github.com/veenone/mvnenv-win/internal/tool"
+)
+
+// Verification is the outcome of checking an installation for changes
+type Verification struct {
+	Tool    string       `json:"tool"`
+	Version string       `json:"version"`
+	Path    string       `json:"path"`
+	Changes []FileChange `json:"changes"`
+
+	// Unknown explains why changes couldn't be determined
+	Unknown string `json:"unknown,omitempty"`
+}
+
+// Clean reports whether the installation is known to be unchanged
+func (v *Verification) Clean() bool {
+	return v.Unknown == "" && len(v.Changes) == 0
+}
+
+// VerifyInstallation compares an installation with the file hashes recorded
+// in its manifest. Installations without recorded hashes are compared with
+// their cached archive instead. Files matching verification.allow in
+// config.yaml are not reported.
+func VerifyInstallation(mvnenvRoot string, def *tool.Definition, version string) (*Verification, error) {
+	installPath := def.InstallPath(mvnenvRoot, version)
+	v := &Verification{Tool: def.Name, Version: version, Path: installPath, Changes: []FileChange{}}
+
+	manifest, err := ReadManifest(installPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	if manifest != nil && manifest.Files != nil {
+		changes, err = compareFiles(installPath, manifest.Files)
+	} else {
+		entry, archivePath, _ := cache.NewArchiveStore(mvnenvRoot).Lookup(def.Name, version)
+		switch {
+		case entry == nil:
+			v.Unknown = "no file hashes recorded and the archive is no longer in the download cache"
+			return v, nil
+		case manifest != nil && manifest.ArchiveSHA512 != entry.SHA512:
+			v.Unknown = "no file hashes recorded and the cached archive is not the one this version was installed from"
+			return v, nil
+		}
+		if changes, err = DiffInstallation(installPath, archivePath); err == nil && manifest != nil {
+			changes = withoutManaged(changes, manifest)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	allow := allowlist(mvnenvRoot)
+	for _, c := range changes {
+		if !allowed(c.Path, allow) {
+			v.Changes = append(v.Changes, c)
+		}
+	}
+	return v, nil
+}
+
+// withoutManaged drops extension jars and overlay files, which differ from
+// the archive on purpose, from the changes an archive comparison reports,
+// unless they are missing
+func withoutManaged(changes []FileChange, manifest *Manifest) []FileChange {
+	managed := make(map[string]bool)
+	for _, ext := range manifest.Extensions {
+		managed[ext.File] = true
+	}
+	for _, o := range manifest.Overlays {
+		for _, f := range o.Files {
+			managed[f] = true
+		}
+	}
+
+	var kept []FileChange
+	for _, c := range changes {
+		if !managed[c.Path] || c.Change == FileMissing {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// RepairVersion restores an installation to the state of its archive,
+// downloading the archive again when it is no longer cached: modified and
+// missing files are extracted again and added files are removed, except
+// those on the allowlist. Extension jars are put back from the archive store
+// or Nexus and overlays are applied again. It returns the changes that were
+// undone.
+func (i *VersionInstaller) RepairVersion(ctx context.Context, version string) ([]FileChange, error) {
+	name := i.tool.DisplayName
+
+	fileLock, err := i.lockVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Release()
+
+	installPath := i.tool.InstallPath(i.mvnenvRoot, version)
+	if _, err := os.Stat(installPath); err != nil {
+		return nil, fmt.Errorf("%s %s is not installed", name, version)
+	}
+
+	// An archive no longer in the store is downloaded again, with the same
+	// checksum and signature checks as when installing
+	entry, archivePath, err := i.fetchArchive(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := ReadManifest(installPath)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil && manifest.ArchiveSHA512 != entry.SHA512 {
+		return nil, fmt.Errorf("the %s %s archive is not the one it was installed from (reinstall with 'mvnenv install --force %s')",
+			name, version, version)
+	}
+
+	v, err := VerifyInstallation(i.mvnenvRoot, i.tool, version)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer r.Close()
+
+	rootPrefix := archiveRoot(r.File)
+	files := make(map[string]*zip.File)
+	for _, f := range r.File {
+		files[strings.TrimPrefix(f.Name, rootPrefix)] = f
+	}
+
+	extensions := make(map[string]Extension)
+	overlayFiles := make(map[string]string)
+	if manifest != nil {
+		for _, ext := range manifest.Extensions {
+			extensions[ext.File] = ext
+		}
+		for _, o := range manifest.Overlays {
+			for _, f := range o.Files {
+				overlayFiles[f] = o.Name
+			}
+		}
+	}
+	reapply := make(map[string]bool)
+	rewrite := false
+
+	for _, c := range v.Changes {
+		destPath := filepath.Join(installPath, filepath.FromSlash(c.Path))
+
+		if ext, ok := extensions[c.Path]; ok && c.Change != FileAdded {
+			if _, err := i.applyExtension(ctx, installPath, manifest, ext); err != nil {
+				return nil, fmt.Errorf("restore %s: %w", c.Path, err)
+			}
+			rewrite = true
+			continue
+		}
+
+		if name, ok := overlayFiles[c.Path]; ok && c.Change != FileAdded {
+			reapply[name] = true
+			continue
+		}
+
+		if c.Change == FileAdded {
+			if err := os.Remove(destPath); err != nil {
+				return nil, fmt.Errorf("remove %s: %w", c.Path, err)
+			}
+			continue
+		}
+
+		f, ok := files[c.Path]
+		if !ok {
+			return nil, fmt.Errorf("%s is not in the archive", c.Path)
+		}
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return nil, fmt.Errorf("create parent directory: %w", err)
+		}
+		if err := i.extractFile(f, destPath); err != nil {
+			return nil, fmt.Errorf("restore %s: %w", c.Path, err)
+		}
+	}
+
+	// Installations from before file hashes were recorded get them now
+	if manifest != nil && manifest.Files == nil {
+		if manifest.Files, err = hashFiles(installPath); err != nil {
+			return nil, err
+		}
+		rewrite = true
+	}
+
+	// Overlays are applied in their configured order, as when installing
+	if len(reapply) > 0 {
+		overlays, err := SelectOverlays(i.mvnenvRoot, i.tool, version)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range overlays {
+			if !reapply[o.Name] {
+				continue
+			}
+			if _, err := i.applyOverlay(ctx, installPath, manifest, o); err != nil {
+				return nil, fmt.Errorf("apply overlay '%s': %w", o.Name, err)
+			}
+			delete(reapply, o.Name)
+		}
+		for name := range reapply {
+			return nil, fmt.Errorf("overlay '%s' is no longer configured for %s %s (reinstall with 'mvnenv install --force %s')",
+				name, i.tool.DisplayName, version, version)
+		}
+		rewrite = true
+	}
+
+	if rewrite {
+		if err := writeManifest(installPath, manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	return v.Changes, nil
+}
+
+// hashFiles returns the SHA-256 of every file in an installation, keyed by
+// slash-separated relative path. The manifest is not included.
+func hashFiles(installPath string) (map[string]string, error) {
+	hashes := make(map[string]string)
+
+	err := filepath.Walk(installPath, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(installPath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ManifestFile {
+			return nil
+		}
+
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		hashes[rel] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("hash installed files: %w", err)
+	}
+	return hashes, nil
+}
+
+// compareFiles compares an installation with recorded file hashes
+func compareFiles(installPath string, recorded map[string]string) ([]FileChange, error) {
+	current, err := hashFiles(installPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FileChange
+	for rel, sum := range recorded {
+		actual, ok := current[rel]
+		switch {
+		case !ok:
+			changes = append(changes, FileChange{Path: rel, Change: FileMissing})
+		case actual != sum:
+			changes = append(changes, FileChange{Path: rel, Change: FileModified})
+		}
+	}
+	for rel := range current {
+		if _, ok := recorded[rel]; !ok {
+			changes = append(changes, FileChange{Path: rel, Change: FileAdded})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// fileSHA256 returns the hex SHA-256 of a file
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// allowlist returns the configured verification.allow patterns
+func allowlist(mvnenvRoot string) []string {
+	cfg, err := config.NewManager(mvnenvRoot).Load()
+	if err != nil || cfg.Verification == nil {
+		return nil
+	}
+	return cfg.Verification.Allow
+}
+
+// allowed reports whether a relative path matches an allowlist pattern.
+// Patterns use path.Match syntax; a trailing "/**" matches a whole tree.
+func allowed(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "/")
+		if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if rel == dir || strings.HasPrefix(rel, dir+"/") {
+				return true
+			}
+			continue
+		}
+		if match, _ := path.Match(pattern, rel); match {
+			return true
+		}
+	}
+	return false
+}
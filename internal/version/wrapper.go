@@ -0,0 +1,249 @@
+package version
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SourceWrapper indicates the version came from a Maven Wrapper project
+const SourceWrapper Source = "wrapper"
+
+// WrapperPropertiesPath is the location of the Maven Wrapper configuration
+// relative to the project root
+var WrapperPropertiesPath = filepath.Join(".mvn", "wrapper", "maven-wrapper.properties")
+
+// wrapperDistributionPattern extracts the version from a distributionUrl such as
+// .../apache-maven/3.9.6/apache-maven-3.9.6-bin.zip
+var wrapperDistributionPattern = regexp.MustCompile(`apache-maven-([^/]+?)-bin\.(?:zip|tar\.gz)$`)
+
+// sha256Pattern matches a hex SHA-256 checksum
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// WrapperDistribution is the Maven distribution requested by a
+// maven-wrapper.properties file
+type WrapperDistribution struct {
+	Version string
+
+	// SHA256 is the distributionSha256Sum the archive must have, if set
+	SHA256 string
+}
+
+// WrapperDistribution returns the distribution requested by the Maven
+// Wrapper properties of the project containing the current directory
+func (r *VersionResolver) WrapperDistribution() (*WrapperDistribution, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("get working directory: %w", err)
+	}
+
+	propsPath, err := FindWrapperProperties(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseWrapperDistribution(propsPath)
+}
+
+// ResolveWrapperVersion resolves the Maven version requested by the Maven
+// Wrapper properties of the project containing the current directory
+func (r *VersionResolver) ResolveWrapperVersion() (*ResolvedVersion, error) {
+	dist, err := r.WrapperDistribution()
+	if err != nil {
+		return nil, err
+	}
+	version := dist.Version
+
+	if !r.isVersionInstalled(version) {
+		return nil, &VersionError{
+			Version: version,
+			Source:  SourceWrapper,
+			Err:     ErrVersionNotInstalled,
+		}
+	}
+
+	return &ResolvedVersion{
+		Version: version,
+		Source:  SourceWrapper,
+		Path:    r.getVersionPath(version),
+		Tool:    r.tool,
+	}, nil
+}
+
+// FindWrapperProperties looks for .mvn/wrapper/maven-wrapper.properties in dir
+// and its parents
+func FindWrapperProperties(dir string) (string, error) {
+	for {
+		propsPath := filepath.Join(dir, WrapperPropertiesPath)
+		if _, err := os.Stat(propsPath); err == nil {
+			return propsPath, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return "", fmt.Errorf("no %s found in current or parent directories", filepath.ToSlash(WrapperPropertiesPath))
+}
+
+// ParseWrapperVersion reads the Maven version from a maven-wrapper.properties file
+func ParseWrapperVersion(propsPath string) (string, error) {
+	dist, err := ParseWrapperDistribution(propsPath)
+	if err != nil {
+		return "", err
+	}
+	return dist.Version, nil
+}
+
+// ParseWrapperDistribution reads the Maven version and the expected archive
+// checksum from a maven-wrapper.properties file
+func ParseWrapperDistribution(propsPath string) (*WrapperDistribution, error) {
+	props, err := readProperties(propsPath)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", propsPath, err)
+	}
+
+	distributionURL := props["distributionUrl"]
+	if distributionURL == "" {
+		return nil, fmt.Errorf("%s does not define distributionUrl", propsPath)
+	}
+
+	match := wrapperDistributionPattern.FindStringSubmatch(distributionURL)
+	if match == nil {
+		return nil, fmt.Errorf("cannot determine Maven version from distributionUrl %s", distributionURL)
+	}
+
+	dist := &WrapperDistribution{Version: match[1], SHA256: strings.ToLower(props["distributionSha256Sum"])}
+	if dist.SHA256 != "" && !sha256Pattern.MatchString(dist.SHA256) {
+		return nil, fmt.Errorf("%s: distributionSha256Sum is not a SHA-256: %s", propsPath, dist.SHA256)
+	}
+	return dist, nil
+}
+
+// CheckArchiveSHA256 checks that an installed version was extracted from an
+// archive with the given SHA-256, such as a Maven Wrapper's
+// distributionSha256Sum. Installations from before the SHA-256 was recorded
+// get it now from their archive, which is downloaded again if it is no
+// longer cached.
+func (i *VersionInstaller) CheckArchiveSHA256(ctx context.Context, version, sum string) error {
+	installPath := i.tool.InstallPath(i.mvnenvRoot, version)
+	manifest, err := ReadManifest(installPath)
+	if err != nil {
+		return err
+	}
+	if manifest == nil || manifest.ArchiveSHA256 == "" {
+		if manifest, err = i.recordArchiveSHA256(ctx, version); err != nil {
+			return err
+		}
+	}
+
+	if manifest.ArchiveSHA256 != strings.ToLower(sum) {
+		return fmt.Errorf("%s %s was installed from an archive with SHA-256 %s, not %s (reinstall with 'mvnenv install --force %s')",
+			i.tool.DisplayName, version, manifest.ArchiveSHA256, sum, version)
+	}
+	return nil
+}
+
+// recordArchiveSHA256 adds the SHA-256 of an installation's archive to its
+// manifest and returns the manifest
+func (i *VersionInstaller) recordArchiveSHA256(ctx context.Context, version string) (*Manifest, error) {
+	name := i.tool.DisplayName
+
+	fileLock, err := i.lockVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	defer fileLock.Release()
+
+	installPath := i.tool.InstallPath(i.mvnenvRoot, version)
+	manifest, err := ReadManifest(installPath)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("%s %s has no manifest recording its archive (reinstall with 'mvnenv install --force %s')",
+			name, version, version)
+	}
+	// Another process may have recorded it while we waited for the lock
+	if manifest.ArchiveSHA256 != "" {
+		return manifest, nil
+	}
+
+	entry, archivePath, err := i.fetchArchive(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.ArchiveSHA512 != entry.SHA512 {
+		return nil, fmt.Errorf("the %s %s archive is not the one it was installed from (reinstall with 'mvnenv install --force %s')",
+			name, version, version)
+	}
+
+	if manifest.ArchiveSHA256, err = fileSHA256(archivePath); err != nil {
+		return nil, fmt.Errorf("calculate checksum: %w", err)
+	}
+	if err := writeManifest(installPath, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// readProperties parses a Java properties file. Only the subset used by
+// maven-wrapper.properties is supported: one key=value (or key:value) per line,
+// # and ! comments, and backslash escapes.
+func readProperties(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep < 0 {
+			continue
+		}
+
+		key := strings.TrimSpace(line[:sep])
+		value := strings.TrimSpace(line[sep+1:])
+		props[unescapeProperty(key)] = unescapeProperty(value)
+	}
+
+	return props, scanner.Err()
+}
+
+// unescapeProperty removes Java properties backslash escapes (e.g. "https\://")
+func unescapeProperty(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	escaped := false
+	for _, ch := range s {
+		if escaped {
+			b.WriteRune(ch)
+			escaped = false
+			continue
+		}
+		if ch == '\\' {
+			escaped = true
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package version
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const distributionSum = "4ec3f26fb1a692473aea0235c300bd20f0f9fe741947c82c1234cefd76ac3a3c"
+
+// writeProperties writes a maven-wrapper.properties file
+func writeProperties(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "maven-wrapper.properties")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseWrapperDistribution(t *testing.T) {
+	url := "distributionUrl=https\\://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.9.6/apache-maven-3.9.6-bin.zip\n"
+
+	dist, err := ParseWrapperDistribution(writeProperties(t, url))
+	if err != nil || dist.Version != "3.9.6" || dist.SHA256 != "" {
+		t.Errorf("without checksum: %+v, %v", dist, err)
+	}
+
+	dist, err = ParseWrapperDistribution(writeProperties(t, url+"distributionSha256Sum="+strings.ToUpper(distributionSum)+"\n"))
+	if err != nil || dist.Version != "3.9.6" || dist.SHA256 != distributionSum {
+		t.Errorf("with checksum: %+v, %v", dist, err)
+	}
+
+	if _, err := ParseWrapperDistribution(writeProperties(t, url+"distributionSha256Sum=abc\n")); err == nil {
+		t.Error("malformed checksum accepted")
+	}
+}
+
+func TestCheckArchiveSHA256(t *testing.T) {
+	root := t.TempDir()
+	installPath := filepath.Join(root, "versions", "3.9.6")
+	if err := os.MkdirAll(installPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	i := NewVersionInstaller(root)
+
+	// Without a manifest the archive can't be identified
+	if err := i.CheckArchiveSHA256(context.Background(), "3.9.6", distributionSum); err == nil {
+		t.Error("installation without a manifest passed")
+	}
+
+	if err := writeManifest(installPath, &Manifest{Tool: "maven", Version: "3.9.6", ArchiveSHA256: distributionSum}); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.CheckArchiveSHA256(context.Background(), "3.9.6", strings.ToUpper(distributionSum)); err != nil {
+		t.Errorf("matching checksum: %v", err)
+	}
+	if err := i.CheckArchiveSHA256(context.Background(), "3.9.6", strings.Repeat("0", 64)); err == nil {
+		t.Error("different checksum passed")
+	}
+}
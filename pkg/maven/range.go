@@ -0,0 +1,118 @@
+package maven
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Range is a set of constraints a version must all satisfy, such as
+// ">=3.9 <4". A bare version matches by prefix: "3.9" selects every 3.9.x.
+//
+// A pre-release sorts before its release, so 4.0.0-rc-2 is below 4.0.0 but
+// does not satisfy "<4": an upper bound excludes the pre-releases of the
+// version it names. Nor does it satisfy ">=4"; select pre-releases with a
+// qualified bound such as ">=4.0.0-alpha-1" or by prefix with "4".
+type Range struct {
+	constraints []constraint
+}
+
+// constraint compares versions with an operator
+type constraint struct {
+	op      string
+	version *Version
+	// parts is how many numbers a bare version has, for prefix matching
+	parts int
+}
+
+// rangeOperators are tried in order, longest first
+var rangeOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseRange parses constraints separated by spaces or commas. An empty
+// range or "*" matches every version.
+func ParseRange(expr string) (*Range, error) {
+	r := &Range{}
+	fields := strings.FieldsFunc(expr, func(c rune) bool {
+		return c == ' ' || c == ','
+	})
+
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if field == "*" {
+			continue
+		}
+
+		op := ""
+		for _, candidate := range rangeOperators {
+			if strings.HasPrefix(field, candidate) {
+				op = candidate
+				break
+			}
+		}
+		value := strings.TrimPrefix(field, op)
+		// Allow a space after the operator, as in ">= 3.9"
+		if value == "" && op != "" && i+1 < len(fields) {
+			i++
+			value = fields[i]
+		}
+
+		v, err := ParseVersion(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version range '%s': %w", expr, err)
+		}
+		r.constraints = append(r.constraints, constraint{
+			op:      op,
+			version: v,
+			parts:   len(strings.Split(strings.SplitN(value, "-", 2)[0], ".")),
+		})
+	}
+
+	return r, nil
+}
+
+// Contains reports whether a version satisfies every constraint
+func (r *Range) Contains(v *Version) bool {
+	for _, c := range r.constraints {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c constraint) matches(v *Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0 && !preReleaseOf(v, c.version)
+	case "!=":
+		return cmp != 0
+	case "=":
+		return cmp == 0
+	}
+
+	// Bare version: compare the numbers it gives, and the qualifier if any
+	switch {
+	case v.Major != c.version.Major:
+		return false
+	case c.parts > 1 && v.Minor != c.version.Minor:
+		return false
+	case c.parts > 2 && v.Patch != c.version.Patch:
+		return false
+	case c.version.Qualifier != "" && v.Qualifier != c.version.Qualifier:
+		return false
+	}
+	return true
+}
+
+// preReleaseOf reports whether v is a pre-release of release, as
+// 4.0.0-rc-2 is of 4.0.0
+func preReleaseOf(v, release *Version) bool {
+	return release.Qualifier == "" && v.Qualifier != "" &&
+		v.Major == release.Major && v.Minor == release.Minor && v.Patch == release.Patch
+}
@@ -0,0 +1,65 @@
+package maven
+
+import "testing"
+
+func TestRangeContains(t *testing.T) {
+	tests := []struct {
+		expr    string
+		version string
+		want    bool
+	}{
+		{"", "3.9.6", true},
+		{"*", "4.0.0-rc-2", true},
+		{">=3.9", "3.9.0", true},
+		{">=3.9", "3.8.8", false},
+		{">= 3.9", "3.9.6", true},
+		{">=3.8 <4", "3.9.6", true},
+		{">=3.8,<4", "4.0.0", false},
+		{"<=3.9.6", "3.9.6", true},
+		{">3.9.6", "3.9.6", false},
+		{"!=3.9.5", "3.9.5", false},
+		{"=3.9.5", "3.9.5", true},
+		{"3.9", "3.9.6", true},
+		{"3.9", "3.10.0", false},
+		{"3", "3.6.3", true},
+		{"3.9.6", "3.9.6", true},
+		{"3.9.6", "3.9.60", false},
+
+		// Pre-releases
+		{"<4", "4.0.0-rc-2", false},
+		{"<4", "3.9.6", true},
+		{"<4.0.0", "4.0.0-alpha-1", false},
+		{"<4.1", "4.0.0-rc-2", true},
+		{">=3.8 <4", "4.0.0-beta-3", false},
+		{">=4", "4.0.0-rc-2", false},
+		{">=4.0.0-alpha-1", "4.0.0-rc-2", true},
+		{"<4.0.0-rc-2", "4.0.0-rc-1", true},
+		{"<=4", "4.0.0-rc-2", true},
+		{"4", "4.0.0-rc-2", true},
+		{"4.0.0-rc-2", "4.0.0-rc-2", true},
+		{"4.0.0-rc-2", "4.0.0-rc-1", false},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRange(tt.expr)
+		if err != nil {
+			t.Errorf("ParseRange(%q) = %v", tt.expr, err)
+			continue
+		}
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Contains(v); got != tt.want {
+			t.Errorf("ParseRange(%q).Contains(%s) = %v, want %v", tt.expr, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestParseRangeInvalid(t *testing.T) {
+	for _, expr := range []string{">=", ">=x", "3.9.6.1", ">=3.9 <"} {
+		if _, err := ParseRange(expr); err == nil {
+			t.Errorf("ParseRange(%q) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package maven
+
+import "fmt"
+
+// Scope limits how far an upgrade may move a version
+type Scope string
+
+const (
+	// ScopePatch keeps the major and minor version, e.g. 3.9.4 to 3.9.9
+	ScopePatch Scope = "patch"
+	// ScopeMinor keeps the major version, e.g. 3.8.8 to 3.9.9
+	ScopeMinor Scope = "minor"
+	// ScopeMajor allows any newer version
+	ScopeMajor Scope = "major"
+)
+
+// ParseScope parses an upgrade scope name
+func ParseScope(s string) (Scope, error) {
+	switch scope := Scope(s); scope {
+	case ScopePatch, ScopeMinor, ScopeMajor:
+		return scope, nil
+	}
+	return "", fmt.Errorf("invalid scope '%s' (use patch, minor or major)", s)
+}
+
+// Allows reports whether moving from current to v stays within the scope
+func (s Scope) Allows(current, v *Version) bool {
+	switch s {
+	case ScopePatch:
+		return v.Major == current.Major && v.Minor == current.Minor
+	case ScopeMinor:
+		return v.Major == current.Major
+	}
+	return true
+}
+
+// NewestInScope returns the newest of versions that is newer than current
+// and within the scope. Pre-releases are only considered when current is
+// one, so 3.9.6 never moves to 4.0.0-rc-2 but 4.0.0-rc-1 moves to
+// 4.0.0-rc-2, or to 4.0.0 once released. It returns false when current is
+// already the newest.
+func NewestInScope(current string, versions []string, scope Scope) (string, bool, error) {
+	cur, err := ParseVersion(current)
+	if err != nil {
+		return "", false, err
+	}
+
+	var newest *Version
+	for _, s := range versions {
+		v, err := ParseVersion(s)
+		if err != nil {
+			continue
+		}
+		if v.Qualifier != "" && cur.Qualifier == "" {
+			continue
+		}
+		if !scope.Allows(cur, v) || v.Compare(cur) <= 0 {
+			continue
+		}
+		if newest == nil || v.Compare(newest) > 0 {
+			newest = v
+		}
+	}
+
+	if newest == nil {
+		return "", false, nil
+	}
+	return newest.String(), true, nil
+}
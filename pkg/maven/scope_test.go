@@ -0,0 +1,56 @@
+package maven
+
+import "testing"
+
+func TestNewestInScope(t *testing.T) {
+	available := []string{"3.8.7", "3.8.8", "3.9.5", "3.9.6", "4.0.0-rc-1", "4.0.0-rc-2", "invalid"}
+
+	tests := []struct {
+		current  string
+		versions []string
+		scope    Scope
+		want     string
+	}{
+		{"3.9.5", available, ScopePatch, "3.9.6"},
+		{"3.8.7", available, ScopePatch, "3.8.8"},
+		{"3.8.7", available, ScopeMinor, "3.9.6"},
+		{"3.9.6", available, ScopePatch, ""},
+
+		// Pre-releases are skipped for a release
+		{"3.9.6", available, ScopeMajor, ""},
+		{"3.8.8", available, ScopeMajor, "3.9.6"},
+
+		// and considered for a pre-release
+		{"4.0.0-rc-1", available, ScopePatch, "4.0.0-rc-2"},
+		{"4.0.0-rc-1", append(available, "4.0.0"), ScopePatch, "4.0.0"},
+		{"4.0.0-rc-2", available, ScopeMajor, ""},
+	}
+
+	for _, tt := range tests {
+		got, ok, err := NewestInScope(tt.current, tt.versions, tt.scope)
+		if err != nil {
+			t.Errorf("NewestInScope(%s, %s) = %v", tt.current, tt.scope, err)
+			continue
+		}
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("NewestInScope(%s, %s) = %q, %v; want %q", tt.current, tt.scope, got, ok, tt.want)
+		}
+	}
+}
+
+func TestNewestInScopeInvalidCurrent(t *testing.T) {
+	if _, _, err := NewestInScope("latest", []string{"3.9.6"}, ScopeMajor); err == nil {
+		t.Error("NewestInScope() with an invalid current version succeeded")
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	for _, s := range []string{"patch", "minor", "major"} {
+		if scope, err := ParseScope(s); err != nil || string(scope) != s {
+			t.Errorf("ParseScope(%q) = %q, %v", s, scope, err)
+		}
+	}
+	if _, err := ParseScope("all"); err == nil {
+		t.Error(`ParseScope("all") succeeded`)
+	}
+}